@@ -1,18 +1,18 @@
 package commands
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/Dynom/ERI/cmd/eri-cli/iterator"
-	"github.com/Dynom/ERI/cmd/eri-cli/werkit"
 	"github.com/Dynom/ERI/types"
 	"github.com/Dynom/ERI/validator"
+	"github.com/Dynom/ERI/validator/resolver"
 	"github.com/Dynom/ERI/validator/validations"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
@@ -25,6 +25,18 @@ const (
 	inputFormatCSV  = "csv"
 )
 
+const (
+	checkDepthSyntax  = "syntax"
+	checkDepthLookup  = "lookup"
+	checkDepthConnect = "connect"
+	checkDepthRCPT    = "rcpt"
+)
+
+const (
+	checkSyntaxStrict  = "strict"
+	checkSyntaxRFC5322 = "rfc5322"
+)
+
 // checkCmd represents the check command
 var checkCmd = &cobra.Command{
 	Use:   "check",
@@ -63,15 +75,124 @@ Some examples:
 			return errors.New("maximum number of workers is 1024")
 		}
 
+		switch checkSettings.Check.Depth {
+		case checkDepthSyntax, checkDepthLookup, checkDepthConnect, checkDepthRCPT:
+		default:
+			return fmt.Errorf("unsupported depth %q", checkSettings.Check.Depth)
+		}
+
+		for _, port := range checkSettings.Check.ConnectPorts {
+			if port < 1 || port > 65535 {
+				return fmt.Errorf("unsupported connect port %d", port)
+			}
+		}
+
+		if _, err := resolver.ParseStrategy(checkSettings.Check.ResolverStrategy); err != nil {
+			return err
+		}
+
+		if checkSettings.Check.DNSCacheSize < 1 {
+			return errors.New("minimum dns-cache-size is 1")
+		}
+
+		switch checkSettings.Check.Syntax {
+		case checkSyntaxStrict, checkSyntaxRFC5322:
+		default:
+			return fmt.Errorf("unsupported syntax %q", checkSettings.Check.Syntax)
+		}
+
+		if _, err := validator.ParseIPClasses(checkSettings.Check.MXAllow); err != nil {
+			return err
+		}
+
+		if _, err := validator.ParseSinkholes(checkSettings.Check.MXSinkholes); err != nil {
+			return err
+		}
+
+		if _, err := validator.ParseHELOName(checkSettings.Check.HELOName); err != nil {
+			return err
+		}
+
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		dialer := &net.Dialer{}
-		if checkSettings.Check.Resolver != nil {
-			setCustomResolver(dialer, checkSettings.Check.Resolver)
+
+		var pool *resolver.Pool
+		if len(checkSettings.Check.Resolvers) > 0 {
+			strategy, _ := resolver.ParseStrategy(checkSettings.Check.ResolverStrategy)
+
+			var err error
+			pool, err = resolver.NewPoolFromAddresses(checkSettings.Check.Resolvers, resolver.WithStrategy(strategy))
+			if err != nil {
+				cmd.PrintErrf("Unable to use the resolvers %s\n", err)
+				return
+			}
+
+			setCustomResolver(dialer, pool)
+		}
+
+		var options []validator.Option
+		if checkSettings.Check.DisposableList != "" {
+			list, err := loadDisposableList(checkSettings.Check.DisposableList)
+			if err != nil {
+				cmd.PrintErrf("Unable to load the disposable list %s\n", err)
+				return
+			}
+
+			options = append(options, validator.WithDisposableList(list))
+		}
+
+		if checkSettings.Check.TLDCheck {
+			list, err := loadTLDList(checkSettings.Check.TLDList)
+			if err != nil {
+				cmd.PrintErrf("Unable to load the TLD list %s\n", err)
+				return
+			}
+
+			options = append(options, validator.WithTLDList(list))
 		}
 
-		v := validator.NewEmailAddressValidator(dialer)
+		allow, _ := validator.ParseIPClasses(checkSettings.Check.MXAllow)
+		sinkholes, _ := validator.ParseSinkholes(checkSettings.Check.MXSinkholes)
+		options = append(options, validator.WithMXAddressConfig(validator.MXAddressConfig{Allow: allow, Sinkholes: sinkholes}))
+
+		options = append(options, validator.WithRoleAccounts(checkSettings.Check.RoleAccounts))
+		if checkSettings.Check.Syntax == checkSyntaxRFC5322 {
+			options = append(options, validator.WithSyntaxMode(validator.SyntaxRFC5322))
+		}
+
+		options = append(options, validator.WithProbeConfig(validator.ProbeConfig{
+			HELOName:  checkSettings.Check.HELOName,
+			MailFrom:  checkSettings.Check.MailFrom,
+			StartTLS:  checkSettings.Check.StartTLS,
+			VerifyTLS: checkSettings.Check.StartTLSVerify,
+		}))
+
+		if checkSettings.Check.DNSCache {
+			options = append(options, validator.WithResolver(createCachingResolver(pool, checkSettings.Check.DNSCacheSize)))
+		}
+
+		ports := make([]uint16, 0, len(checkSettings.Check.ConnectPorts))
+		for _, port := range checkSettings.Check.ConnectPorts {
+			ports = append(ports, uint16(port))
+		}
+
+		options = append(options, validator.WithConnectConfig(validator.ConnectConfig{
+			Ports: ports,
+		}))
+
+		pipeline := mapDepthToPipeline(checkSettings.Check.Depth)
+		if checkSettings.Check.Posture {
+			pipeline = pipeline.InsertAfter(validations.FMXDomainHasIP, validator.StepPosture)
+
+			// TLSA records can only be looked up with the configured resolvers
+			if pool != nil {
+				options = append(options, validator.WithPostureConfig(validator.PostureConfig{Resolver: resolver.NewRecords(pool)}))
+			}
+		}
+
+		v := validator.NewEmailAddressValidator(dialer, options...)
 
 		workers := int(checkSettings.Workers)
 		var it *iterator.CallbackIterator
@@ -98,74 +219,100 @@ Some examples:
 			return
 		}
 
-		jsonEncoder := json.NewEncoder(cmd.OutOrStdout())
-		wi := werkit.WerkIt{}
-		wi.StartCheckWorkers(workers, func(tasks <-chan werkit.CheckTask) {
-			for task := range tasks {
+		ctx := cmd.Context()
+		parts := make(chan types.EmailParts)
+		go func() {
+			defer close(parts)
+			for it.Next() {
+				email, err := it.Value()
+				if err != nil {
+					cmd.PrintErr(err)
+					continue
+				}
 
-				ctx, cancel := context.WithTimeout(task.Ctx, checkSettings.Check.TTL)
-				r := doCheck(ctx, task.Fn, task.Parts)
-				cancel()
+				if email == "" {
+					continue
+				}
 
-				err := jsonEncoder.Encode(r)
+				p, err := types.NewEmailParts(email)
 				if err != nil {
-					cmd.PrintErr(err)
+					if err == types.ErrInvalidEmailAddress && !checkSettings.Check.InputIsEmailAddress {
+						p = types.EmailParts{
+							Address: email,
+							Domain:  email,
+						}
+					} else {
+						cmd.PrintErr(err)
+						continue
+					}
 				}
-			}
-		})
 
-		for it.Next() {
-			email, err := it.Value()
-			if err != nil {
-				cmd.PrintErr(err)
-				continue
+				// CheckStream stops reading once ctx is done
+				select {
+				case parts <- p:
+				case <-ctx.Done():
+					return
+				}
 			}
+		}()
 
-			if email == "" {
-				continue
-			}
+		jsonEncoder := json.NewEncoder(cmd.OutOrStdout())
+		results := v.CheckStream(ctx, parts, validator.BatchConfig{
+			Pipeline:    pipeline,
+			Concurrency: workers,
+			Timeout:     checkSettings.Check.TTL,
+		})
 
-			parts, err := types.NewEmailParts(email)
+		for r := range results {
+			err := jsonEncoder.Encode(newCheckResultFull(r.Parts, r.Result))
 			if err != nil {
-				if err == types.ErrInvalidEmailAddress && !checkSettings.Check.InputIsEmailAddress {
-					parts = types.EmailParts{
-						Address: email,
-						Domain:  email,
-					}
-				} else {
-					cmd.PrintErr(err)
-					continue
-				}
+				cmd.PrintErr(err)
 			}
-
-			wi.Process(werkit.CheckTask{
-				Ctx:   cmd.Context(),
-				Fn:    v.CheckWithLookup,
-				Parts: parts,
-			})
 		}
-
-		wi.Wait()
 	},
 }
 
-func doCheck(ctx context.Context, fn validator.CheckFn, parts types.EmailParts) CheckResultFull {
+func newCheckResultFull(parts types.EmailParts, checkResult validator.Result) CheckResultFull {
 	result := CheckResultFull{
 		Input:   parts.Address,
-		Version: 2,
+		Version: 9,
 	}
 
-	{
-		checkResult := fn(ctx, parts)
+	result.Valid = checkResult.Validations.IsValid()
+	result.Disposable = checkResult.Validations.HasFlag(validations.FDisposable)
+	result.NullMX = checkResult.Validations.HasFlag(validations.FNullMX)
+	result.RoleAccount = checkResult.IsRoleAccount()
+	result.Recipient = string(checkResult.RecipientStatus())
+	result.Reason = string(checkResult.Diagnostics.Reason)
+	result.Posture = checkResult.Posture
 
-		result.Valid = checkResult.Validations.IsValid()
-		result.Passed = validations.Flag(checkResult.Validations.RemoveFlag(validations.FValid)).AsStringSlice()
-		result.Checks = validations.Flag(checkResult.Steps).AsStringSlice()
+	if checkSettings.Check.Diagnostics {
+		result.Diagnostics = &checkResult.Diagnostics
 	}
 
+	passed := checkResult.Validations
+	passed.RemoveFlag(validations.FValid)
+	passed.RemoveFlag(validations.FDisposable | validations.FNullMX | validations.FAcceptAll | validations.FRoleAccount)
+	result.Passed = validations.Flag(passed).AsStringSlice()
+	result.Checks = validations.Flag(checkResult.Steps).AsStringSlice()
+
 	return result
 }
 
+// mapDepthToPipeline returns the Pipeline that runs all checks up to and including depth
+func mapDepthToPipeline(depth string) validator.Pipeline {
+	switch depth {
+	case checkDepthSyntax:
+		return validator.SyntaxPipeline()
+	case checkDepthConnect:
+		return validator.ConnectPipeline()
+	case checkDepthRCPT:
+		return validator.RCPTPipeline()
+	default:
+		return validator.LookupPipeline()
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(checkCmd)
 
@@ -173,8 +320,26 @@ func init() {
 	// checkCmd.Flags().StringVar(&checkSettings.Format, "format", inputFormatCSV, "Format to read. CSV works also for unquoted emails separated with a '\\n'")
 	checkCmd.Flags().Uint64Var(&checkSettings.CSV.skipRows, "csv-skip-rows", 0, "Rows to skip, useful when wanting to skip the header in CSV files")
 	checkCmd.Flags().Uint64Var(&checkSettings.CSV.column, "csv-column", 0, "The column to read email addresses from, 0-indexed")
-	checkCmd.Flags().IPVar(&checkSettings.Check.Resolver, "resolver", nil, "Custom DNS resolver IP (e.g.: 1.1.1.1) to use, otherwise system default is used")
+	checkCmd.Flags().StringSliceVar(&checkSettings.Check.Resolvers, "resolver", nil, "Custom DNS resolvers to use (e.g.: 1.1.1.1, '[2001:db8::1]:5353', tls://1.1.1.1 or https://1.1.1.1/dns-query), otherwise system default is used. Repeatable, a failing resolver is demoted")
+	checkCmd.Flags().StringVar(&checkSettings.Check.ResolverStrategy, "resolver-strategy", "failover", "How multiple resolvers are used: 'failover' tries them in order, 'race' queries the first two at once")
+	checkCmd.Flags().BoolVar(&checkSettings.Check.DNSCache, "dns-cache", true, "Cache DNS answers for as long as their TTL allows, saves lookups when domains repeat in the input")
+	checkCmd.Flags().IntVar(&checkSettings.Check.DNSCacheSize, "dns-cache-size", resolver.DefaultMaxEntries, "The maximum number of cached DNS answers")
+	checkCmd.Flags().StringVar(&checkSettings.Check.DisposableList, "disposable-list", "", "File with disposable domains to flag, one per line. MX hosts are prefixed with 'mx:'")
 	checkCmd.Flags().DurationVar(&checkSettings.Check.TTL, "ttl", 30*time.Second, "Max duration per check, e.g.: '2s' or '100ms'. When exceeded, a check is considered invalid")
 	checkCmd.Flags().BoolVar(&checkSettings.Check.InputIsEmailAddress, "input-is-email", false, "If the input isn't an e-mail address, don't fall back on domain only checks")
+	checkCmd.Flags().BoolVar(&checkSettings.Check.TLDCheck, "tld-check", true, "Reject domains with an unknown top-level domain (e.g.: 'example.cmo'), without a DNS lookup")
+	checkCmd.Flags().StringVar(&checkSettings.Check.TLDList, "tld-list", "", "File with top-level domains in the format of the IANA list, replaces the embedded list")
+	checkCmd.Flags().StringSliceVar(&checkSettings.Check.MXAllow, "mx-allow", nil, "Classes of unroutable MX addresses to accept: 'loopback', 'private', 'link_local', 'documentation', 'unspecified' or 'sinkhole'")
+	checkCmd.Flags().StringSliceVar(&checkSettings.Check.MXSinkholes, "mx-sinkhole", nil, "Addresses or networks (CIDR) of sinkholes, MX hosts resolving to them are rejected. Repeatable")
+	checkCmd.Flags().BoolVar(&checkSettings.Check.Posture, "posture", false, "Look up the SPF, DMARC, MTA-STS and DANE records of the domain. DANE requires --resolver")
+	checkCmd.Flags().StringSliceVar(&checkSettings.Check.RoleAccounts, "role-accounts", validator.DefaultRoleAccounts, "Local parts to flag as role or system account, e.g.: 'info,noreply'. An empty value disables the check")
+	checkCmd.Flags().BoolVar(&checkSettings.Check.Diagnostics, "diagnostics", false, "Include the evidence behind each result: MX hosts, resolved addresses, SMTP replies and timings")
+	checkCmd.Flags().StringVar(&checkSettings.Check.Depth, "depth", checkDepthLookup, "How far to check: 'syntax', 'lookup', 'connect' or 'rcpt'. Connecting requires outbound access on port 25")
+	checkCmd.Flags().StringVar(&checkSettings.Check.Syntax, "syntax", checkSyntaxStrict, "Which address forms to accept: 'strict' or 'rfc5322', which adds quoted local parts and address literals (e.g.: john@[192.0.2.1])")
+	checkCmd.Flags().StringVar(&checkSettings.Check.HELOName, "helo-name", "", "The fully-qualified name to identify with in HELO/EHLO when probing recipients, defaults to the host name or else the local address")
+	checkCmd.Flags().StringVar(&checkSettings.Check.MailFrom, "mail-from", "", "The sender to use in MAIL FROM when probing recipients, defaults to the null sender")
+	checkCmd.Flags().BoolVar(&checkSettings.Check.StartTLS, "starttls", false, "Upgrade the connection with STARTTLS when the MX advertises it, before probing recipients")
+	checkCmd.Flags().BoolVar(&checkSettings.Check.StartTLSVerify, "starttls-verify", false, "Verify the certificate of the MX host on STARTTLS, a certificate that doesn't match fails the probe")
+	checkCmd.Flags().UintSliceVar(&checkSettings.Check.ConnectPorts, "connect-ports", []uint{25, 587, 2525, 465}, "Ports to try, in order, on each MX host when connecting. Port 465 expects implicit TLS")
 	checkCmd.Flags().Uint64Var(&checkSettings.Workers, "workers", 50, "The number of concurrent workers to use when in piped mode (1-1024)")
 }
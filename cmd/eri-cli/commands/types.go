@@ -2,9 +2,10 @@ package commands
 
 import (
 	"fmt"
-	"net"
 	"strings"
 	"time"
+
+	"github.com/Dynom/ERI/validator"
 )
 
 type ReportStats struct {
@@ -14,11 +15,19 @@ type ReportStats struct {
 }
 
 type CheckResultFull struct {
-	Input   string   `json:"input"`
-	Valid   bool     `json:"valid"`
-	Checks  []string `json:"checks_run"`
-	Passed  []string `json:"checks_passed"`
-	Version uint     `json:"version"`
+	Input       string   `json:"input"`
+	Valid       bool     `json:"valid"`
+	Checks      []string `json:"checks_run"`
+	Passed      []string `json:"checks_passed"`
+	Disposable  bool     `json:"disposable"`
+	NullMX      bool     `json:"null_mx"`
+	RoleAccount bool     `json:"role_account"`
+	Recipient   string   `json:"recipient"`
+	Reason      string   `json:"reason,omitempty"`
+	Version     uint     `json:"version"`
+
+	Posture     *validator.Posture     `json:"posture,omitempty"`
+	Diagnostics *validator.Diagnostics `json:"diagnostics,omitempty"`
 }
 
 func (c CheckResultFull) String() string {
@@ -41,6 +50,28 @@ func (c CheckResultFull) String() string {
 	f("%-7s ", valid)
 	f("Checks:%-27s ", fmt.Sprintf("%+v", c.Checks))
 	f("Passed:%-27s ", fmt.Sprintf("%+v", c.Passed))
+	if c.Disposable {
+		f("disposable ")
+	}
+
+	if c.NullMX {
+		f("null-mx ")
+	}
+
+	if c.RoleAccount {
+		f("role-account ")
+	}
+
+	f("Recipient:%-10s ", c.Recipient)
+
+	if c.Reason != "" {
+		f("Reason:%s ", c.Reason)
+	}
+
+	if c.Posture != nil {
+		f("Posture:%s ", postureSummary(c.Posture))
+	}
+
 	f("Version:%d ", c.Version)
 
 	f("%s", c.Input)
@@ -48,6 +79,32 @@ func (c CheckResultFull) String() string {
 	return result.String()
 }
 
+// postureSummary lists the mail-authentication records the domain publishes, e.g.: "spf(-all),dmarc(reject)"
+func postureSummary(p *validator.Posture) string {
+	var published []string
+	if p.SPF.Record != "" {
+		published = append(published, "spf("+p.SPF.All+")")
+	}
+
+	if p.DMARC.Record != "" {
+		published = append(published, "dmarc("+p.DMARC.Policy+")")
+	}
+
+	if p.MTASTS.Record != "" {
+		published = append(published, "mta-sts("+p.MTASTS.Mode+")")
+	}
+
+	if len(p.DANE.TLSA) > 0 {
+		published = append(published, "dane")
+	}
+
+	if len(published) == 0 {
+		return "none"
+	}
+
+	return strings.Join(published, ",")
+}
+
 type CheckSettings struct {
 	Format  string
 	CSV     csvOptions
@@ -56,9 +113,27 @@ type CheckSettings struct {
 }
 
 type checkOptions struct {
-	Resolver            net.IP
+	Resolvers           []string
+	ResolverStrategy    string
+	DisposableList      string
+	TLDCheck            bool
+	TLDList             string
+	MXAllow             []string
+	MXSinkholes         []string
+	Posture             bool
 	TTL                 time.Duration
 	InputIsEmailAddress bool
+	Depth               string
+	Syntax              string
+	HELOName            string
+	MailFrom            string
+	StartTLS            bool
+	StartTLSVerify      bool
+	ConnectPorts        []uint
+	RoleAccounts        []string
+	Diagnostics         bool
+	DNSCache            bool
+	DNSCacheSize        int
 }
 
 type csvOptions struct {
@@ -1,23 +1,63 @@
 package commands
 
 import (
-	"context"
 	"net"
+	"os"
+
+	"github.com/Dynom/ERI/validator"
+	"github.com/Dynom/ERI/validator/resolver"
 )
 
-func setCustomResolver(dialer *net.Dialer, ip net.IP) {
-	if dialer == nil {
-		dialer = &net.Dialer{}
+// setCustomResolver makes the dialer resolve host names using pool
+func setCustomResolver(dialer *net.Dialer, pool resolver.Transport) {
+	dialer.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial:     resolver.DialFunc(pool),
 	}
+}
 
-	if dialer.Resolver == nil {
-		dialer.Resolver = &net.Resolver{
-			PreferGo: true,
-		}
+// createCachingResolver creates a resolver that caches up to size answers. With custom resolvers, they're queried
+// directly so that the TTLs of the answers are known. Otherwise the system's resolver is used, with a fixed TTL.
+func createCachingResolver(pool *resolver.Pool, size int) *resolver.Cache {
+	var upstream resolver.Upstream
+	if pool != nil {
+		upstream = resolver.NewClient(pool)
+	} else {
+		upstream = resolver.FixedTTL(net.DefaultResolver, resolver.DefaultTTL)
 	}
 
-	dialer.Resolver.Dial = func(ctx context.Context, network, address string) (conn net.Conn, e error) {
-		d := net.Dialer{}
-		return d.DialContext(ctx, network, net.JoinHostPort(ip.String(), `53`))
+	return resolver.NewCache(upstream, resolver.WithMaxEntries(size))
+}
+
+func loadDisposableList(fileName string) (*validator.DisposableList, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
 	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	list := validator.NewDisposableList(nil, nil)
+	return list, list.Load(f)
+}
+
+// loadTLDList returns the embedded list of top-level domains, replaced by the content of fileName when it's defined
+func loadTLDList(fileName string) (*validator.TLDList, error) {
+	list := validator.NewTLDList()
+	if fileName == "" {
+		return list, nil
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return list, list.Load(f)
 }
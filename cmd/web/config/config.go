@@ -18,6 +18,10 @@ var (
 
 	LFJSON LogFormat = "json"
 	LGText LogFormat = "text"
+
+	RSMap     RecipientStorage = "map"
+	RSCompact RecipientStorage = "compact"
+	RSCount   RecipientStorage = "count"
 )
 
 func NewConfig(fileName string) (Config, error) {
@@ -63,15 +67,60 @@ type Config struct {
 		Format LogFormat `toml:"format" usage:"The log output format \"json\" or \"text\""`
 	} `toml:"log"`
 	Hash struct {
-		Key string `toml:"key"`
+		Key         string `toml:"key"`
+		KeyID       int    `toml:"keyID" usage:"Identifies the key in the persisted recipients, 1-31. Increase it when rotating the key"`
+		Rekey       bool   `toml:"rekey" usage:"Re-key the persisted recipients that were written before they had a key ID, at start-up"`
+		LegacyKey   string `toml:"legacyKey" usage:"The key the recipients to re-key were written with, defaults to key"`
+		DropRetired bool   `toml:"dropRetired" usage:"Remove the persisted recipients of a previous key ID, at start-up"`
 	} `toml:"hash"`
+	HitList struct {
+		MaxDomains       int              `toml:"maxDomains" usage:"The maximum number of domains kept in memory, 0 means no limit"`
+		MaxRecipients    int              `toml:"maxRecipients" usage:"The maximum number of recipients kept in memory, of all domains combined. 0 means no limit"`
+		SweepInterval    Duration         `toml:"sweepInterval" usage:"Interval to remove expired domains and recipients with, 0 disables it"`
+		RecipientStorage RecipientStorage `toml:"recipientStorage" usage:"How recipients are kept in memory \"map\", \"compact\" or \"count\""`
+		TTL              struct {
+			Valid     Duration `toml:"valid" usage:"How long the result of a domain that passed its checks is used"`
+			Invalid   Duration `toml:"invalid" usage:"How long the result of a domain that failed its checks (e.g. NXDOMAIN or no MX) is used"`
+			Recipient Duration `toml:"recipient" usage:"How long a recipient is kept"`
+			FollowDNS bool     `toml:"followDNS" usage:"Use the result of a domain no longer than the TTL of its MX records, requires [validator.cache]"`
+		} `toml:"ttl"`
+	} `toml:"hitList"`
 	Finder struct {
 		UseBuckets      bool    `toml:"useBuckets" usage:"Buckets speedup matching, but assumes no mistakes are made at the start"`
 		LengthTolerance float64 `toml:"lengthTolerance" usage:"percentage, number 0.0-1.0, of length difference to consider"`
 	} `toml:"finder"`
 	Validator struct {
-		Resolver         string        `toml:"resolver" usage:"The resolver to use for DNS lookups"`
+		Resolver         string        `toml:"resolver" usage:"The resolver to use for DNS lookups, a host or a tls:// (DoT) or https:// (DoH) URL"`
+		Resolvers        []string      `toml:"resolvers" usage:"Resolvers to use for DNS lookups, tried after resolver. A port is optional, e.g.: \"[2001:db8::1]:5353\""`
+		ResolverStrategy string        `toml:"resolverStrategy" usage:"How multiple resolvers are used: \"failover\" (default) or \"race\" the first two"`
 		SuggestValidator ValidatorType `toml:"suggest"`
+		Disposable       struct {
+			List    string   `toml:"list" usage:"Path to a list of disposable domains, one per line. MX hosts are prefixed with \"mx:\""`
+			Refresh Duration `toml:"refresh" usage:"Interval to reload the disposable list with, 0 disables reloading"`
+		} `toml:"disposable"`
+		TLD struct {
+			Enable  bool     `toml:"enable" usage:"Reject domains with an unknown top-level domain, without a DNS lookup"`
+			List    string   `toml:"list" usage:"Path to a list of top-level domains in the format of the IANA list, replaces the embedded list"`
+			Refresh Duration `toml:"refresh" usage:"Interval to reload the TLD list with, 0 disables reloading"`
+		} `toml:"tld"`
+		PublicSuffix struct {
+			List    string   `toml:"list" usage:"Path to a Public Suffix List, replaces the embedded list"`
+			Refresh Duration `toml:"refresh" usage:"Interval to reload the Public Suffix List with, 0 disables reloading"`
+		} `toml:"publicSuffix"`
+		MXAddress struct {
+			Allow     []string `toml:"allow" usage:"Classes of unroutable MX addresses to accept: loopback, private, link_local, documentation, unspecified or sinkhole"`
+			Sinkholes []string `toml:"sinkholes" usage:"Addresses or networks (CIDR) of sinkholes, MX hosts resolving to them are rejected"`
+		} `toml:"mxAddress"`
+		Posture struct {
+			Enable bool `toml:"enable" usage:"Look up the SPF, DMARC, MTA-STS and DANE records of the domain, adds DNS lookups and an HTTPS request to a check"`
+		} `toml:"posture"`
+		RoleAccounts []string `toml:"roleAccounts" usage:"Local parts of role or system accounts (e.g. \"info\"), replaces the built-in list"`
+		Cache        struct {
+			Enable      bool     `toml:"enable" usage:"Cache DNS answers for as long as their TTL allows"`
+			MaxEntries  int      `toml:"maxEntries" usage:"The maximum number of cached DNS answers"`
+			MaxTTL      Duration `toml:"maxTTL" usage:"Caps the TTL of cached DNS answers"`
+			NegativeTTL Duration `toml:"negativeTTL" usage:"Caps the TTL of cached \"not found\" answers, 0 disables caching them"`
+		} `toml:"cache"`
 	} `toml:"validator" flag:",inline" env:",inline"`
 	Services struct {
 		Autocomplete struct {
@@ -109,10 +158,21 @@ const (
 	valueMask = "**masked**"
 )
 
+// ResolverAddresses returns the configured resolvers, in the order they're to be used
+func (c Config) ResolverAddresses() []string {
+	var addresses []string
+	if c.Validator.Resolver != "" {
+		addresses = append(addresses, c.Validator.Resolver)
+	}
+
+	return append(addresses, c.Validator.Resolvers...)
+}
+
 // GetSensored returns a copy of Config with all sensitive values masked
 func (c Config) GetSensored() Config {
 	c.Backend.URL = valueMask
 	c.Hash.Key = valueMask
+	c.Hash.LegacyKey = valueMask
 	c.Server.Profiler.Prefix = valueMask
 
 	return c
@@ -273,3 +333,28 @@ func (vt *LogFormat) UnmarshalText(value []byte) error {
 	expected := strings.Join(validTypes, ", ")
 	return fmt.Errorf("unsupported value %q for log format. Expected one of: %q", value, expected)
 }
+
+type RecipientStorage string
+
+func (rs RecipientStorage) String() string {
+	return string(rs)
+}
+
+func (rs *RecipientStorage) Set(v string) error {
+	*rs = RecipientStorage(v)
+	return nil
+}
+
+func (rs *RecipientStorage) UnmarshalText(value []byte) error {
+	validTypes := []string{string(RSMap), string(RSCompact), string(RSCount)}
+	v := string(value)
+	for _, t := range validTypes {
+		if t == v {
+			*rs = RecipientStorage(v)
+			return nil
+		}
+	}
+
+	expected := strings.Join(validTypes, ", ")
+	return fmt.Errorf("unsupported value %q for recipient storage. Expected one of: %q", value, expected)
+}
@@ -497,6 +497,7 @@ func TestConfig_GetSensored(t *testing.T) {
 	exp := Config{}
 	exp.Backend.URL = valueMask
 	exp.Hash.Key = valueMask
+	exp.Hash.LegacyKey = valueMask
 	exp.Server.Profiler.Prefix = valueMask
 
 	tests := []struct {
@@ -559,3 +560,29 @@ func TestConfig_String(t *testing.T) {
 		})
 	}
 }
+
+func TestRecipientStorage_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    RecipientStorage
+		wantErr bool
+	}{
+		{name: "map", value: "map", want: RSMap},
+		{name: "compact", value: "compact", want: RSCompact},
+		{name: "count", value: "count", want: RSCount},
+		{name: "unsupported value", value: "hll", want: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rs RecipientStorage
+			if err := rs.UnmarshalText([]byte(tt.value)); (err != nil) != tt.wantErr {
+				t.Errorf("UnmarshalText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if rs != tt.want {
+				t.Errorf("Expected UnmarshalText(%q) to result in %q, got %q", tt.value, tt.want, rs)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package erihttp
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/Dynom/ERI/validator"
+)
 
 var (
 	ErrMissingBody            = errors.New("missing body")
@@ -30,8 +34,16 @@ func (r *AutoCompleteResponse) PrepareResponse() {
 type SuggestResponse struct {
 	Alternatives    []string `json:"alternatives"`
 	MalformedSyntax bool     `json:"malformed_syntax"`
+	UnknownTLD      bool     `json:"unknown_tld"`
 	MisconfiguredMX bool     `json:"misconfigured_mx"`
-	Error           string   `json:"error,omitempty"`
+	Disposable      bool     `json:"disposable"`
+	RoleAccount     bool     `json:"role_account"`
+	Recipient       string   `json:"recipient"`
+	Canonical       string   `json:"canonical"`
+
+	// Posture is only set when enabled, see [validator.posture] in the configuration
+	Posture *validator.Posture `json:"posture,omitempty"`
+	Error   string             `json:"error,omitempty"`
 }
 
 func (r *SuggestResponse) PrepareResponse() {
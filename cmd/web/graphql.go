@@ -24,6 +24,31 @@ func NewGraphQLSchema(conf config.Config, suggestSvc *services.SuggestSvc, autoc
 				Description: "Boolean value that when true, means the address can't be valid. Conversely when false, doesn't mean it is.",
 				Type:        graphql.NewNonNull(graphql.Boolean),
 			},
+
+			"unknownTLD": &graphql.Field{
+				Description: "Boolean value that when true, means the top-level domain doesn't exist (e.g. \"example.cmo\"). The syntax is malformed as well.",
+				Type:        graphql.NewNonNull(graphql.Boolean),
+			},
+
+			"disposable": &graphql.Field{
+				Description: "Boolean value that when true, means the domain is known to offer disposable (throw-away) addresses.",
+				Type:        graphql.NewNonNull(graphql.Boolean),
+			},
+
+			"roleAccount": &graphql.Field{
+				Description: "Boolean value that when true, means the local part belongs to a role or system account (e.g. \"info\" or \"noreply\"), typically a shared mailbox.",
+				Type:        graphql.NewNonNull(graphql.Boolean),
+			},
+
+			"recipient": &graphql.Field{
+				Description: "The state of the recipient: \"unknown\", \"valid\", \"rejected\" or \"accept-all\" when the domain accepts any recipient.",
+				Type:        graphql.NewNonNull(graphql.String),
+			},
+
+			"canonical": &graphql.Field{
+				Description: "The canonical form of the address, different forms of the same mailbox (e.g. \"john.doe+news@gmail.com\" and \"johndoe@googlemail.com\") share it. Empty when the input can't be split.",
+				Type:        graphql.NewNonNull(graphql.String),
+			},
 		},
 		Description: "",
 	})
@@ -69,7 +94,12 @@ func NewGraphQLSchema(conf config.Config, suggestSvc *services.SuggestSvc, autoc
 				return erihttp.SuggestResponse{
 					Alternatives:    result.Alternatives,
 					MalformedSyntax: errors.Is(sugErr, validator.ErrEmailAddressSyntax),
+					UnknownTLD:      result.UnknownTLD,
 					MisconfiguredMX: !result.HasValidMX,
+					Disposable:      result.Disposable,
+					RoleAccount:     result.RoleAccount,
+					Recipient:       string(result.Recipient),
+					Canonical:       result.Canonical,
 				}, err
 			},
 			Description: "Get suggestions",
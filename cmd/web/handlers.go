@@ -152,7 +152,13 @@ func NewSuggestHandler(logger logrus.FieldLogger, svc *services.SuggestSvc, maxB
 		sr := erihttp.SuggestResponse{
 			Alternatives:    alts,
 			MalformedSyntax: errors.Is(sugErr, validator.ErrEmailAddressSyntax),
+			UnknownTLD:      result.UnknownTLD,
 			MisconfiguredMX: !result.HasValidMX,
+			Disposable:      result.Disposable,
+			RoleAccount:     result.RoleAccount,
+			Recipient:       string(result.Recipient),
+			Canonical:       result.Canonical,
+			Posture:         result.Posture,
 		}
 
 		if sugErr != nil {
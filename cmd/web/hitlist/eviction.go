@@ -0,0 +1,164 @@
+package hitlist
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Sweep removes the recipients that expired, and the domains of which the result expired that have no recipients left.
+// A domain in use keeps its expired result, which is checked again before it's used, see GetDomainValidationDetails.
+// It returns the number of removed domains and recipients.
+func (hl *HitList) Sweep() (domains, recipients int) {
+	now := time.Now()
+
+	var evicted []Domain
+	for _, s := range hl.shards {
+		s.lock.Lock()
+
+		var removed, removedRecipients int
+		for domain, hit := range s.hits {
+			removedRecipients += hit.Recipients.expire(now)
+
+			if hit.ValidUntil.Before(now) && hit.Recipients.len() == 0 {
+				removed++
+				evicted = append(evicted, domain)
+				delete(s.hits, domain)
+			}
+		}
+
+		atomic.AddInt64(&hl.domains, -int64(removed))
+		atomic.AddInt64(&hl.recipients, -int64(removedRecipients))
+		s.lock.Unlock()
+
+		recipients += removedRecipients
+	}
+
+	hl.notify(evicted)
+
+	return len(evicted), recipients
+}
+
+// isFull returns true when the maximum number of domains is reached
+func (hl *HitList) isFull() bool {
+	return hl.maxDomains > 0 && atomic.LoadInt64(&hl.domains) >= int64(hl.maxDomains)
+}
+
+// makeRoomForDomain evicts domains when the maximum is reached, so that another domain can be added. The domains with
+// the fewest recipients go first and, among those, the ones that were seen least recently. Domains are evicted in
+// batches, down to 90% of the maximum, so that a full HitList isn't sorted for every domain that is added. No shard
+// lock must be held.
+func (hl *HitList) makeRoomForDomain() []Domain {
+	hl.lockAll()
+	defer hl.unlockAll()
+
+	// Another call might have made room, while waiting for the locks
+	if !hl.isFull() {
+		return nil
+	}
+
+	type candidate struct {
+		shard      *shard
+		domain     Domain
+		recipients int
+		stored     int
+		validUntil time.Time
+	}
+
+	candidates := make([]candidate, 0, atomic.LoadInt64(&hl.domains))
+	for _, s := range hl.shards {
+		for domain, hit := range s.hits {
+			candidates = append(candidates, candidate{
+				shard:      s,
+				domain:     domain,
+				recipients: hit.Recipients.len(),
+				stored:     hit.Recipients.stored(),
+				validUntil: hit.ValidUntil,
+			})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.recipients == b.recipients {
+			return a.validUntil.Before(b.validUntil)
+		}
+
+		return a.recipients < b.recipients
+	})
+
+	evict := len(candidates) - lowWater(hl.maxDomains)
+	evicted := make([]Domain, 0, evict)
+	for _, c := range candidates[:evict] {
+		atomic.AddInt64(&hl.recipients, -int64(c.stored))
+		delete(c.shard.hits, c.domain)
+		evicted = append(evicted, c.domain)
+	}
+
+	atomic.AddInt64(&hl.domains, -int64(evict))
+
+	return evicted
+}
+
+// evictRecipients evicts the recipients that expire first when the maximum is exceeded, down to 90% of the maximum.
+// Domains are kept, even when they lose all of their recipients. No shard lock must be held.
+func (hl *HitList) evictRecipients() {
+	if hl.maxRecipients <= 0 || atomic.LoadInt64(&hl.recipients) <= int64(hl.maxRecipients) {
+		return
+	}
+
+	hl.lockAll()
+	defer hl.unlockAll()
+
+	if atomic.LoadInt64(&hl.recipients) <= int64(hl.maxRecipients) {
+		return
+	}
+
+	expiries := make([]time.Time, 0, atomic.LoadInt64(&hl.recipients))
+	for _, s := range hl.shards {
+		for _, hit := range s.hits {
+			expiries = hit.Recipients.expiries(expiries)
+		}
+	}
+
+	sort.Slice(expiries, func(i, j int) bool {
+		return expiries[i].Before(expiries[j])
+	})
+
+	// Recipients that expire at the same moment as the last one to evict, are evicted as well
+	cutoff := expiries[len(expiries)-lowWater(hl.maxRecipients)-1]
+
+	var removed int
+	for _, s := range hl.shards {
+		for _, hit := range s.hits {
+			removed += hit.Recipients.expire(cutoff)
+		}
+	}
+
+	atomic.AddInt64(&hl.recipients, -int64(removed))
+}
+
+// notify calls the EvictFn, when domains were evicted
+func (hl *HitList) notify(evicted []Domain) {
+	if len(evicted) == 0 {
+		return
+	}
+
+	hl.evictLock.Lock()
+	fn := hl.onEvict
+	hl.evictLock.Unlock()
+
+	if fn != nil {
+		fn(evicted)
+	}
+}
+
+// lowWater returns the size to evict down to, leaving room for at least one more entry
+func lowWater(max int) int {
+	n := max - max/10
+	if n >= max {
+		n = max - 1
+	}
+
+	return n
+}
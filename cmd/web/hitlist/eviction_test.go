@@ -0,0 +1,185 @@
+package hitlist
+
+import (
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+var validDomainVR = validator.Result{
+	Validations: validations.Validations(validations.FValid | validations.FSyntax | validations.FMXLookup),
+}
+
+func TestHitList_Sweep(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour)
+
+	_ = hl.AddDeadline(types.NewEmailFromParts("john", "example.org"), validDomainVR, -time.Hour)
+	_ = hl.AddDeadline(types.NewEmailFromParts("jane", "example.org"), validDomainVR, -time.Hour)
+	_ = hl.AddDeadline(types.NewEmailFromParts("john", "example.com"), validDomainVR, -time.Hour)
+	_ = hl.AddDeadline(types.NewEmailFromParts("jane", "example.com"), validDomainVR, time.Hour)
+	_ = hl.AddDomain("example.net", validDomainVR)
+
+	var notified []Domain
+	hl.OnEvict(func(domains []Domain) {
+		// Called without holding the lock
+		_ = hl.GetValidAndUsageSortedDomains()
+		notified = append(notified, domains...)
+	})
+
+	domains, recipients := hl.Sweep()
+	if domains != 1 || recipients != 3 {
+		t.Errorf("Expected 1 domain and 3 recipients to be removed, got %d and %d", domains, recipients)
+	}
+
+	if !reflect.DeepEqual(notified, []Domain{"example.org"}) {
+		t.Errorf("Expected the expired domain to be reported, got %v", notified)
+	}
+
+	if _, local := hl.Has(types.NewEmailFromParts("john", "example.com")); local {
+		t.Errorf("Expected the expired recipient to be removed")
+	}
+
+	if _, local := hl.Has(types.NewEmailFromParts("jane", "example.com")); !local {
+		t.Errorf("Expected the valid recipient to be kept")
+	}
+
+	if got := hl.GetValidAndUsageSortedDomains(); !reflect.DeepEqual(got, []string{"example.com", "example.net"}) {
+		t.Errorf("Expected the valid domains to be kept, got %v", got)
+	}
+
+	if atomic.LoadInt64(&hl.recipients) != 1 {
+		t.Errorf("Expected 1 recipient to be counted, got %d", atomic.LoadInt64(&hl.recipients))
+	}
+
+	if domains, recipients := hl.Sweep(); domains != 0 || recipients != 0 {
+		t.Errorf("Expected nothing to be removed, got %d and %d", domains, recipients)
+	}
+}
+
+func TestHitList_Sweep_keepsDomainsInUse(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour, WithTTLs(TTLs{Valid: time.Minute, Recipient: time.Hour}))
+
+	// E.g. a short MX TTL, while the recipients are still live
+	_ = hl.Add(types.NewEmailFromParts("john", "example.org"), validDomainVR)
+	_ = hl.Add(types.NewEmailFromParts("jane", "example.org"), validDomainVR)
+	_ = hl.AddDeadline(types.NewEmailFromParts("", "example.org"), validDomainVR, -time.Minute)
+
+	if domains, recipients := hl.Sweep(); domains != 0 || recipients != 0 {
+		t.Errorf("Expected nothing to be removed, got %d domains and %d recipients", domains, recipients)
+	}
+
+	if got := hl.GetRecipientCount("example.org"); got != 2 {
+		t.Errorf("Expected the recipients to be kept, got %d", got)
+	}
+
+	details, ok := hl.GetDomainValidationDetails("example.org")
+	if !ok || details.ValidUntil.After(time.Now()) {
+		t.Errorf("Expected the domain to be kept with its expired result, got %t %s", ok, details.ValidUntil)
+	}
+
+	if got := hl.GetValidAndUsageSortedDomains(); !reflect.DeepEqual(got, []string{"example.org"}) {
+		t.Errorf("Expected the domain to remain listed, got %v", got)
+	}
+
+	// Once the recipients expired as well, the domain goes
+	_ = hl.AddDeadline(types.NewEmailFromParts("john", "example.org"), validDomainVR, -time.Minute)
+	_ = hl.AddDeadline(types.NewEmailFromParts("jane", "example.org"), validDomainVR, -time.Minute)
+
+	if domains, recipients := hl.Sweep(); domains != 1 || recipients != 2 {
+		t.Errorf("Expected 1 domain and 2 recipients to be removed, got %d and %d", domains, recipients)
+	}
+}
+
+func TestHitList_WithMaxDomains(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour, WithMaxDomains(3))
+
+	var notified []Domain
+	hl.OnEvict(func(domains []Domain) {
+		notified = append(notified, domains...)
+	})
+
+	// Popular domains have more recipients
+	for _, local := range []string{"john", "jane", "jake"} {
+		_ = hl.AddEmailAddress(local+"@popular.example", validDomainVR)
+	}
+
+	_ = hl.AddEmailAddress("john@unpopular.example", validDomainVR)
+	_ = hl.AddEmailAddress("john@known.example", validDomainVR)
+	_ = hl.AddEmailAddress("jane@known.example", validDomainVR)
+
+	// The HitList is full, the least popular domain makes room
+	_ = hl.AddEmailAddress("john@new.example", validDomainVR)
+
+	if !reflect.DeepEqual(notified, []Domain{"unpopular.example"}) {
+		t.Errorf("Expected the least popular domain to be evicted, got %v", notified)
+	}
+
+	got := hl.GetValidAndUsageSortedDomains()
+	sort.Strings(got)
+	if want := []string{"known.example", "new.example", "popular.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the domains %v, got %v", want, got)
+	}
+
+	if atomic.LoadInt64(&hl.recipients) != 6 {
+		t.Errorf("Expected 6 recipients to be counted, got %d", atomic.LoadInt64(&hl.recipients))
+	}
+}
+
+func TestHitList_WithMaxRecipients(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour, WithMaxRecipients(10))
+
+	// Recipients seen earlier expire earlier
+	for i := 1; i <= 10; i++ {
+		local := string(rune('a' + i))
+		_ = hl.AddDeadline(types.NewEmailFromParts(local, "example.org"), validDomainVR, time.Duration(i)*time.Minute)
+	}
+
+	_ = hl.AddDeadline(types.NewEmailFromParts("z", "example.com"), validDomainVR, time.Hour)
+
+	if atomic.LoadInt64(&hl.recipients) != 9 {
+		t.Errorf("Expected 9 recipients, got %d", atomic.LoadInt64(&hl.recipients))
+	}
+
+	if got := hl.GetRecipientCount("example.org"); got != 8 {
+		t.Errorf("Expected 8 recipients to remain, got %d", got)
+	}
+
+	for _, local := range []string{"b", "c"} {
+		domain, known := hl.Has(types.NewEmailFromParts(local, "example.org"))
+		if !domain {
+			t.Errorf("Expected the domain of %q to be kept", local)
+		}
+
+		if known {
+			t.Errorf("Expected %q to be evicted, it expires first", local)
+		}
+	}
+
+	if _, local := hl.Has(types.NewEmailFromParts("z", "example.com")); !local {
+		t.Errorf("Expected the recipient that was just added to be kept")
+	}
+}
+
+func Test_lowWater(t *testing.T) {
+	tests := []struct {
+		max  int
+		want int
+	}{
+		{max: 1, want: 0},
+		{max: 2, want: 1},
+		{max: 10, want: 9},
+		{max: 1000, want: 900},
+	}
+
+	for _, tt := range tests {
+		if got := lowWater(tt.max); got != tt.want {
+			t.Errorf("lowWater(%d) = %d, want %d", tt.max, got, tt.want)
+		}
+	}
+}
@@ -2,10 +2,9 @@ package hitlist
 
 import (
 	"errors"
-	"hash"
 	"sort"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Dynom/ERI/types"
@@ -21,7 +20,7 @@ type (
 	Hits   map[Domain]Hit
 	Domain string
 	Hit    struct {
-		Recipients       map[rcpt]struct{}
+		Recipients       recipients // The recipients, with the moment they expire. See WithRecipientStorage
 		ValidUntil       time.Time
 		ValidationResult validator.Result
 	}
@@ -32,22 +31,78 @@ type (
 	rcpt      string
 )
 
-func New(h hash.Hash, ttl time.Duration) *HitList {
+// NewDomain returns the Domain of a domain name, aliases of a provider share a single Domain. See types.Canonicalizer
+func NewDomain(d string) Domain {
+	return Domain(types.DefaultCanonicalizer.Domain(d))
+}
+
+// Option configures a HitList
+type Option func(*HitList)
+
+// EvictFn is called with the domains that were removed from the HitList, either because they expired or to stay
+// within the maximum number of domains
+type EvictFn func(domains []Domain)
+
+// WithMaxDomains limits the number of domains, 0 means no limit. Concurrent calls that each add a domain can exceed it
+// by one. See makeRoomForDomain for the policy.
+func WithMaxDomains(max int) Option {
+	return func(hl *HitList) {
+		hl.maxDomains = max
+	}
+}
+
+// WithMaxRecipients limits the number of recipients of all domains combined, 0 means no limit. See evictRecipients
+// for the policy.
+func WithMaxRecipients(max int) Option {
+	return func(hl *HitList) {
+		hl.maxRecipients = max
+	}
+}
+
+// New creates a HitList, recipients are pseudonymised with p. See NewKeys. Results remain valid for ttl, unless
+// WithTTLs defines a TTL for their outcome.
+func New(p Pseudonymizer, ttl time.Duration, options ...Option) *HitList {
 	l := HitList{
-		hits: make(Hits),
-		lock: sync.RWMutex{},
-		h:    h,
-		ttl:  ttl,
+		pseudonymizer: p,
+		ttl:           ttl,
+	}
+
+	for _, o := range options {
+		o(&l)
+	}
+
+	if l.shards == nil {
+		l.shards = newShards(defaultShards)
 	}
 
 	return &l
 }
 
+// HitList holds the domains and recipients that were seen. Domains are spread over shards, each with their own lock,
+// so that concurrent calls for different domains don't wait on one another. See WithShards.
 type HitList struct {
-	hits Hits
-	ttl  time.Duration
-	lock sync.RWMutex
-	h    hash.Hash
+	domains    int64 // The number of domains, accessed atomically
+	recipients int64 // The number of recipients stored of all domains, accessed atomically
+
+	shards        []*shard
+	ttl           time.Duration
+	ttls          TTLs
+	dnsTTL        DNSTTLFn
+	pseudonymizer Pseudonymizer
+
+	storage       RecipientStorage
+	maxDomains    int
+	maxRecipients int
+	evictLock     sync.Mutex
+	onEvict       EvictFn
+}
+
+// OnEvict sets the function that is called after domains were removed, e.g. to keep a dictionary of the domains
+// consistent. It's called without holding a lock on the HitList.
+func (hl *HitList) OnEvict(fn EvictFn) {
+	hl.evictLock.Lock()
+	hl.onEvict = fn
+	hl.evictLock.Unlock()
 }
 
 // Has returns true if HitList knows about (part of) the argument
@@ -58,20 +113,20 @@ func (hl *HitList) Has(parts types.EmailParts) (domain, local bool) {
 		return
 	}
 
-	inputDomain := Domain(strings.ToLower(parts.Domain))
-
-	hl.lock.RLock()
-	defer hl.lock.RUnlock()
+	canonical := types.DefaultCanonicalizer.Address(parts)
+	inputDomain := Domain(canonical.Domain)
 
-	if hit, domain = hl.hits[inputDomain]; domain {
-		if parts.Local == "" {
-			return
-		}
+	var recipient rcpt
+	if canonical.Local != "" {
+		recipient = rcpt(hl.pseudonymizer.Recipient(canonical.Local))
+	}
 
-		inputLocal := strings.ToLower(parts.Local)
-		recipient := rcpt(hl.h.Sum([]byte(inputLocal)))
+	s := hl.shard(inputDomain)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
 
-		_, local = hit.Recipients[recipient]
+	if hit, domain = s.hits[inputDomain]; domain && recipient != "" {
+		local = hit.Recipients.has(recipient)
 	}
 
 	return
@@ -79,7 +134,8 @@ func (hl *HitList) Has(parts types.EmailParts) (domain, local bool) {
 
 // CreateInternalTypes returns the Recipient and Domain types for an Email Type Parts. It's stateless, and solely
 // works on the input. The input is not allowed to have empty parts. Typical use-case is when wanting to persist the
-// "safe" value, to later re-add to a HitList
+// "safe" value, to later re-add to a HitList. Both are created from the canonical address, so that the different forms
+// of a mailbox (e.g.: "john.doe+news@gmail.com" and "johndoe@googlemail.com") are a single recipient.
 func (hl *HitList) CreateInternalTypes(p types.EmailParts) (domain Domain, recipient Recipient, err error) {
 	if len(p.Domain) == 0 || len(p.Local) == 0 {
 		recipient = Recipient("")
@@ -87,16 +143,14 @@ func (hl *HitList) CreateInternalTypes(p types.EmailParts) (domain Domain, recip
 		return
 	}
 
-	domain = Domain(strings.ToLower(p.Domain))
-	recipient = hl.h.Sum([]byte(strings.ToLower(p.Local)))
+	canonical := types.DefaultCanonicalizer.Address(p)
+	domain = Domain(canonical.Domain)
+	recipient = hl.pseudonymizer.Recipient(canonical.Local)
 	return
 }
 
 func (hl *HitList) GetDomainValidationDetails(d Domain) (validator.Details, bool) {
-	hl.lock.RLock()
-	hit, ok := hl.hits[d]
-	hl.lock.RUnlock()
-
+	hit, ok := hl.lookup(d)
 	if ok {
 		return validator.Details{
 			Result:     hit.ValidationResult,
@@ -109,74 +163,131 @@ func (hl *HitList) GetDomainValidationDetails(d Domain) (validator.Details, bool
 
 // GetValidAndUsageSortedDomains returns the used domains, sorted by their associated recipients (high>low)
 func (hl *HitList) GetValidAndUsageSortedDomains() []string {
-	hl.lock.RLock()
-	domains := getValidDomains(hl.hits)
-	hl.lock.RUnlock()
+	stats := make([]domainStats, 0, atomic.LoadInt64(&hl.domains))
+	for _, s := range hl.shards {
+		s.lock.RLock()
+		stats = appendValidDomains(stats, s.hits)
+		s.lock.RUnlock()
+	}
+
+	// Sorting happens without holding a lock
+	return sortByRecipients(stats)
+}
 
-	return domains
+// RegistrableDomainStats holds the usage of a registrable domain, summed over the domains rolled up into it
+type RegistrableDomainStats struct {
+	Domain     string
+	Domains    int
+	Recipients uint64
+}
+
+// GetRegistrableDomainStats rolls the known domains up to their registrable domain (e.g.: "mail.corp.example.co.uk" to
+// "example.co.uk"), sorted by their recipients (high>low). Domains that are a public suffix themselves are kept as-is.
+func (hl *HitList) GetRegistrableDomainStats(suffixes *validator.SuffixList) []RegistrableDomainStats {
+	byDomain := make(map[string]*RegistrableDomainStats)
+
+	for _, s := range hl.shards {
+		s.lock.RLock()
+		for domain, hit := range s.hits {
+			registrable, ok := suffixes.RegistrableDomain(string(domain))
+			if !ok {
+				registrable = string(domain)
+			}
+
+			stats, exists := byDomain[registrable]
+			if !exists {
+				stats = &RegistrableDomainStats{Domain: registrable}
+				byDomain[registrable] = stats
+			}
+
+			stats.Domains++
+			stats.Recipients += uint64(hit.Recipients.len())
+		}
+		s.lock.RUnlock()
+	}
+
+	result := make([]RegistrableDomainStats, 0, len(byDomain))
+	for _, stats := range byDomain {
+		result = append(result, *stats)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Recipients == result[j].Recipients {
+			return result[i].Domain < result[j].Domain
+		}
+
+		return result[i].Recipients > result[j].Recipients
+	})
+
+	return result
 }
 
 // GetRecipientCount returns the amount of recipients known for a domain
 func (hl *HitList) GetRecipientCount(d Domain) (amount uint64) {
-	hl.lock.RLock()
-	if hit, exists := hl.hits[d]; exists {
-		amount = uint64(len(hit.Recipients))
+	s := hl.shard(d)
+	s.lock.RLock()
+	if hit, exists := s.hits[d]; exists {
+		amount = uint64(hit.Recipients.len())
 	}
-	hl.lock.RUnlock()
+	s.lock.RUnlock()
 
 	return
 }
 
+// lookup returns the Hit of a domain. The Recipients must not be accessed, other than while holding the lock of its shard.
+func (hl *HitList) lookup(d Domain) (Hit, bool) {
+	s := hl.shard(d)
+	s.lock.RLock()
+	hit, ok := s.hits[d]
+	s.lock.RUnlock()
+
+	return hit, ok
+}
+
 // AddInternalParts adds values considered "safe". Typically you would only use this on provisioning HitList from a storage layer
 func (hl *HitList) AddInternalParts(domain Domain, recipient Recipient, vr validator.Result) error {
-	return hl.AddInternalPartsDuration(domain, recipient, vr, hl.ttl)
+	hl.add(domain, recipient, vr, hl.domainTTL(domain, vr), hl.recipientTTL(), true)
+	return nil
 }
 
 // AddInternalPartsDuration adds values considered "safe". Has an extra duration option which shouldn't be negative
 func (hl *HitList) AddInternalPartsDuration(domain Domain, recipient Recipient, vr validator.Result, duration time.Duration) error {
-	hl.lock.Lock()
-	defer hl.lock.Unlock()
-
-	now := time.Now()
-	dh, ok := hl.hits[domain]
-
-	if !ok {
-
-		hl.hits[domain] = Hit{
-			Recipients:       map[rcpt]struct{}{rcpt(recipient): {}},
-			ValidUntil:       now.Add(duration),
-			ValidationResult: vr,
-		}
+	hl.add(domain, recipient, vr, duration, duration, true)
+	return nil
+}
 
-		return nil
+// Add records the result of a check. The domain remains valid for the TTL of the outcome, see WithTTLs.
+func (hl *HitList) Add(parts types.EmailParts, vr validator.Result) error {
+	domain, recipient, err := hl.internalTypes(parts)
+	if err != nil {
+		return err
 	}
 
-	dh.ValidationResult.Validations = dh.ValidationResult.Validations.MergeWithNext(vr.Validations)
-	dh.ValidationResult.Steps = dh.ValidationResult.Steps.MergeWithNext(vr.Steps)
-	dh.ValidUntil = now.Add(duration)
-	dh.Recipients[rcpt(recipient)] = struct{}{}
-
-	hl.hits[domain] = dh
-
+	hl.add(domain, recipient, vr, hl.domainTTL(domain, vr), hl.recipientTTL(), true)
 	return nil
 }
 
-func (hl *HitList) Add(parts types.EmailParts, vr validator.Result) error {
-	return hl.AddDeadline(parts, vr, hl.ttl)
+// AddCached records the result of a check that ran with the cached result of its domain, see GetDomainValidationDetails.
+// The validity of the domain isn't extended, so that it's checked again once it expires.
+func (hl *HitList) AddCached(parts types.EmailParts, vr validator.Result) error {
+	domain, recipient, err := hl.internalTypes(parts)
+	if err != nil {
+		return err
+	}
+
+	hl.add(domain, recipient, vr, hl.domainTTL(domain, vr), hl.recipientTTL(), false)
+	return nil
 }
 
 // AddDeadline is similar to Add, but allows for custom TTL. Duration shouldn't be negative.
 func (hl *HitList) AddDeadline(parts types.EmailParts, vr validator.Result, duration time.Duration) error {
-	if parts.Local == "" {
-		return hl.AddDomain(parts.Domain, vr)
-	}
-
-	domain, safeLocal, err := hl.CreateInternalTypes(parts)
+	domain, recipient, err := hl.internalTypes(parts)
 	if err != nil {
 		return err
 	}
 
-	return hl.AddInternalPartsDuration(domain, safeLocal, vr, duration)
+	hl.add(domain, recipient, vr, duration, duration, true)
+	return nil
 }
 
 // AddEmailAddress records validations for a particular e-mail address.
@@ -186,67 +297,135 @@ func (hl *HitList) AddEmailAddress(email string, vr validator.Result) error {
 		return err
 	}
 
-	return hl.AddDeadline(parts, vr, hl.ttl)
+	return hl.Add(parts, vr)
 }
 
 // AddDomain learns of a domain and it's validity.
 func (hl *HitList) AddDomain(d string, vr validator.Result) error {
-	domain := Domain(strings.ToLower(d))
+	domain := NewDomain(d)
 
 	if len(domain) == 0 {
 		return ErrInvalidDomainSyntax
 	}
 
-	hl.lock.Lock()
-	defer hl.lock.Unlock()
+	hl.add(domain, nil, vr, hl.domainTTL(domain, vr), 0, true)
+	return nil
+}
 
-	hit, ok := hl.hits[domain]
-	if !ok {
-		hl.hits[domain] = Hit{
-			Recipients:       map[rcpt]struct{}{},
-			ValidUntil:       time.Now().Add(hl.ttl),
-			ValidationResult: vr,
+// internalTypes is similar to CreateInternalTypes, but allows for parts without a local part
+func (hl *HitList) internalTypes(parts types.EmailParts) (Domain, Recipient, error) {
+	if parts.Local == "" {
+		domain := NewDomain(parts.Domain)
+		if len(domain) == 0 {
+			return "", nil, ErrInvalidDomainSyntax
 		}
 
-		return nil
+		return domain, nil, nil
 	}
 
-	hit.ValidationResult.Validations = hit.ValidationResult.Validations.MergeWithNext(vr.Validations)
-	hit.ValidationResult.Steps = hit.ValidationResult.Steps.MergeWithNext(vr.Steps)
-	hl.hits[domain] = hit
+	return hl.CreateInternalTypes(parts)
+}
 
-	return nil
+// add records vr for the domain and, unless empty, the recipient. With renew, the domain remains valid for domainTTL
+// from now on. A result that expired is replaced rather than merged with vr, so that a domain that failed (e.g. a typo
+// that was registered since) isn't held to its previous result.
+func (hl *HitList) add(domain Domain, recipient Recipient, vr validator.Result, domainTTL, recipientTTL time.Duration, renew bool) {
+	var evicted []Domain
+
+	s := hl.shard(domain)
+	s.lock.Lock()
+
+	if _, ok := s.hits[domain]; !ok && hl.isFull() {
+		// Making room locks every shard, the domain is looked up again afterwards
+		s.lock.Unlock()
+		evicted = hl.makeRoomForDomain()
+		s.lock.Lock()
+	}
+
+	now := time.Now()
+	hit, ok := s.hits[domain]
+	switch {
+	case !ok:
+		atomic.AddInt64(&hl.domains, 1)
+		hit = Hit{
+			Recipients:       hl.newRecipients(),
+			ValidationResult: withoutDiagnostics(vr),
+		}
+
+		renew = true
+
+	case renew && hit.ValidUntil.Before(now):
+		hit.ValidationResult = withoutDiagnostics(vr)
+
+	default:
+		hit.ValidationResult.Validations = hit.ValidationResult.Validations.MergeWithNext(vr.Validations)
+		hit.ValidationResult.Steps = hit.ValidationResult.Steps.MergeWithNext(vr.Steps)
+		if vr.Posture != nil {
+			hit.ValidationResult.Posture = vr.Posture
+		}
+	}
+
+	if renew {
+		hit.ValidUntil = now.Add(domainTTL)
+	}
+
+	if len(recipient) > 0 {
+		if hit.Recipients.add(rcpt(recipient), now.Add(recipientTTL)) {
+			atomic.AddInt64(&hl.recipients, 1)
+		}
+	}
+
+	s.hits[domain] = hit
+	s.lock.Unlock()
+
+	hl.evictRecipients()
+	hl.notify(evicted)
 }
 
-// getValidDomains returns domains which are valid, sorted by their recipients in descending order
-func getValidDomains(hits Hits) []string {
-	type stats struct {
-		Domain     string
-		Recipients int64
+// withoutDiagnostics strips the per-check diagnostics, which have no meaning for other checks on the same domain. The
+// posture is kept, it describes the domain.
+func withoutDiagnostics(vr validator.Result) validator.Result {
+	return validator.Result{
+		Validations: vr.Validations,
+		Steps:       vr.Steps,
+		Posture:     vr.Posture,
 	}
+}
 
-	sortStats := make([]stats, 0, len(hits))
+type domainStats struct {
+	Domain     string
+	Recipients int64
+}
+
+// appendValidDomains appends the domains which are valid to stats
+func appendValidDomains(stats []domainStats, hits Hits) []domainStats {
 	for domain, details := range hits {
 
 		if !details.ValidationResult.Validations.IsValidationsForValidDomain() {
 			continue
 		}
 
-		sortStats = append(sortStats, stats{
+		stats = append(stats, domainStats{
 			Domain:     string(domain),
-			Recipients: int64(len(details.Recipients)),
+			Recipients: int64(details.Recipients.len()),
 		})
 	}
 
+	return stats
+}
+
+// sortByRecipients returns the domains of stats, sorted by their recipients in descending order
+func sortByRecipients(stats []domainStats) []string {
+
 	// Sorting on recipient count in Descending order
-	sort.Slice(sortStats, func(i, j int) bool {
-		return sortStats[i].Recipients > sortStats[j].Recipients
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Recipients > stats[j].Recipients
 	})
 
 	// @todo Could probably be an object pool, could relieve the GC
-	result := make([]string, 0, len(sortStats))
-	for _, stats := range sortStats {
-		result = append(result, stats.Domain)
+	result := make([]string, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, s.Domain)
 	}
 
 	return result
@@ -2,14 +2,15 @@ package hitlist
 
 import (
 	"bytes"
+	"fmt"
 	"math"
 	"math/rand"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/Dynom/ERI/types"
 	"github.com/Dynom/ERI/validator"
-	"github.com/minio/highwayhash"
 )
 
 type FakeInt8 struct {
@@ -96,13 +97,13 @@ func BenchmarkMemoryUsage(b *testing.B) {
 }
 
 func BenchmarkHitlistHas(b *testing.B) {
-	h, err := highwayhash.New128([]byte("00000000000000000000000000000000"))
+	keys, err := NewKeys(1, []byte("00000000000000000000000000000000"))
 	if err != nil {
-		b.Errorf("Unable to create our hash.Hash %s", err)
+		b.Errorf("Unable to create our keys %s", err)
 		return
 	}
 
-	hl := New(mockHasher{}, time.Second*1)
+	hl := New(mockPseudonymizer{}, time.Second*1)
 
 	domains := []string{
 		"kwlwyboeei", "rasuesvqky", "lvtdvnorpe", "jyzbmzhhgt", "azuhmpiwzv", "vlefllcgkn", "cxwxgxnczu", "cnqjdfdfpf",
@@ -129,17 +130,17 @@ func BenchmarkHitlistHas(b *testing.B) {
 
 	b.ResetTimer()
 	b.SetParallelism(1)
-	b.Run("Bench nonexisting, mock hasher", func(b *testing.B) {
+	b.Run("Bench nonexisting, mock pseudonymizer", func(b *testing.B) {
 		b.ReportAllocs()
-		hl.h = mockHasher{}
+		hl.pseudonymizer = mockPseudonymizer{}
 		for i := 0; i < b.N; i++ {
 			t1l, t1d = hl.Has(ExpectNonExisting)
 		}
 	})
 
-	b.Run("Bench existing, mock hasher", func(b *testing.B) {
+	b.Run("Bench existing, mock pseudonymizer", func(b *testing.B) {
 		b.ReportAllocs()
-		hl.h = mockHasher{}
+		hl.pseudonymizer = mockPseudonymizer{}
 		for i := 0; i < b.N; i++ {
 			t2l, t2d = hl.Has(ExpectExisting)
 		}
@@ -147,7 +148,7 @@ func BenchmarkHitlistHas(b *testing.B) {
 
 	b.Run("Bench nonexisting", func(b *testing.B) {
 		b.ReportAllocs()
-		hl.h = h
+		hl.pseudonymizer = keys
 		for i := 0; i < b.N; i++ {
 			t1l, t1d = hl.Has(ExpectNonExisting)
 		}
@@ -155,7 +156,7 @@ func BenchmarkHitlistHas(b *testing.B) {
 
 	b.Run("Bench existing", func(b *testing.B) {
 		b.ReportAllocs()
-		hl.h = h
+		hl.pseudonymizer = keys
 		for i := 0; i < b.N; i++ {
 			t2l, t2d = hl.Has(ExpectExisting)
 		}
@@ -164,6 +165,131 @@ func BenchmarkHitlistHas(b *testing.B) {
 	_ = t1l && t1d && t2l && t2d
 }
 
+// BenchmarkHitListParallel compares a single lock with the default sharding, run with -cpu to see how throughput
+// scales with cores.
+func BenchmarkHitListParallel(b *testing.B) {
+	keys, err := NewKeys(1, []byte("00000000000000000000000000000000"))
+	if err != nil {
+		b.Errorf("Unable to create our keys %s", err)
+		return
+	}
+
+	const domainCount = 1000
+	const localCount = 10
+
+	parts := make([]types.EmailParts, 0, domainCount*localCount)
+	for i := 0; i < domainCount*localCount; i++ {
+		parts = append(parts, types.NewEmailFromParts(fmt.Sprintf("john%d", i%localCount), fmt.Sprintf("example%d.org", i/localCount)))
+	}
+
+	populate := func(shards int) *HitList {
+		hl := New(keys, time.Hour, WithShards(shards))
+		for _, p := range parts {
+			_ = hl.Add(p, validator.Result{})
+		}
+
+		return hl
+	}
+
+	for _, shards := range []int{1, defaultShards} {
+		b.Run(fmt.Sprintf("Has, %d shards", shards), func(b *testing.B) {
+			hl := populate(shards)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := rand.Intn(len(parts)); pb.Next(); i++ {
+					_, _ = hl.Has(parts[i%len(parts)])
+				}
+			})
+		})
+
+		b.Run(fmt.Sprintf("Add, %d shards", shards), func(b *testing.B) {
+			hl := populate(shards)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := rand.Intn(len(parts)); pb.Next(); i++ {
+					_ = hl.Add(parts[i%len(parts)], validator.Result{})
+				}
+			})
+		})
+
+		// Roughly what /suggest does, with the occasional refresh of the domains
+		b.Run(fmt.Sprintf("Mixed, %d shards", shards), func(b *testing.B) {
+			hl := populate(shards)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := rand.Intn(len(parts)); pb.Next(); i++ {
+					p := parts[i%len(parts)]
+					switch {
+					case i%1000 == 0:
+						_ = hl.GetValidAndUsageSortedDomains()
+					case i%10 == 0:
+						_ = hl.Add(p, validator.Result{})
+					default:
+						_, _ = hl.Has(p)
+						_, _ = hl.GetDomainValidationDetails(Domain(p.Domain))
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkRecipientStorage reports the heap used per recipient, for each RecipientStorage. Most recipients share a
+// few popular domains, while most domains only have a few recipients.
+func BenchmarkRecipientStorage(b *testing.B) {
+	keys, err := NewKeys(1, []byte("00000000000000000000000000000000"))
+	if err != nil {
+		b.Errorf("Unable to create our keys %s", err)
+		return
+	}
+
+	const recipientCount = 200000
+
+	parts := make([]types.EmailParts, 0, recipientCount)
+	for i := 0; i < recipientCount; i++ {
+		domain := fmt.Sprintf("example%d.org", i%10)
+		if i%20 == 0 {
+			domain = fmt.Sprintf("example%d.org", i)
+		}
+
+		parts = append(parts, types.NewEmailFromParts(fmt.Sprintf("john%d", i), domain))
+	}
+
+	heapInUse := func() uint64 {
+		var stats runtime.MemStats
+
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+
+		return stats.HeapAlloc
+	}
+
+	for _, storage := range []RecipientStorage{StorageMap, StorageCompact, StorageCount} {
+		b.Run(storage.String(), func(b *testing.B) {
+			var perRecipient float64
+			for i := 0; i < b.N; i++ {
+				before := heapInUse()
+
+				hl := New(keys, time.Hour, WithRecipientStorage(storage))
+				for _, p := range parts {
+					_ = hl.Add(p, validator.Result{})
+				}
+
+				perRecipient = float64(heapInUse()-before) / recipientCount
+				runtime.KeepAlive(hl)
+			}
+
+			b.ReportMetric(perRecipient, "heap-B/recipient")
+		})
+	}
+}
+
 func BenchmarkLenOrEqual(b *testing.B) {
 	input := []byte("raboof")
 	var refs [][]byte
@@ -1,9 +1,8 @@
 package hitlist
 
 import (
-	"hash"
 	"reflect"
-	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,7 +11,7 @@ import (
 	"github.com/Dynom/ERI/validator/validations"
 )
 
-func Test_getValidDomains(t *testing.T) {
+func Test_sortByRecipients(t *testing.T) {
 	validDuration := time.Now().Add(1 * time.Hour)
 	validFlags := validations.FValid | validations.FSyntax | validations.FMXLookup | validations.FMXDomainHasIP
 	validVR := validator.Result{
@@ -22,46 +21,46 @@ func Test_getValidDomains(t *testing.T) {
 
 	allValidHits := Hits{
 		Domain("a"): Hit{
-			Recipients: map[rcpt]struct{}{
-				rcpt("john.doe"): {},
-				rcpt("jane.doe"): {},
-				rcpt("joan.doe"): {},
-				rcpt("jake.doe"): {},
+			Recipients: recipientMap{
+				rcpt("john.doe"): validDuration,
+				rcpt("jane.doe"): validDuration,
+				rcpt("joan.doe"): validDuration,
+				rcpt("jake.doe"): validDuration,
 			},
 			ValidUntil:       validDuration,
 			ValidationResult: validVR,
 		},
 		Domain("b"): Hit{
-			Recipients: map[rcpt]struct{}{
-				rcpt("john.doe"): {},
-				rcpt("jane.doe"): {},
+			Recipients: recipientMap{
+				rcpt("john.doe"): validDuration,
+				rcpt("jane.doe"): validDuration,
 			},
 			ValidUntil:       validDuration,
 			ValidationResult: validVR,
 		},
 		Domain("c"): Hit{
-			Recipients: map[rcpt]struct{}{
-				rcpt("john.doe"): {},
+			Recipients: recipientMap{
+				rcpt("john.doe"): validDuration,
 			},
 			ValidUntil:       validDuration,
 			ValidationResult: validVR,
 		},
 		Domain("d"): Hit{
-			Recipients: map[rcpt]struct{}{
-				rcpt("john.doe"): {},
-				rcpt("jane.doe"): {},
-				rcpt("joan.doe"): {},
+			Recipients: recipientMap{
+				rcpt("john.doe"): validDuration,
+				rcpt("jane.doe"): validDuration,
+				rcpt("joan.doe"): validDuration,
 			},
 			ValidUntil:       validDuration,
 			ValidationResult: validVR,
 		},
 		Domain("e"): Hit{
-			Recipients: map[rcpt]struct{}{
-				rcpt("john.doe"):    {},
-				rcpt("jane.doe"):    {},
-				rcpt("joan.doe"):    {},
-				rcpt("jake.doe"):    {},
-				rcpt("winston.doe"): {},
+			Recipients: recipientMap{
+				rcpt("john.doe"):    validDuration,
+				rcpt("jane.doe"):    validDuration,
+				rcpt("joan.doe"):    validDuration,
+				rcpt("jake.doe"):    validDuration,
+				rcpt("winston.doe"): validDuration,
 			},
 			ValidUntil:       validDuration,
 			ValidationResult: validVR,
@@ -77,8 +76,8 @@ func Test_getValidDomains(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := getValidDomains(tt.hits); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("getValidDomains() = %v, want %v", got, tt.want)
+			if got := sortByRecipients(appendValidDomains(nil, tt.hits)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortByRecipients() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -127,7 +126,7 @@ func TestHitList_FunctionalAddAndReturn(t *testing.T) {
 	}
 
 	ttl := time.Hour * 1
-	h := mockHasher{}
+	h := mockPseudonymizer{}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -138,8 +137,8 @@ func TestHitList_FunctionalAddAndReturn(t *testing.T) {
 				t.Errorf("AddDeadline() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			if len(hl.hits) != tt.wantTotalDomains {
-				t.Errorf("Expected %d domains known to HL, instead I have %d", tt.wantTotalDomains, len(hl.hits))
+			if hl.domainCount() != tt.wantTotalDomains {
+				t.Errorf("Expected %d domains known to HL, instead I have %d", tt.wantTotalDomains, hl.domainCount())
 			}
 
 			if vds := hl.GetValidAndUsageSortedDomains(); len(vds) != tt.wantValidDomains {
@@ -155,33 +154,76 @@ func TestHitList_AddEmailAddressDeadlineDuplicates(t *testing.T) {
 		Validations: validations.Validations(validations.FValid | validations.FSyntax | validations.FMXLookup),
 	}
 
-	populatedHitList := New(mockHasher{}, time.Hour*1)
+	populatedHitList := New(mockPseudonymizer{}, time.Hour*1)
 	_ = populatedHitList.AddEmailAddress("john.doe@example.org", validVR) // example caseR
 	_ = populatedHitList.AddEmailAddress("jane.doe@example.org", validVR)
 
 	expect := 2
-	if got := len(populatedHitList.hits[Domain("example.org")].Recipients); got != expect {
+	if got := populatedHitList.hit(Domain("example.org")).Recipients.len(); got != expect {
 		t.Errorf("Expecting multiple recipients to be added for the same domain. Expected %d, got %d", expect, got)
 	}
 }
 
+func TestHitList_AddEmailAddressInternationalised(t *testing.T) {
+	validVR := validator.Result{
+		Validations: validations.Validations(validations.FValid | validations.FSyntax | validations.FMXLookup),
+	}
+
+	hl := New(mockPseudonymizer{}, time.Hour*1)
+	_ = hl.AddEmailAddress("john.doe@Bücher.example", validVR)
+	_ = hl.AddEmailAddress("jane.doe@xn--bcher-kva.example", validVR)
+
+	if got := hl.domainCount(); got != 1 {
+		t.Errorf("Expected both forms to share a single domain, got %d domains", got)
+	}
+
+	if got := hl.GetRecipientCount(Domain("xn--bcher-kva.example")); got != 2 {
+		t.Errorf("Expected 2 recipients for the ASCII form of the domain, got %d", got)
+	}
+
+	if domain, local := hl.Has(types.NewEmailFromParts("john.doe", "bücher.example")); !domain || !local {
+		t.Errorf("Expected the Unicode form to be found, got domain: %t local: %t", domain, local)
+	}
+}
+
+func TestHitList_AddEmailAddressCanonical(t *testing.T) {
+	validVR := validator.Result{
+		Validations: validations.Validations(validations.FValid | validations.FSyntax | validations.FMXLookup),
+	}
+
+	hl := New(mockPseudonymizer{}, time.Hour*1)
+	_ = hl.AddEmailAddress("john.doe+news@gmail.com", validVR)
+	_ = hl.AddEmailAddress("JohnDoe@googlemail.com", validVR)
+
+	if got := hl.GetRecipientCount(Domain("gmail.com")); got != 1 {
+		t.Errorf("Expected the forms of a single mailbox to be one recipient, got %d", got)
+	}
+
+	if _, ok := hl.GetDomainValidationDetails(Domain("googlemail.com")); ok {
+		t.Errorf("Expected the alias to be stored under the canonical domain")
+	}
+
+	if domain, local := hl.Has(types.NewEmailFromParts("j.o.h.n.doe", "googlemail.com")); !domain || !local {
+		t.Errorf("Expected another form of the mailbox to be found, got domain: %t local: %t", domain, local)
+	}
+}
+
 func TestHitList_AddEmailAddressDeadline(t *testing.T) {
 	validVR := validator.Result{
 		// Validations need to be valid for a domain for this test
 		Validations: validations.Validations(validations.FValid | validations.FSyntax | validations.FMXLookup),
 	}
 
-	populatedHitList := New(mockHasher{}, time.Hour*1)
+	populatedHitList := New(mockPseudonymizer{}, time.Hour*1)
 	_ = populatedHitList.AddEmailAddress("john.doe@example.org", validVR) // example caseR
 	_ = populatedHitList.AddEmailAddress("jane.doe@example.org", validVR)
 	_ = populatedHitList.AddEmailAddress("alexander@example.com", validVR)
 	_ = populatedHitList.AddEmailAddress("edward@example.com", validVR)
 
 	type fields struct {
-		hits Hits
-		ttl  time.Duration
-		lock sync.RWMutex
-		h    hash.Hash
+		shards []*shard
+		ttl    time.Duration
+		p      Pseudonymizer
 	}
 
 	type args struct {
@@ -200,10 +242,9 @@ func TestHitList_AddEmailAddressDeadline(t *testing.T) {
 		{
 			name: "Add with future duration",
 			fields: fields{
-				hits: make(Hits),
-				ttl:  time.Hour * 2, // Not used in this case
-				lock: sync.RWMutex{},
-				h:    mockHasher{},
+				shards: newShards(defaultShards),
+				ttl:    time.Hour * 2, // Not used in this case
+				p:      mockPseudonymizer{},
 			},
 			args: args{
 				emailLocal:  "john.doe",
@@ -216,10 +257,9 @@ func TestHitList_AddEmailAddressDeadline(t *testing.T) {
 		{
 			name: "Add with expired duration",
 			fields: fields{
-				hits: make(Hits),
-				ttl:  time.Hour * 2, // Not used in this case
-				lock: sync.RWMutex{},
-				h:    mockHasher{},
+				shards: newShards(defaultShards),
+				ttl:    time.Hour * 2, // Not used in this case
+				p:      mockPseudonymizer{},
 			},
 			args: args{
 				emailLocal:  "john.doe",
@@ -232,10 +272,9 @@ func TestHitList_AddEmailAddressDeadline(t *testing.T) {
 		{
 			name: "Add duplicate",
 			fields: fields{
-				hits: populatedHitList.hits,
-				ttl:  time.Hour * 2,
-				lock: sync.RWMutex{},
-				h:    mockHasher{},
+				shards: populatedHitList.shards,
+				ttl:    time.Hour * 2,
+				p:      mockPseudonymizer{},
 			},
 			args: args{
 				emailLocal:  "john.doe",
@@ -250,10 +289,9 @@ func TestHitList_AddEmailAddressDeadline(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			hl := &HitList{
-				hits: tt.fields.hits,
-				ttl:  tt.fields.ttl,
-				lock: tt.fields.lock,
-				h:    tt.fields.h,
+				shards:        tt.fields.shards,
+				ttl:           tt.fields.ttl,
+				pseudonymizer: tt.fields.p,
 			}
 
 			parts := types.NewEmailFromParts(tt.args.emailLocal, tt.args.emailDomain)
@@ -262,7 +300,7 @@ func TestHitList_AddEmailAddressDeadline(t *testing.T) {
 				t.Errorf("AddDeadline() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			hit, ok := hl.hits[Domain(tt.args.emailDomain)]
+			hit, ok := hl.lookup(Domain(tt.args.emailDomain))
 			if !ok {
 				t.Errorf("Expected %q to be present, it's not", tt.args.emailDomain)
 				return
@@ -277,37 +315,29 @@ func TestHitList_AddEmailAddressDeadline(t *testing.T) {
 	}
 }
 
-type mockHasher struct {
-	v []byte
+// hit returns the Hit of a domain, or the zero value when it's unknown
+func (hl *HitList) hit(d Domain) Hit {
+	hit, _ := hl.lookup(d)
+	return hit
 }
 
-func (s mockHasher) Write(p []byte) (int, error) {
-	s.v = p
-	return len(p), nil
+// domainCount returns the number of domains
+func (hl *HitList) domainCount() int {
+	return int(atomic.LoadInt64(&hl.domains))
 }
 
-func (s mockHasher) Sum(p []byte) []byte {
-	// Make sure we did something, reverse the input
-	r := make([]byte, len(p))
-	for i, v := range p {
-		j := len(r) - 1 - i
-		r[j] = v
+// mockPseudonymizer reverses the local part, to make sure we did something
+type mockPseudonymizer struct{}
+
+func (mockPseudonymizer) Recipient(local string) Recipient {
+	r := make(Recipient, len(local))
+	for i := 0; i < len(local); i++ {
+		r[len(r)-1-i] = local[i]
 	}
 
 	return r
 }
 
-func (s mockHasher) Reset() {
-}
-
-func (s mockHasher) Size() int {
-	return len(s.v)
-}
-
-func (s mockHasher) BlockSize() int {
-	return 128
-}
-
 func TestHitList_GetValidAndUsageSortedDomains(t *testing.T) {
 	validVR := validator.Result{
 		// Validations need to be valid for a domain for this test
@@ -318,12 +348,12 @@ func TestHitList_GetValidAndUsageSortedDomains(t *testing.T) {
 		Validations: validations.Validations(0),
 	}
 
-	populatedFullyValidHitList := New(mockHasher{}, time.Hour*1)
+	populatedFullyValidHitList := New(mockPseudonymizer{}, time.Hour*1)
 	_ = populatedFullyValidHitList.AddEmailAddress("john.doe@example.org", validVR) // example case
 	_ = populatedFullyValidHitList.AddEmailAddress("jane.doe@example.org", validVR)
 	_ = populatedFullyValidHitList.AddEmailAddress("alexander@example.com", validVR)
 
-	populatedHitListFaultyDomains := New(mockHasher{}, time.Hour*1)
+	populatedHitListFaultyDomains := New(mockPseudonymizer{}, time.Hour*1)
 	_ = populatedHitListFaultyDomains.AddEmailAddress("john.doe@example.or", invalidVR)
 	_ = populatedHitListFaultyDomains.AddEmailAddress("alexan der@example.com", invalidVR)
 
@@ -338,17 +368,16 @@ func TestHitList_GetValidAndUsageSortedDomains(t *testing.T) {
 		return p
 	}
 
-	populatedHitListExpiredDomains := New(mockHasher{}, time.Hour*1 /* Not used for this test set */)
+	populatedHitListExpiredDomains := New(mockPseudonymizer{}, time.Hour*1 /* Not used for this test set */)
 	_ = populatedHitListExpiredDomains.AddDeadline(np("john.doe@example.org"), validVR, 0)
 	_ = populatedHitListExpiredDomains.AddDeadline(np("alexander@example.com"), validVR, 0)
 
 	_ = populatedHitListExpiredDomains.AddDeadline(np("jane.doe@example.org"), validVR, 0)
 
 	type fields struct {
-		hits Hits
-		ttl  time.Duration
-		lock sync.RWMutex
-		h    hash.Hash
+		shards []*shard
+		ttl    time.Duration
+		p      Pseudonymizer
 	}
 
 	tests := []struct {
@@ -359,10 +388,9 @@ func TestHitList_GetValidAndUsageSortedDomains(t *testing.T) {
 		{
 			name: "All valid domains",
 			fields: fields{
-				hits: populatedFullyValidHitList.hits,
-				ttl:  populatedFullyValidHitList.ttl,
-				lock: sync.RWMutex{},
-				h:    populatedFullyValidHitList.h,
+				shards: populatedFullyValidHitList.shards,
+				ttl:    populatedFullyValidHitList.ttl,
+				p:      populatedFullyValidHitList.pseudonymizer,
 			},
 			want: []string{
 				"example.org",
@@ -372,10 +400,9 @@ func TestHitList_GetValidAndUsageSortedDomains(t *testing.T) {
 		{
 			name: "With faulty domains",
 			fields: fields{
-				hits: populatedHitListFaultyDomains.hits,
-				ttl:  populatedHitListFaultyDomains.ttl,
-				lock: sync.RWMutex{},
-				h:    populatedHitListFaultyDomains.h,
+				shards: populatedHitListFaultyDomains.shards,
+				ttl:    populatedHitListFaultyDomains.ttl,
+				p:      populatedHitListFaultyDomains.pseudonymizer,
 			},
 			want: []string{
 				"example.org",
@@ -386,10 +413,9 @@ func TestHitList_GetValidAndUsageSortedDomains(t *testing.T) {
 			// point. This property should be an indicator to a caching layer to determine if it's information is stale.
 			name: "With expired domains",
 			fields: fields{
-				hits: populatedHitListExpiredDomains.hits,
-				ttl:  populatedHitListExpiredDomains.ttl,
-				lock: sync.RWMutex{},
-				h:    populatedHitListExpiredDomains.h,
+				shards: populatedHitListExpiredDomains.shards,
+				ttl:    populatedHitListExpiredDomains.ttl,
+				p:      populatedHitListExpiredDomains.pseudonymizer,
 			},
 			want: []string{
 				"example.org",
@@ -400,10 +426,9 @@ func TestHitList_GetValidAndUsageSortedDomains(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			hl := &HitList{
-				hits: tt.fields.hits,
-				ttl:  tt.fields.ttl,
-				lock: tt.fields.lock,
-				h:    tt.fields.h,
+				shards:        tt.fields.shards,
+				ttl:           tt.fields.ttl,
+				pseudonymizer: tt.fields.p,
 			}
 
 			if got := hl.GetValidAndUsageSortedDomains(); !reflect.DeepEqual(got, tt.want) {
@@ -419,12 +444,12 @@ func TestHitList_AddEmailAddress(t *testing.T) {
 		Validations: validations.Validations(validations.FValid | validations.FSyntax | validations.FMXLookup),
 	}
 
-	hl := New(mockHasher{}, time.Hour*1)
+	hl := New(mockPseudonymizer{}, time.Hour*1)
 
 	now := time.Now()
 	_ = hl.AddEmailAddress("john.doe@example.org", validVR) // example caseR
 
-	if vu, expected := hl.hits[Domain("example.org")].ValidUntil.Round(time.Second*1), now.Add(hl.ttl).Round(time.Second*1); !expected.Equal(vu) {
+	if vu, expected := hl.hit(Domain("example.org")).ValidUntil.Round(time.Second*1), now.Add(hl.ttl).Round(time.Second*1); !expected.Equal(vu) {
 		t.Errorf("Expected the TTL to have been set with the short-hand AddEmailAddress. \nExpected %v, \ngot      %v", expected, vu)
 	}
 }
@@ -439,13 +464,13 @@ func TestHitList_AddDomain(t *testing.T) {
 		Validations: validations.Validations(0),
 	}
 
-	populatedFullyValidHitList := New(mockHasher{}, time.Hour*1)
+	populatedFullyValidHitList := New(mockPseudonymizer{}, time.Hour*1)
 	_ = populatedFullyValidHitList.AddDomain("example.org", validVR)
 
 	type fields struct {
-		hits Hits
-		ttl  time.Duration
-		h    hash.Hash
+		shards []*shard
+		ttl    time.Duration
+		p      Pseudonymizer
 	}
 
 	type args struct {
@@ -463,9 +488,9 @@ func TestHitList_AddDomain(t *testing.T) {
 		{
 			name: "Unknown",
 			fields: fields{
-				hits: populatedFullyValidHitList.hits,
-				ttl:  populatedFullyValidHitList.ttl,
-				h:    populatedFullyValidHitList.h,
+				shards: populatedFullyValidHitList.shards,
+				ttl:    populatedFullyValidHitList.ttl,
+				p:      populatedFullyValidHitList.pseudonymizer,
 			},
 			args: args{
 				d:  "example1.com",
@@ -477,9 +502,9 @@ func TestHitList_AddDomain(t *testing.T) {
 		{
 			name: "Unknown, invalid VR",
 			fields: fields{
-				hits: populatedFullyValidHitList.hits,
-				ttl:  populatedFullyValidHitList.ttl,
-				h:    populatedFullyValidHitList.h,
+				shards: populatedFullyValidHitList.shards,
+				ttl:    populatedFullyValidHitList.ttl,
+				p:      populatedFullyValidHitList.pseudonymizer,
 			},
 			args: args{
 				d:  "example2.com",
@@ -491,9 +516,9 @@ func TestHitList_AddDomain(t *testing.T) {
 		{
 			name: "Known, with same VR",
 			fields: fields{
-				hits: populatedFullyValidHitList.hits,
-				ttl:  populatedFullyValidHitList.ttl,
-				h:    populatedFullyValidHitList.h,
+				shards: populatedFullyValidHitList.shards,
+				ttl:    populatedFullyValidHitList.ttl,
+				p:      populatedFullyValidHitList.pseudonymizer,
 			},
 			args: args{
 				d:  "example.org",
@@ -505,9 +530,9 @@ func TestHitList_AddDomain(t *testing.T) {
 		{
 			name: "Known, with different VR",
 			fields: fields{
-				hits: populatedFullyValidHitList.hits,
-				ttl:  populatedFullyValidHitList.ttl,
-				h:    populatedFullyValidHitList.h,
+				shards: populatedFullyValidHitList.shards,
+				ttl:    populatedFullyValidHitList.ttl,
+				p:      populatedFullyValidHitList.pseudonymizer,
 			},
 			args: args{
 				d: "example.org",
@@ -523,9 +548,9 @@ func TestHitList_AddDomain(t *testing.T) {
 		{
 			name: "Empty input",
 			fields: fields{
-				hits: populatedFullyValidHitList.hits,
-				ttl:  populatedFullyValidHitList.ttl,
-				h:    populatedFullyValidHitList.h,
+				shards: populatedFullyValidHitList.shards,
+				ttl:    populatedFullyValidHitList.ttl,
+				p:      populatedFullyValidHitList.pseudonymizer,
 			},
 			args: args{
 				d:  "",
@@ -539,17 +564,16 @@ func TestHitList_AddDomain(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			hl := &HitList{
-				hits: tt.fields.hits,
-				ttl:  tt.fields.ttl,
-				lock: sync.RWMutex{},
-				h:    tt.fields.h,
+				shards:        tt.fields.shards,
+				ttl:           tt.fields.ttl,
+				pseudonymizer: tt.fields.p,
 			}
 
 			if err := hl.AddDomain(tt.args.d, tt.args.vr); (err != nil) != tt.wantErr {
 				t.Errorf("AddDomain() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
-			if vr := hl.hits[Domain(tt.args.d)].ValidationResult; !reflect.DeepEqual(vr, tt.wantVR) {
+			if vr := hl.hit(Domain(tt.args.d)).ValidationResult; !reflect.DeepEqual(vr, tt.wantVR) {
 				t.Errorf("Expected the Validation Result to be \n%+v, instead I got \n%+v", tt.wantVR, vr)
 			}
 		})
@@ -600,7 +624,7 @@ func TestHitList_Add(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			hl := New(mockHasher{}, time.Hour*1)
+			hl := New(mockPseudonymizer{}, time.Hour*1)
 			if err := hl.Add(tt.args.parts, tt.args.vr); (err != nil) != tt.wantErr {
 				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -611,7 +635,7 @@ func TestHitList_Add(t *testing.T) {
 				t.Errorf("Domain wasn't added, while it should've been")
 			}
 
-			if rcnt := len(hl.hits[domain].Recipients); rcnt != tt.recipientCount {
+			if rcnt := hl.GetRecipientCount(domain); rcnt != uint64(tt.recipientCount) {
 				t.Errorf("Expected %d recipients to have been added, instead I have %d", tt.recipientCount, rcnt)
 			}
 		})
@@ -697,7 +721,7 @@ func TestHitList_Has(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			hl := New(mockHasher{}, time.Hour*1)
+			hl := New(mockPseudonymizer{}, time.Hour*1)
 			for _, p := range tt.toAdd {
 				err := hl.Add(p, validator.Result{})
 				if err != nil {
@@ -750,7 +774,7 @@ func TestHitList_GetInternalTypes(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			hl := New(mockHasher{}, time.Second*1)
+			hl := New(mockPseudonymizer{}, time.Second*1)
 
 			gotDomain, gotRecipient, err := hl.CreateInternalTypes(tt.p)
 			if (err != nil) != tt.wantErr {
@@ -802,7 +826,7 @@ func TestHitList_GetRecipientCount(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			hl := New(mockHasher{}, time.Second*1)
+			hl := New(mockPseudonymizer{}, time.Second*1)
 			for _, a := range tt.toAdd {
 				err := hl.Add(a, validator.Result{})
 				if err != nil {
@@ -817,3 +841,29 @@ func TestHitList_GetRecipientCount(t *testing.T) {
 		})
 	}
 }
+
+func TestHitList_GetRegistrableDomainStats(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour*1)
+
+	for _, a := range []string{
+		"john@mail.corp.example.co.uk",
+		"jane@example.co.uk",
+		"jake@example.co.uk",
+		"john@example.org",
+		"john@co.uk",
+	} {
+		if err := hl.AddEmailAddress(a, validator.Result{}); err != nil {
+			t.Fatalf("Preparing test failed %s", err)
+		}
+	}
+
+	want := []RegistrableDomainStats{
+		{Domain: "example.co.uk", Domains: 2, Recipients: 3},
+		{Domain: "co.uk", Domains: 1, Recipients: 1},
+		{Domain: "example.org", Domains: 1, Recipients: 1},
+	}
+
+	if got := hl.GetRegistrableDomainStats(validator.NewSuffixList()); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRegistrableDomainStats() = %v, want %v", got, want)
+	}
+}
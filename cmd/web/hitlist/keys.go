@@ -0,0 +1,115 @@
+package hitlist
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/minio/highwayhash"
+)
+
+// MaxKeyID is the highest KeyID. Key IDs are control characters, which the local part in the clear never starts with.
+const MaxKeyID KeyID = 31
+
+// recipientSize is the size of a Recipient created by Keys, the KeyID followed by a 128-bit digest
+const recipientSize = 1 + highwayhash.Size128
+
+var (
+	ErrInvalidKey   = errors.New("invalid key, must be exactly 32 bytes")
+	ErrInvalidKeyID = fmt.Errorf("invalid key ID, must be between 1 and %d", MaxKeyID)
+)
+
+// Pseudonymizer turns the canonical local part of an address into a Recipient, so that neither the HitList nor the
+// persisted recipients hold the local part itself. It must be safe for concurrent use.
+type Pseudonymizer interface {
+	Recipient(local string) Recipient
+}
+
+// RekeyFn returns the Recipient under the current key and true, nil and true when r is to be removed, or false when r
+// is to be kept as-is
+type RekeyFn func(d Domain, r Recipient) (Recipient, bool)
+
+// KeyID identifies the key a Recipient was created with
+type KeyID uint8
+
+// NewKeys creates a Pseudonymizer using a keyed HighwayHash. The id is stored as first byte of every Recipient, so that
+// recipients of a previous key are recognised after the key is rotated.
+func NewKeys(id KeyID, key []byte) (*Keys, error) {
+	if id == 0 || id > MaxKeyID {
+		return nil, ErrInvalidKeyID
+	}
+
+	if len(key) != highwayhash.Size {
+		return nil, ErrInvalidKey
+	}
+
+	return &Keys{
+		id:  id,
+		key: append([]byte(nil), key...),
+	}, nil
+}
+
+// Keys pseudonymise recipients with the current key. It holds no hash state, so it's safe for concurrent use.
+type Keys struct {
+	id  KeyID
+	key []byte
+}
+
+// ID returns the KeyID of the current key
+func (k *Keys) ID() KeyID {
+	return k.id
+}
+
+// Recipient returns the KeyID, followed by the HighwayHash-128 of local
+func (k *Keys) Recipient(local string) Recipient {
+	sum := highwayhash.Sum128([]byte(local), k.key)
+
+	r := make(Recipient, 1, recipientSize)
+	r[0] = byte(k.id)
+
+	return append(r, sum[:]...)
+}
+
+// KeyID returns the KeyID of a Recipient created by Keys, or false for recipients that have none
+func (k *Keys) KeyID(r Recipient) (KeyID, bool) {
+	return keyID(r)
+}
+
+func keyID(r []byte) (KeyID, bool) {
+	if len(r) != recipientSize || r[0] == 0 || KeyID(r[0]) > MaxKeyID {
+		return 0, false
+	}
+
+	return KeyID(r[0]), true
+}
+
+// Rekeyer returns a RekeyFn for the recipients persisted before they had a KeyID. Those hold the local part in the
+// clear, followed by the digest of no input under legacyKey, and are re-keyed from their canonical local part.
+// Recipients of a retired KeyID can't be re-keyed, since their local part can't be recovered. They're kept, unless
+// dropRetired is set.
+func (k *Keys) Rekeyer(legacyKey []byte, dropRetired bool) (RekeyFn, error) {
+	if len(legacyKey) != highwayhash.Size {
+		return nil, ErrInvalidKey
+	}
+
+	empty := highwayhash.Sum128(nil, legacyKey)
+
+	return func(d Domain, r Recipient) (Recipient, bool) {
+		if id, ok := k.KeyID(r); ok {
+			if dropRetired && id != k.id {
+				return nil, true
+			}
+
+			return r, false
+		}
+
+		local := bytes.TrimSuffix(r, empty[:])
+		if len(local) == 0 || len(local) == len(r) {
+			return r, false
+		}
+
+		canonical := types.DefaultCanonicalizer.Address(types.NewEmailFromParts(string(local), string(d)))
+		return k.Recipient(canonical.Local), true
+	}, nil
+}
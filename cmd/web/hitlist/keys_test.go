@@ -0,0 +1,151 @@
+package hitlist
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/minio/highwayhash"
+)
+
+var (
+	testKey      = []byte("00000000000000000000000000000000")
+	testOtherKey = []byte("11111111111111111111111111111111")
+)
+
+func TestNewKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      KeyID
+		key     []byte
+		wantErr error
+	}{
+		{name: "valid", id: 1, key: testKey},
+		{name: "highest ID", id: MaxKeyID, key: testKey},
+		{name: "ID zero", id: 0, key: testKey, wantErr: ErrInvalidKeyID},
+		{name: "ID too high", id: MaxKeyID + 1, key: testKey, wantErr: ErrInvalidKeyID},
+		{name: "short key", id: 1, key: []byte("0000"), wantErr: ErrInvalidKey},
+		{name: "no key", id: 1, wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := NewKeys(tt.id, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && keys.ID() != tt.id {
+				t.Errorf("Expected key ID %d, got %d", tt.id, keys.ID())
+			}
+		})
+	}
+}
+
+func TestKeys_Recipient(t *testing.T) {
+	keys, _ := NewKeys(1, testKey)
+	rotated, _ := NewKeys(2, testOtherKey)
+
+	r := keys.Recipient("john.doe")
+	if len(r) != recipientSize || r[0] != 1 {
+		t.Fatalf("Expected the key ID followed by a digest, got %x", r)
+	}
+
+	if bytes.Contains(r, []byte("john.doe")) {
+		t.Errorf("Expected the local part to be pseudonymised, got %q", r)
+	}
+
+	if !bytes.Equal(r, keys.Recipient("john.doe")) {
+		t.Errorf("Expected the same local part to result in the same recipient")
+	}
+
+	if bytes.Equal(r, keys.Recipient("jane.doe")) {
+		t.Errorf("Expected different local parts to result in different recipients")
+	}
+
+	if other := rotated.Recipient("john.doe"); other[0] != 2 || bytes.Equal(r[1:], other[1:]) {
+		t.Errorf("Expected another key to result in another recipient, got %x and %x", r, other)
+	}
+
+	if id, ok := keys.KeyID(rotated.Recipient("john.doe")); !ok || id != 2 {
+		t.Errorf("Expected the key ID of the rotated key, got %d %t", id, ok)
+	}
+
+	if _, ok := keys.KeyID(Recipient("john.doe")); ok {
+		t.Errorf("Expected a local part in the clear to have no key ID")
+	}
+}
+
+func TestKeys_Recipient_concurrent(t *testing.T) {
+	keys, _ := NewKeys(1, testKey)
+	want := keys.Recipient("john.doe")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got := keys.Recipient("john.doe"); !bytes.Equal(got, want) {
+					t.Errorf("Expected %x, got %x", want, got)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestKeys_Rekeyer(t *testing.T) {
+	keys, _ := NewKeys(2, testOtherKey)
+	retired, _ := NewKeys(1, testKey)
+
+	// What hash.Hash.Sum(local) of an unused highwayhash.New128(testKey) returned
+	legacy := func(local string) Recipient {
+		empty := highwayhash.Sum128(nil, testKey)
+		return append(Recipient(local), empty[:]...)
+	}
+
+	tests := []struct {
+		name        string
+		domain      Domain
+		recipient   Recipient
+		dropRetired bool
+		want        Recipient
+		wantRekeyed bool
+	}{
+		{name: "legacy", domain: "example.org", recipient: legacy("john.doe"), want: keys.Recipient("john.doe"), wantRekeyed: true},
+		{name: "legacy, canonicalised", domain: "gmail.com", recipient: legacy("John.Doe"), want: keys.Recipient("johndoe"), wantRekeyed: true},
+		{name: "legacy, single character", domain: "example.org", recipient: legacy("j"), want: keys.Recipient("j"), wantRekeyed: true},
+		{name: "current key", domain: "example.org", recipient: keys.Recipient("john.doe"), want: keys.Recipient("john.doe")},
+		{name: "retired key", domain: "example.org", recipient: retired.Recipient("john.doe"), want: retired.Recipient("john.doe")},
+		{name: "retired key, dropped", domain: "example.org", recipient: retired.Recipient("john.doe"), dropRetired: true, wantRekeyed: true},
+		{name: "current key, dropping retired", domain: "example.org", recipient: keys.Recipient("john.doe"), dropRetired: true, want: keys.Recipient("john.doe")},
+		{name: "unknown form", domain: "example.org", recipient: Recipient("john.doe"), want: Recipient("john.doe")},
+		{name: "digest only", domain: "example.org", recipient: legacy(""), want: legacy("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rekey, err := keys.Rekeyer(testKey, tt.dropRetired)
+			if err != nil {
+				t.Fatalf("Rekeyer() error = %v", err)
+			}
+
+			got, rekeyed := rekey(tt.domain, tt.recipient)
+			if rekeyed != tt.wantRekeyed {
+				t.Errorf("Expected re-keyed to be %t, got %t", tt.wantRekeyed, rekeyed)
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Expected %x, got %x", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := keys.Rekeyer([]byte("0000"), false); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected an invalid legacy key to fail, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package hitlist
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+const (
+	hllPrecision = 10
+	hllRegisters = 1 << hllPrecision
+
+	// hllSparse is the number of hashes kept, before switching to the registers. It's where both take 1KiB.
+	hllSparse = hllRegisters / 4
+)
+
+// recipientCount estimates the number of recipients with a HyperLogLog, with a standard error of about 3%. Domains
+// with few recipients, typically most of them, keep a sorted list of 32-bit hashes instead, which is exact. Recipients
+// expire together, once none was seen for their TTL.
+type recipientCount struct {
+	sparse    []uint32
+	registers []uint8
+	until     time.Time // The moment the last recipient expires
+}
+
+func (c *recipientCount) add(r rcpt, validUntil time.Time) bool {
+	if validUntil.After(c.until) {
+		c.until = validUntil
+	}
+
+	d := digestOf(r)
+	h := binary.LittleEndian.Uint64(d[:8])
+
+	if c.registers != nil {
+		c.addHash(h)
+		return false
+	}
+
+	top := uint32(h >> 32)
+	i := sort.Search(len(c.sparse), func(i int) bool { return c.sparse[i] >= top })
+	if i < len(c.sparse) && c.sparse[i] == top {
+		return false
+	}
+
+	if len(c.sparse) < hllSparse {
+		c.sparse = append(c.sparse, 0)
+		copy(c.sparse[i+1:], c.sparse[i:])
+		c.sparse[i] = top
+		return false
+	}
+
+	c.registers = make([]uint8, hllRegisters)
+	for _, s := range c.sparse {
+		c.addHash(uint64(s) << 32)
+	}
+
+	c.sparse = nil
+	c.addHash(h)
+
+	return false
+}
+
+func (c *recipientCount) addHash(h uint64) {
+	i := h >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(h<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > c.registers[i] {
+		c.registers[i] = rank
+	}
+}
+
+// has always returns false, recipients themselves aren't kept
+func (c *recipientCount) has(rcpt) bool {
+	return false
+}
+
+func (c *recipientCount) len() int {
+	if c.registers == nil {
+		return len(c.sparse)
+	}
+
+	var sum float64
+	var zeros int
+	for _, r := range c.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	const m = float64(hllRegisters)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting, which is more accurate for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return int(estimate + 0.5)
+}
+
+func (c *recipientCount) stored() int {
+	return 0
+}
+
+// expire forgets all recipients once the last one expired, they weren't stored and 0 is returned
+func (c *recipientCount) expire(t time.Time) int {
+	if c.until.After(t) {
+		return 0
+	}
+
+	c.sparse, c.registers = nil, nil
+	return 0
+}
+
+func (c *recipientCount) expiries(dst []time.Time) []time.Time {
+	return dst
+}
@@ -0,0 +1,150 @@
+package hitlist
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// slot is an entry of a recipientSet, 20 bytes. A zero validUntil marks an empty slot.
+type slot struct {
+	digest     digest
+	validUntil uint32 // Seconds since the Unix epoch
+}
+
+// recipientSet keeps the digests of recipients in an open addressing set, using linear probing. It's kept at most 75%
+// full, so that it takes between 27 and 54 bytes per recipient, where a map takes well over 100.
+type recipientSet struct {
+	slots []slot
+	n     int
+}
+
+func (s *recipientSet) add(r rcpt, validUntil time.Time) bool {
+	d := digestOf(r)
+	if i, ok := s.find(d); ok {
+		s.slots[i].validUntil = toSeconds(validUntil)
+		return false
+	}
+
+	if (s.n+1)*4 > len(s.slots)*3 {
+		s.resize(len(s.slots) * 2)
+	}
+
+	s.insert(slot{digest: d, validUntil: toSeconds(validUntil)})
+	return true
+}
+
+func (s *recipientSet) has(r rcpt) bool {
+	_, ok := s.find(digestOf(r))
+	return ok
+}
+
+func (s *recipientSet) len() int {
+	return s.n
+}
+
+func (s *recipientSet) stored() int {
+	return s.n
+}
+
+func (s *recipientSet) expire(t time.Time) int {
+	cutoff := toSeconds(t)
+
+	var removed int
+	for _, e := range s.slots {
+		if e.validUntil != 0 && e.validUntil <= cutoff {
+			removed++
+		}
+	}
+
+	if removed == 0 {
+		return 0
+	}
+
+	kept := make([]slot, 0, s.n-removed)
+	for _, e := range s.slots {
+		if e.validUntil > cutoff {
+			kept = append(kept, e)
+		}
+	}
+
+	// Rebuilding is simpler than deleting in place, and shrinks the set when most recipients expired
+	s.slots, s.n = nil, 0
+	if len(kept) > 0 {
+		s.resize((len(kept)*4 + 2) / 3)
+		for _, e := range kept {
+			s.insert(e)
+		}
+	}
+
+	return removed
+}
+
+func (s *recipientSet) expiries(dst []time.Time) []time.Time {
+	for _, e := range s.slots {
+		if e.validUntil != 0 {
+			dst = append(dst, time.Unix(int64(e.validUntil), 0))
+		}
+	}
+
+	return dst
+}
+
+// find returns the index of the slot holding d, or false when it's not in the set
+func (s *recipientSet) find(d digest) (int, bool) {
+	if s.n == 0 {
+		return 0, false
+	}
+
+	mask := len(s.slots) - 1
+	for i := home(d) & mask; ; i = (i + 1) & mask {
+		switch {
+		case s.slots[i].validUntil == 0:
+			return 0, false
+		case s.slots[i].digest == d:
+			return i, true
+		}
+	}
+}
+
+// insert places an entry that isn't in the set yet, there must be room
+func (s *recipientSet) insert(e slot) {
+	mask := len(s.slots) - 1
+	i := home(e.digest) & mask
+	for s.slots[i].validUntil != 0 {
+		i = (i + 1) & mask
+	}
+
+	s.slots[i] = e
+	s.n++
+}
+
+// resize moves the entries to a table of at least size slots, rounded up to a power of two
+func (s *recipientSet) resize(size int) {
+	n := 2
+	for n < size {
+		n <<= 1
+	}
+
+	old := s.slots
+	s.slots, s.n = make([]slot, n), 0
+	for _, e := range old {
+		if e.validUntil != 0 {
+			s.insert(e)
+		}
+	}
+}
+
+// home returns the preferred index of a digest, digests are uniformly distributed already
+func home(d digest) int {
+	return int(binary.LittleEndian.Uint64(d[:8]) >> 1)
+}
+
+// toSeconds returns t in seconds since the Unix epoch. It's never zero, since that marks an empty slot.
+func toSeconds(t time.Time) uint32 {
+	s := t.Unix()
+	if s <= 0 {
+		return 1
+	}
+
+	return uint32(s)
+}
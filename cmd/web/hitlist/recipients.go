@@ -0,0 +1,137 @@
+package hitlist
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/minio/highwayhash"
+)
+
+// RecipientStorage defines how the recipients of a domain are kept in memory
+type RecipientStorage int
+
+const (
+	// StorageMap keeps every recipient as-is, in a map. The default.
+	StorageMap RecipientStorage = iota
+
+	// StorageCompact keeps a 128-bit digest of every recipient in an open addressing set, together with the second it
+	// expires. It takes a fraction of the memory of StorageMap.
+	StorageCompact
+
+	// StorageCount only keeps an estimate of the number of recipients of a domain, in a HyperLogLog. Has never reports a
+	// recipient as known, recipients expire together once none was seen for their TTL and they don't count towards
+	// WithMaxRecipients.
+	StorageCount
+)
+
+func (s RecipientStorage) String() string {
+	switch s {
+	case StorageMap:
+		return "map"
+	case StorageCompact:
+		return "compact"
+	case StorageCount:
+		return "count"
+	}
+
+	return fmt.Sprintf("RecipientStorage(%d)", int(s))
+}
+
+// WithRecipientStorage sets how recipients are kept, see RecipientStorage
+func WithRecipientStorage(s RecipientStorage) Option {
+	return func(hl *HitList) {
+		hl.storage = s
+	}
+}
+
+// RecipientStorage returns how the recipients are kept, see WithRecipientStorage
+func (hl *HitList) RecipientStorage() RecipientStorage {
+	return hl.storage
+}
+
+// recipients holds the recipients of a domain. It's not safe for concurrent use, the lock of the shard must be held.
+type recipients interface {
+	// add adds or renews a recipient, it returns true when the recipient is new and stored
+	add(r rcpt, validUntil time.Time) bool
+	has(r rcpt) bool
+
+	// len returns the number of recipients, which might be an estimate
+	len() int
+
+	// stored returns the number of recipients stored, these count towards WithMaxRecipients
+	stored() int
+
+	// expire removes the recipients that expire at, or before t and returns how many were removed
+	expire(t time.Time) int
+
+	// expiries appends the moments the stored recipients expire to dst
+	expiries(dst []time.Time) []time.Time
+}
+
+func (hl *HitList) newRecipients() recipients {
+	switch hl.storage {
+	case StorageCompact:
+		return &recipientSet{}
+	case StorageCount:
+		return &recipientCount{}
+	}
+
+	return make(recipientMap)
+}
+
+// recipientMap keeps the recipients with the moment they expire
+type recipientMap map[rcpt]time.Time
+
+func (m recipientMap) add(r rcpt, validUntil time.Time) bool {
+	_, exists := m[r]
+	m[r] = validUntil
+
+	return !exists
+}
+
+func (m recipientMap) has(r rcpt) bool {
+	_, exists := m[r]
+	return exists
+}
+
+func (m recipientMap) len() int {
+	return len(m)
+}
+
+func (m recipientMap) stored() int {
+	return len(m)
+}
+
+func (m recipientMap) expire(t time.Time) (removed int) {
+	for r, validUntil := range m {
+		if !validUntil.After(t) {
+			delete(m, r)
+			removed++
+		}
+	}
+
+	return
+}
+
+func (m recipientMap) expiries(dst []time.Time) []time.Time {
+	for _, validUntil := range m {
+		dst = append(dst, validUntil)
+	}
+
+	return dst
+}
+
+type digest [highwayhash.Size128]byte
+
+// digestKey is used for recipients that aren't a digest already, it isn't secret
+var digestKey = make([]byte, highwayhash.Size)
+
+// digestOf returns the 128-bit digest of a recipient. Recipients created by Keys are a digest already.
+func digestOf(r rcpt) (d digest) {
+	if _, ok := keyID([]byte(r)); ok {
+		copy(d[:], r[1:])
+		return
+	}
+
+	return highwayhash.Sum128([]byte(r), digestKey)
+}
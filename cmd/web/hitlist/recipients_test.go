@@ -0,0 +1,200 @@
+package hitlist
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Dynom/ERI/types"
+)
+
+func TestRecipients(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		storage     RecipientStorage
+		wantHas     bool
+		wantStored  int
+		wantExpired int
+	}{
+		{storage: StorageMap, wantHas: true, wantStored: 3, wantExpired: 1},
+		{storage: StorageCompact, wantHas: true, wantStored: 3, wantExpired: 1},
+		{storage: StorageCount, wantHas: false, wantStored: 0, wantExpired: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.storage.String(), func(t *testing.T) {
+			r := (&HitList{storage: tt.storage}).newRecipients()
+
+			r.add("john", now.Add(-time.Hour))
+			r.add("jane", now.Add(time.Hour))
+			r.add("jake", now.Add(time.Hour))
+
+			if r.add("jane", now.Add(2*time.Hour)) {
+				t.Errorf("Expected a known recipient not to be added again")
+			}
+
+			if got := r.len(); got != 3 {
+				t.Errorf("Expected 3 recipients, got %d", got)
+			}
+
+			if got := r.stored(); got != tt.wantStored {
+				t.Errorf("Expected %d recipients to be stored, got %d", tt.wantStored, got)
+			}
+
+			if got := r.has("jane"); got != tt.wantHas {
+				t.Errorf("Expected has() to return %t, got %t", tt.wantHas, got)
+			}
+
+			if r.has("joan") {
+				t.Errorf("Expected an unknown recipient not to be found")
+			}
+
+			if got := len(r.expiries(nil)); got != tt.wantStored {
+				t.Errorf("Expected %d expiries, got %d", tt.wantStored, got)
+			}
+
+			if got := r.expire(now); got != tt.wantExpired {
+				t.Errorf("Expected %d recipients to expire, got %d", tt.wantExpired, got)
+			}
+
+			if tt.wantHas && (r.has("john") || !r.has("jane") || !r.has("jake")) {
+				t.Errorf("Expected only the expired recipient to be removed")
+			}
+		})
+	}
+}
+
+func TestRecipientSet(t *testing.T) {
+	keys, _ := NewKeys(1, testKey)
+	now := time.Now()
+
+	s := &recipientSet{}
+	for i := 0; i < 1000; i++ {
+		ttl := time.Hour
+		if i%2 == 0 {
+			ttl = -time.Hour
+		}
+
+		if !s.add(rcpt(keys.Recipient(fmt.Sprint(i))), now.Add(ttl)) {
+			t.Fatalf("Expected recipient %d to be added", i)
+		}
+	}
+
+	if s.len() != 1000 || len(s.slots) != 2048 {
+		t.Errorf("Expected 1000 recipients in 2048 slots, got %d in %d", s.len(), len(s.slots))
+	}
+
+	if got := s.expire(now); got != 500 {
+		t.Errorf("Expected 500 recipients to expire, got %d", got)
+	}
+
+	if len(s.slots) != 1024 {
+		t.Errorf("Expected the set to shrink to 1024 slots, got %d", len(s.slots))
+	}
+
+	for i := 0; i < 1000; i++ {
+		if got, want := s.has(rcpt(keys.Recipient(fmt.Sprint(i)))), i%2 == 1; got != want {
+			t.Fatalf("Expected has() for recipient %d to return %t", i, want)
+		}
+	}
+
+	if got := s.expire(now.Add(2 * time.Hour)); got != 500 || s.len() != 0 || s.slots != nil {
+		t.Errorf("Expected the set to be emptied, %d expired and %d remain", got, s.len())
+	}
+
+	if s.has("john") {
+		t.Errorf("Expected an empty set to have no recipients")
+	}
+}
+
+func TestRecipientCount(t *testing.T) {
+	keys, _ := NewKeys(1, testKey)
+
+	for _, n := range []int{1, hllSparse, hllSparse + 1, 1000, 100000} {
+		c := &recipientCount{}
+		for i := 0; i < n; i++ {
+			c.add(rcpt(keys.Recipient(fmt.Sprint(i))), time.Time{})
+			c.add(rcpt(keys.Recipient(fmt.Sprint(i))), time.Time{})
+		}
+
+		got := c.len()
+		if n <= hllSparse && got != n {
+			t.Errorf("Expected an exact count of %d, got %d", n, got)
+		}
+
+		if e := math.Abs(float64(got-n)) / float64(n); e > 0.1 {
+			t.Errorf("Expected an estimate close to %d, got %d (%.1f%% off)", n, got, e*100)
+		}
+
+		if n > hllSparse && (c.sparse != nil || len(c.registers) != hllRegisters) {
+			t.Errorf("Expected %d recipients to use the registers", n)
+		}
+	}
+}
+
+func Test_digestOf(t *testing.T) {
+	keys, _ := NewKeys(1, testKey)
+
+	r := keys.Recipient("john")
+	if d := digestOf(rcpt(r)); string(d[:]) != string(r[1:]) {
+		t.Errorf("Expected the digest of Keys to be used as-is, got %x", d)
+	}
+
+	if digestOf("john") == digestOf("jane") {
+		t.Errorf("Expected other recipients to be hashed")
+	}
+}
+
+func TestHitList_WithRecipientStorage(t *testing.T) {
+	for _, storage := range []RecipientStorage{StorageMap, StorageCompact, StorageCount} {
+		t.Run(storage.String(), func(t *testing.T) {
+			hl := New(mockPseudonymizer{}, time.Hour, WithRecipientStorage(storage))
+
+			_ = hl.AddDeadline(types.NewEmailFromParts("john", "example.org"), validDomainVR, -time.Minute)
+			_ = hl.Add(types.NewEmailFromParts("jane", "example.org"), validDomainVR)
+			_ = hl.Add(types.NewEmailFromParts("jane", "example.com"), validDomainVR)
+
+			if got := hl.GetRecipientCount("example.org"); got != 2 {
+				t.Errorf("Expected 2 recipients, got %d", got)
+			}
+
+			if got := hl.GetValidAndUsageSortedDomains(); got[0] != "example.org" {
+				t.Errorf("Expected the domain with the most recipients first, got %v", got)
+			}
+
+			_, local := hl.Has(types.NewEmailFromParts("jane", "example.org"))
+			if want := storage != StorageCount; local != want {
+				t.Errorf("Expected Has() to return %t for the recipient, got %t", want, local)
+			}
+
+			hl.Sweep()
+
+			want := uint64(1)
+			if storage == StorageCount {
+				want = 2
+			}
+
+			if got := hl.GetRecipientCount("example.org"); got != want {
+				t.Errorf("Expected %d recipients after sweeping, got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestRecipientCount_expire(t *testing.T) {
+	now := time.Now()
+
+	c := &recipientCount{}
+	c.add("john", now.Add(-time.Hour))
+	c.add("jane", now.Add(time.Hour))
+
+	if c.expire(now); c.len() != 2 {
+		t.Errorf("Expected the recipients to be kept while one is live, got %d", c.len())
+	}
+
+	if c.expire(now.Add(2 * time.Hour)); c.len() != 0 {
+		t.Errorf("Expected the recipients to expire together, got %d", c.len())
+	}
+}
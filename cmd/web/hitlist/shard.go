@@ -0,0 +1,60 @@
+package hitlist
+
+import "sync"
+
+// defaultShards is the number of shards of a HitList, unless WithShards is used
+const defaultShards = 64
+
+// shard holds part of the domains of a HitList, so that operations on different domains rarely wait on one another
+type shard struct {
+	lock sync.RWMutex
+	hits Hits
+}
+
+// WithShards sets the number of shards the domains are spread over, it's rounded up to a power of two. More shards
+// reduce contention between concurrent calls, at the expense of operations that visit every domain.
+func WithShards(n int) Option {
+	return func(hl *HitList) {
+		hl.shards = newShards(n)
+	}
+}
+
+func newShards(n int) []*shard {
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+
+	shards := make([]*shard, size)
+	for i := range shards {
+		shards[i] = &shard{hits: make(Hits)}
+	}
+
+	return shards
+}
+
+// shard returns the shard that holds the domain
+func (hl *HitList) shard(domain Domain) *shard {
+	// FNV-1a, inlined to not allocate
+	var h uint32 = 2166136261
+	for i := 0; i < len(domain); i++ {
+		h ^= uint32(domain[i])
+		h *= 16777619
+	}
+
+	return hl.shards[h&uint32(len(hl.shards)-1)]
+}
+
+// lockAll locks every shard, for operations that need a consistent view of all domains. Shards are always locked in
+// the same order, while holding no other shard lock.
+func (hl *HitList) lockAll() {
+	for _, s := range hl.shards {
+		s.lock.Lock()
+	}
+}
+
+func (hl *HitList) unlockAll() {
+	for _, s := range hl.shards {
+		s.lock.Unlock()
+	}
+}
@@ -0,0 +1,114 @@
+package hitlist
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Dynom/ERI/types"
+)
+
+func TestWithShards(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{n: 0, want: 1},
+		{n: 1, want: 1},
+		{n: 3, want: 4},
+		{n: 64, want: 64},
+		{n: 65, want: 128},
+	}
+
+	for _, tt := range tests {
+		hl := New(mockPseudonymizer{}, time.Hour, WithShards(tt.n))
+		if got := len(hl.shards); got != tt.want {
+			t.Errorf("WithShards(%d) resulted in %d shards, want %d", tt.n, got, tt.want)
+		}
+	}
+
+	if got := len(New(mockPseudonymizer{}, time.Hour).shards); got != defaultShards {
+		t.Errorf("Expected %d shards by default, got %d", defaultShards, got)
+	}
+}
+
+func TestHitList_shard(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour)
+
+	used := make(map[*shard]struct{})
+	for i := 0; i < 1000; i++ {
+		domain := Domain(fmt.Sprintf("example%d.org", i))
+		if hl.shard(domain) != hl.shard(domain) {
+			t.Fatalf("Expected %q to always map to the same shard", domain)
+		}
+
+		used[hl.shard(domain)] = struct{}{}
+	}
+
+	if len(used) != defaultShards {
+		t.Errorf("Expected the domains to be spread over all %d shards, got %d", defaultShards, len(used))
+	}
+}
+
+func TestHitList_concurrent(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour, WithShards(4), WithMaxDomains(50), WithMaxRecipients(200))
+
+	var evicted int64
+	hl.OnEvict(func(domains []Domain) {
+		atomic.AddInt64(&evicted, int64(len(domains)))
+	})
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < 500; i++ {
+				parts := types.NewEmailFromParts(fmt.Sprintf("john%d", i%7), fmt.Sprintf("example%d.org", (w*31+i)%97))
+				switch i % 4 {
+				case 0:
+					_ = hl.AddDeadline(parts, validDomainVR, time.Duration(i%3-1)*time.Minute)
+				case 1:
+					_, _ = hl.Has(parts)
+				case 2:
+					_ = hl.GetValidAndUsageSortedDomains()
+				default:
+					_ = hl.Add(parts, validDomainVR)
+					if i%50 == 3 {
+						hl.Sweep()
+					}
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	var domains, recipients int
+	for _, s := range hl.shards {
+		domains += len(s.hits)
+		for _, hit := range s.hits {
+			recipients += hit.Recipients.len()
+		}
+	}
+
+	if got := hl.domainCount(); got != domains {
+		t.Errorf("Expected %d domains to be counted, got %d", domains, got)
+	}
+
+	if got := int(atomic.LoadInt64(&hl.recipients)); got != recipients {
+		t.Errorf("Expected %d recipients to be counted, got %d", recipients, got)
+	}
+
+	// Concurrent calls that add a domain can exceed the maximum by one each
+	if domains > 50+8 || recipients > 200 {
+		t.Errorf("Expected the limits to be respected, got %d domains and %d recipients", domains, recipients)
+	}
+
+	if atomic.LoadInt64(&evicted) == 0 {
+		t.Errorf("Expected domains to be evicted")
+	}
+}
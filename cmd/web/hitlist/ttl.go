@@ -0,0 +1,71 @@
+package hitlist
+
+import (
+	"time"
+
+	"github.com/Dynom/ERI/validator"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+// domainChecks are the validations that apply to a domain. A domain failed when one of them ran, without passing.
+const domainChecks = validations.FMXLookup | validations.FMXDomainHasIP | validations.FHostConnect
+
+// TTLs define how long results remain valid, depending on their outcome. A zero value uses the TTL passed to New.
+type TTLs struct {
+	// Valid applies to domains that passed their checks
+	Valid time.Duration
+
+	// Invalid applies to domains that failed their checks, e.g. a domain that doesn't exist or has no MX. It's typically
+	// short, a domain can be registered or fixed at any time.
+	Invalid time.Duration
+
+	// Recipient applies to the recipients of a domain
+	Recipient time.Duration
+}
+
+// DNSTTLFn returns the remaining TTL of the MX records of a domain, or false when it isn't known. See
+// resolver.Cache.MXTTL
+type DNSTTLFn func(domain string) (time.Duration, bool)
+
+// WithTTLs sets the TTLs by outcome
+func WithTTLs(ttls TTLs) Option {
+	return func(hl *HitList) {
+		hl.ttls = ttls
+	}
+}
+
+// WithDNSTTL limits the validity of domains to the TTL of their MX records, as observed while checking them
+func WithDNSTTL(fn DNSTTLFn) Option {
+	return func(hl *HitList) {
+		hl.dnsTTL = fn
+	}
+}
+
+// domainTTL returns how long the result of a domain remains valid
+func (hl *HitList) domainTTL(domain Domain, vr validator.Result) time.Duration {
+	ttl := hl.ttls.Valid
+	if failed := validations.Flag(vr.Steps) & domainChecks &^ validations.Flag(vr.Validations); failed != 0 {
+		ttl = hl.ttls.Invalid
+	}
+
+	if ttl == 0 {
+		ttl = hl.ttl
+	}
+
+	if hl.dnsTTL != nil {
+		if dnsTTL, ok := hl.dnsTTL(string(domain)); ok && dnsTTL < ttl {
+			ttl = dnsTTL
+		}
+	}
+
+	return ttl
+}
+
+// recipientTTL returns how long a recipient is kept
+func (hl *HitList) recipientTTL() time.Duration {
+	if hl.ttls.Recipient == 0 {
+		return hl.ttl
+	}
+
+	return hl.ttls.Recipient
+}
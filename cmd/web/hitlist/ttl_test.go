@@ -0,0 +1,120 @@
+package hitlist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+var (
+	lookupSteps = validations.Steps(validations.FSyntax | validations.FMXLookup | validations.FMXDomainHasIP)
+	passedVR    = validator.Result{
+		Validations: validations.Validations(validations.FValid | validations.FSyntax | validations.FMXLookup | validations.FMXDomainHasIP),
+		Steps:       lookupSteps,
+	}
+	failedVR = validator.Result{
+		Validations: validations.Validations(validations.FSyntax),
+		Steps:       lookupSteps,
+	}
+)
+
+func TestHitList_domainTTL(t *testing.T) {
+	ttls := TTLs{Valid: 10 * time.Hour, Invalid: time.Hour, Recipient: 20 * time.Hour}
+	dnsTTL := func(domain string) (time.Duration, bool) {
+		return 30 * time.Minute, domain == "short.example"
+	}
+
+	tests := []struct {
+		name    string
+		options []Option
+		domain  Domain
+		vr      validator.Result
+		want    time.Duration
+	}{
+		{name: "passed", options: []Option{WithTTLs(ttls)}, vr: passedVR, want: 10 * time.Hour},
+		{name: "failed", options: []Option{WithTTLs(ttls)}, vr: failedVR, want: time.Hour},
+		{name: "syntax only", options: []Option{WithTTLs(ttls)}, vr: validator.Result{Steps: validations.Steps(validations.FSyntax)}, want: 10 * time.Hour},
+		{name: "default", vr: failedVR, want: 60 * time.Hour},
+		{name: "partially defined", options: []Option{WithTTLs(TTLs{Invalid: time.Hour})}, vr: passedVR, want: 60 * time.Hour},
+		{name: "shorter DNS TTL", options: []Option{WithTTLs(ttls), WithDNSTTL(dnsTTL)}, domain: "short.example", vr: passedVR, want: 30 * time.Minute},
+		{name: "unknown DNS TTL", options: []Option{WithTTLs(ttls), WithDNSTTL(dnsTTL)}, domain: "example.org", vr: passedVR, want: 10 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hl := New(mockPseudonymizer{}, 60*time.Hour, tt.options...)
+			if got := hl.domainTTL(tt.domain, tt.vr); got != tt.want {
+				t.Errorf("domainTTL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHitList_Add_ttls(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour, WithTTLs(TTLs{Valid: 10 * time.Hour, Invalid: time.Hour, Recipient: 20 * time.Hour}))
+	parts := types.NewEmailFromParts("john", "example.org")
+
+	_ = hl.Add(parts, passedVR)
+	hit := hl.hit("example.org")
+
+	if until := time.Until(hit.ValidUntil); until < 9*time.Hour || until > 10*time.Hour {
+		t.Errorf("Expected the domain to be valid for 10h, got %s", until)
+	}
+
+	if until := time.Until(hit.Recipients.(recipientMap)[rcpt("nhoj")]); until < 19*time.Hour || until > 20*time.Hour {
+		t.Errorf("Expected the recipient to be kept for 20h, got %s", until)
+	}
+}
+
+func TestHitList_AddDomain_renews(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour)
+
+	_ = hl.AddDeadline(types.NewEmailFromParts("", "example.org"), passedVR, time.Minute)
+	_ = hl.AddDomain("example.org", passedVR)
+
+	if until := time.Until(hl.hit("example.org").ValidUntil); until < 59*time.Minute {
+		t.Errorf("Expected the validity to be renewed, got %s", until)
+	}
+}
+
+func TestHitList_Add_replacesExpired(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour)
+
+	// A typo domain that failed, and got registered after its result expired
+	_ = hl.AddDeadline(types.NewEmailFromParts("john", "example.org"), failedVR, -time.Minute)
+	_ = hl.Add(types.NewEmailFromParts("john", "example.org"), passedVR)
+
+	details, _ := hl.GetDomainValidationDetails("example.org")
+	if details.Validations != passedVR.Validations {
+		t.Errorf("Expected the expired result to be replaced, got %s", details.Validations)
+	}
+
+	// The other way around
+	_ = hl.AddDeadline(types.NewEmailFromParts("john", "example.com"), passedVR, -time.Minute)
+	_ = hl.Add(types.NewEmailFromParts("john", "example.com"), failedVR)
+
+	details, _ = hl.GetDomainValidationDetails("example.com")
+	if details.Validations.HasFlag(validations.FMXLookup) {
+		t.Errorf("Expected the expired result not to be merged, got %s", details.Validations)
+	}
+}
+
+func TestHitList_AddCached(t *testing.T) {
+	hl := New(mockPseudonymizer{}, time.Hour)
+
+	_ = hl.AddDeadline(types.NewEmailFromParts("john", "example.org"), failedVR, time.Minute)
+	validUntil := hl.hit("example.org").ValidUntil
+
+	_ = hl.AddCached(types.NewEmailFromParts("jane", "example.org"), failedVR)
+
+	if got := hl.hit("example.org").ValidUntil; !got.Equal(validUntil) {
+		t.Errorf("Expected the validity to remain %s, got %s", validUntil, got)
+	}
+
+	if got := hl.GetRecipientCount("example.org"); got != 2 {
+		t.Errorf("Expected the recipient to be added, got %d recipients", got)
+	}
+}
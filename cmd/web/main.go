@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,12 +12,12 @@ import (
 	"github.com/Dynom/ERI/cmd/web/preferrer"
 	"github.com/Dynom/ERI/cmd/web/pubsub/gcp"
 	"github.com/Dynom/ERI/runtimer"
+	"github.com/Dynom/ERI/validator/resolver"
 	"github.com/rs/cors"
 
 	"github.com/Pimmr/rig"
 
 	"github.com/Dynom/ERI/cmd/web/hitlist"
-	"github.com/minio/highwayhash"
 
 	"github.com/juju/ratelimit"
 
@@ -39,6 +40,9 @@ import (
 // Version contains the app version, the value is changed during compile time to the appropriate Git tag
 var Version = "dev"
 
+// defaultHitListTTL applies to the outcomes without a TTL of their own, see [hitList.ttl] in the configuration
+const defaultHitListTTL = time.Hour * 60
+
 func main() {
 	var conf config.Config
 	var err error
@@ -84,19 +88,28 @@ func main() {
 		"config": conf.String(),
 	}).Info("Starting up...")
 
-	h, err := highwayhash.New128([]byte(conf.Hash.Key))
+	keys, err := createKeys(conf)
 	if err != nil {
-		logger.WithError(err).Error("Unable to create our hash.Hash")
+		logger.WithError(err).Error("Unable to create the recipient keys")
 		exitCode = ErrExConfig
 		runtime.Goexit()
 	}
 
-	hitList := hitlist.New(
-		h,
-		time.Hour*60, // @todo figure out what todo with TTLs
-	)
+	resolverPool, err := createResolverPool(conf)
+	if err != nil {
+		logger.WithError(err).Error("Unable to create the resolvers")
+		exitCode = ErrExConfig
+		runtime.Goexit()
+	}
+
+	var dnsCache *resolver.Cache
+	if conf.Validator.Cache.Enable {
+		dnsCache = createCachingResolver(conf, resolverPool)
+	}
+
+	hitList := hitlist.New(keys, defaultHitListTTL, createHitListOptions(conf, dnsCache)...)
 
-	persister, err := createPersister(conf, logger, hitList)
+	persister, err := createPersister(conf, logger, hitList, keys)
 	if err != nil {
 		logger.WithError(err).Error("Unable to setup PG persister")
 		exitCode = ErrExUnavailable
@@ -117,9 +130,25 @@ func main() {
 		runtime.Goexit()
 	}
 
+	// Evicted domains are no longer suggested
+	hitList.OnEvict(func(_ []hitlist.Domain) {
+		myFinder.Refresh(hitList.GetValidAndUsageSortedDomains())
+	})
+
 	rtPubSub := runtimer.New(os.Interrupt, os.Kill)
 	rtWeb := runtimer.New(os.Interrupt, os.Kill)
 
+	// The background work (sweeping the HitList and reloading lists) stops when the server shuts down
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+
+	rtWeb.RegisterCallback(func(s os.Signal) {
+		logger.Debug("Stopping the background work")
+		bgCancel()
+	})
+
+	sweepHitList(bgCtx, hitList, conf.HitList.SweepInterval.AsDuration(), logger)
+
 	var pubSubSvc *gcp.PubSubSvc
 	pubSubSvc, err = createPubSubSvc(conf, logger, rtPubSub, hitList, myFinder)
 
@@ -131,7 +160,37 @@ func main() {
 
 	prefer := preferrer.New(preferrer.Mapping(conf.Services.Suggest.Prefer))
 
-	validatorFn := createProxiedValidator(conf, logger, hitList, myFinder, pubSubSvc, persister)
+	disposableList, err := createDisposableList(bgCtx, conf, logger)
+	if err != nil {
+		logger.WithError(err).Error("Unable to load the disposable list")
+		exitCode = ErrExConfig
+		runtime.Goexit()
+	}
+
+	tldList, err := createTLDList(bgCtx, conf, logger)
+	if err != nil {
+		logger.WithError(err).Error("Unable to load the TLD list")
+		exitCode = ErrExConfig
+		runtime.Goexit()
+	}
+
+	suffixList, err := createSuffixList(bgCtx, conf, logger)
+	if err != nil {
+		logger.WithError(err).Error("Unable to load the Public Suffix List")
+		exitCode = ErrExConfig
+		runtime.Goexit()
+	}
+
+	logHitListStats(hitList, suffixList, logger)
+
+	mxAddress, err := createMXAddressConfig(conf)
+	if err != nil {
+		logger.WithError(err).Error("Unable to configure the accepted MX addresses")
+		exitCode = ErrExConfig
+		runtime.Goexit()
+	}
+
+	validatorFn := createProxiedValidator(conf, logger, hitList, myFinder, pubSubSvc, persister, disposableList, tldList, mxAddress, resolverPool, dnsCache)
 	suggestSvc := services.NewSuggestService(myFinder, validatorFn, prefer, logger)
 	autocompleteSvc := services.NewAutocompleteService(myFinder, hitList, conf.Services.Autocomplete.RecipientThreshold, logger)
 
@@ -19,5 +19,10 @@ type Persister interface {
 	// a non-nil error. The implementation decides on the most optimal strategy.
 	Range(ctx context.Context, cb PersistCallbackFn) error
 
+	// Rekey replaces the recipients for which fn returns a new one and removes those for which it returns nil. It returns
+	// the number of replaced and removed recipients. When the new recipient is already stored for the domain, the old one
+	// is removed.
+	Rekey(ctx context.Context, fn hitlist.RekeyFn) (uint64, error)
+
 	io.Closer
 }
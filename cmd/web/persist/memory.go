@@ -2,10 +2,10 @@ package persist
 
 import (
 	"context"
+	"strings"
 	"sync"
 
 	"github.com/Dynom/ERI/cmd/web/hitlist"
-	"github.com/Dynom/ERI/types"
 	"github.com/Dynom/ERI/validator"
 )
 
@@ -28,14 +28,14 @@ func (s *Memory) Store(ctx context.Context, d hitlist.Domain, r hitlist.Recipien
 		return ctx.Err()
 	}
 
-	s.m.Store(string(r)+`@`+string(d), vr)
+	s.m.Store(memoryKey(d, r), vr)
 	return nil
 }
 
 func (s *Memory) Range(_ context.Context, cb PersistCallbackFn) error {
 	s.m.Range(func(key, value interface{}) bool {
-		internalParts, err := types.NewEmailParts(key.(string))
-		if err != nil {
+		domain, recipient, ok := splitMemoryKey(key.(string))
+		if !ok {
 			return true // Ignoring non-recoverable problem
 		}
 
@@ -45,12 +45,52 @@ func (s *Memory) Range(_ context.Context, cb PersistCallbackFn) error {
 			return true // Ignoring non-recoverable problem
 		}
 
-		domain := hitlist.Domain(internalParts.Domain)
-		recipient := hitlist.Recipient(internalParts.Local)
-
-		err = cb(domain, recipient, vr)
+		err := cb(domain, recipient, vr)
 		return err == nil
 	})
 
 	return nil
 }
+
+func (s *Memory) Rekey(ctx context.Context, fn hitlist.RekeyFn) (uint64, error) {
+	var rekeyed uint64
+	var err error
+
+	s.m.Range(func(key, value interface{}) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+
+		domain, recipient, ok := splitMemoryKey(key.(string))
+		if !ok {
+			return true
+		}
+
+		if recipient, ok = fn(domain, recipient); ok {
+			s.m.Delete(key)
+			if recipient != nil {
+				s.m.LoadOrStore(memoryKey(domain, recipient), value)
+			}
+
+			rekeyed++
+		}
+
+		return true
+	})
+
+	return rekeyed, err
+}
+
+// memoryKey returns the key of a recipient. Recipients are binary, the domain never contains an "@"
+func memoryKey(d hitlist.Domain, r hitlist.Recipient) string {
+	return string(r) + `@` + string(d)
+}
+
+func splitMemoryKey(key string) (hitlist.Domain, hitlist.Recipient, bool) {
+	i := strings.LastIndexByte(key, '@')
+	if i < 1 || i == len(key)-1 {
+		return "", nil, false
+	}
+
+	return hitlist.Domain(key[i+1:]), hitlist.Recipient(key[:i]), true
+}
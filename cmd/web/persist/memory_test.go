@@ -8,14 +8,25 @@ import (
 	"time"
 
 	"github.com/Dynom/ERI/cmd/web/hitlist"
-	"github.com/Dynom/ERI/testutil"
 	"github.com/Dynom/ERI/types"
 	"github.com/Dynom/ERI/validator"
 	"github.com/Dynom/ERI/validator/validations"
+	"github.com/minio/highwayhash"
 )
 
+func newTestKeys(t *testing.T) *hitlist.Keys {
+	t.Helper()
+
+	keys, err := hitlist.NewKeys(1, []byte("00000000000000000000000000000000"))
+	if err != nil {
+		t.Fatalf("Test setup failed %s", err)
+	}
+
+	return keys
+}
+
 func TestStorage_Range(t *testing.T) {
-	list := hitlist.New(&testutil.MockHasher{}, time.Second*1)
+	list := hitlist.New(newTestKeys(t), time.Second*1)
 
 	type testDataS struct {
 		parts types.EmailParts
@@ -151,7 +162,7 @@ func TestStorage_Range(t *testing.T) {
 }
 
 func TestStorage_Store(t *testing.T) {
-	list := hitlist.New(&testutil.MockHasher{}, time.Second*1)
+	list := hitlist.New(newTestKeys(t), time.Second*1)
 
 	type args struct {
 		ctx   context.Context
@@ -246,3 +257,59 @@ func TestStoreAndRetrieve(t *testing.T) {
 		return nil
 	})
 }
+
+func TestMemory_Rekey(t *testing.T) {
+	ctx := context.Background()
+	keys := newTestKeys(t)
+
+	legacy := func(local string) hitlist.Recipient {
+		empty := highwayhash.Sum128(nil, []byte("00000000000000000000000000000000"))
+		return append(hitlist.Recipient(local), empty[:]...)
+	}
+
+	rekey, err := keys.Rekeyer([]byte("00000000000000000000000000000000"), true)
+	if err != nil {
+		t.Fatalf("Test setup failed %s", err)
+	}
+
+	retired, err := hitlist.NewKeys(2, []byte("11111111111111111111111111111111"))
+	if err != nil {
+		t.Fatalf("Test setup failed %s", err)
+	}
+
+	s := NewMemory()
+	_ = s.Store(ctx, "example.org", legacy("john"), validator.Result{})
+	_ = s.Store(ctx, "example.org", legacy("jane"), validator.Result{})
+	_ = s.Store(ctx, "example.org", keys.Recipient("jane"), validator.Result{})
+	_ = s.Store(ctx, "example.com", keys.Recipient("john"), validator.Result{})
+	_ = s.Store(ctx, "example.com", retired.Recipient("john"), validator.Result{})
+
+	rekeyed, err := s.Rekey(ctx, rekey)
+	if err != nil {
+		t.Fatalf("Rekey() error = %v", err)
+	}
+
+	if rekeyed != 3 {
+		t.Errorf("Expected 2 re-keyed and 1 removed recipient, got %d", rekeyed)
+	}
+
+	want := map[string]bool{
+		string(keys.Recipient("john")) + "@example.org": true,
+		string(keys.Recipient("jane")) + "@example.org": true,
+		string(keys.Recipient("john")) + "@example.com": true,
+	}
+
+	var got int
+	_ = s.Range(ctx, func(d hitlist.Domain, r hitlist.Recipient, vr validator.Result) error {
+		got++
+		if !want[string(r)+"@"+string(d)] {
+			t.Errorf("Unexpected recipient %x of %q", r, d)
+		}
+
+		return nil
+	})
+
+	if got != len(want) {
+		t.Errorf("Expected %d recipients, got %d", len(want), got)
+	}
+}
@@ -28,7 +28,19 @@ func (p *Postgres) Close() error {
 	return p.db.Close()
 }
 
+// Store persists the result. The validations and steps columns are expected to be of type bigint, wide enough to hold
+// validations.EncodingCurrent
 func (p *Postgres) Store(ctx context.Context, d hitlist.Domain, r hitlist.Recipient, vr validator.Result) error {
+	v, err := validations.Flag(vr.Validations).Encode(validations.EncodingCurrent)
+	if err != nil {
+		return err
+	}
+
+	s, err := validations.Flag(vr.Steps).Encode(validations.EncodingCurrent)
+	if err != nil {
+		return err
+	}
+
 	stmt, err := p.db.Prepare(`
 			INSERT INTO
 				hitlist (domain, recipient, validations, steps)
@@ -43,7 +55,7 @@ func (p *Postgres) Store(ctx context.Context, d hitlist.Domain, r hitlist.Recipi
 	}
 
 	defer deferClose(stmt, p.logger)
-	_, err = stmt.ExecContext(ctx, string(d), []byte(r), int64(vr.Validations), int64(vr.Steps))
+	_, err = stmt.ExecContext(ctx, string(d), []byte(r), v, s)
 
 	return err
 }
@@ -84,11 +96,13 @@ func (p *Postgres) Range(ctx context.Context, cb PersistCallbackFn) error {
 		}
 
 		d, r := rowToInternalParts(row)
+		vr, err := rowToResult(row)
+		if err != nil {
+			p.logger.WithError(err).Warn("Error decoding field")
+			continue
+		}
 
-		err := cb(d, r, validator.Result{
-			Validations: validations.Validations(row.Validations),
-			Steps:       validations.Steps(row.Steps),
-		})
+		err = cb(d, r, vr)
 		if err != nil {
 			return err
 		}
@@ -97,10 +111,125 @@ func (p *Postgres) Range(ctx context.Context, cb PersistCallbackFn) error {
 	return nil
 }
 
+// Rekey replaces and removes the recipients in a single transaction, so that a failed migration leaves the table
+// untouched
+func (p *Postgres) Rekey(ctx context.Context, fn hitlist.RekeyFn) (uint64, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	type change struct {
+		domain   string
+		from, to []byte
+	}
+
+	var changes []change
+	err = func() error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT
+				domain,
+				recipient::bytea
+			FROM
+				hitlist
+			FOR UPDATE`)
+		if err != nil {
+			return err
+		}
+
+		defer deferClose(rows, p.logger)
+
+		for rows.Next() {
+			var row hitListRow
+			if err := rows.Scan(&row.Domain, &row.Recipient); err != nil {
+				return err
+			}
+
+			d, r := rowToInternalParts(row)
+			if to, ok := fn(d, r); ok {
+				changes = append(changes, change{domain: row.Domain, from: row.Recipient, to: to})
+			}
+		}
+
+		return rows.Err()
+	}()
+	if err != nil {
+		return 0, err
+	}
+
+	update, err := tx.PrepareContext(ctx, `
+		UPDATE
+			hitlist
+		SET
+			recipient = $3::bytea
+		WHERE
+			domain = $1 AND recipient = $2::bytea
+			AND NOT EXISTS (SELECT 1 FROM hitlist WHERE domain = $1 AND recipient = $3::bytea)`)
+	if err != nil {
+		return 0, err
+	}
+
+	defer deferClose(update, p.logger)
+
+	remove, err := tx.PrepareContext(ctx, `DELETE FROM hitlist WHERE domain = $1 AND recipient = $2::bytea`)
+	if err != nil {
+		return 0, err
+	}
+
+	defer deferClose(remove, p.logger)
+
+	for _, c := range changes {
+		if c.to == nil {
+			if _, err := remove.ExecContext(ctx, c.domain, c.from); err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+
+		res, err := update.ExecContext(ctx, c.domain, c.from, c.to)
+		if err != nil {
+			return 0, err
+		}
+
+		// The recipient is already known under the new key
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			if _, err := remove.ExecContext(ctx, c.domain, c.from); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return uint64(len(changes)), tx.Commit()
+}
+
 func rowToInternalParts(row hitListRow) (hitlist.Domain, hitlist.Recipient) {
 	return hitlist.Domain(row.Domain), hitlist.Recipient(row.Recipient)
 }
 
+// rowToResult decodes the stored validations and steps. Rows written before the flags were widened hold
+// validations.EncodingV1 values, which are a subset of the current encoding.
+func rowToResult(row hitListRow) (validator.Result, error) {
+	v, err := validations.Decode(row.Validations, validations.EncodingCurrent)
+	if err != nil {
+		return validator.Result{}, err
+	}
+
+	s, err := validations.Decode(row.Steps, validations.EncodingCurrent)
+	if err != nil {
+		return validator.Result{}, err
+	}
+
+	return validator.Result{
+		Validations: validations.Validations(v),
+		Steps:       validations.Steps(s),
+	}, nil
+}
+
 type hitListRow struct {
 	Domain      string `sql:"domain"`
 	Recipient   []byte `sql:"recipient"`
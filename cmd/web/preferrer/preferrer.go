@@ -8,9 +8,20 @@ type HasPreferred interface {
 
 type Mapping map[string]string
 
+// New creates a Preferrer, the domains of the mapping are normalised to their canonical form
 func New(mapping Mapping) *Preferrer {
+	if mapping == nil {
+		return &Preferrer{}
+	}
+
+	m := make(Mapping, len(mapping))
+	for domain, preferred := range mapping {
+		ascii, _ := types.NormalizeDomain(domain)
+		m[ascii] = preferred
+	}
+
 	return &Preferrer{
-		m: mapping,
+		m: m,
 	}
 }
 
@@ -21,7 +32,7 @@ type Preferrer struct {
 // HasPreferred returns the input when there isn't a match or a preferred result if it has. The second return argument
 // should be used to discriminate between the two.
 func (p *Preferrer) HasPreferred(parts types.EmailParts) (string, bool) {
-	if l, ok := p.m[parts.Domain]; ok {
+	if l, ok := p.m[parts.CanonicalDomain()]; ok {
 		return l, true
 	}
 
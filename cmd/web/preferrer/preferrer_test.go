@@ -20,6 +20,7 @@ func TestNew(t *testing.T) {
 		{name: "nil map", args: args{mapping: nil}, want: &Preferrer{}},
 		{name: "populated 1", args: args{mapping: Mapping{"a": "b"}}, want: &Preferrer{m: Mapping{"a": "b"}}},
 		{name: "populated N", args: args{mapping: Mapping{"a": "b", "b": "c"}}, want: &Preferrer{m: Mapping{"a": "b", "b": "c"}}},
+		{name: "unicode", args: args{mapping: Mapping{"Bücher.example": "example.org"}}, want: &Preferrer{m: Mapping{"xn--bcher-kva.example": "example.org"}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -40,6 +41,7 @@ func TestPreferrer_HasPreferred(t *testing.T) {
 	}{
 		{name: "nil map", m: nil, parts: types.NewEmailFromParts("john.doe", "example.org"), want: "example.org", has: false},
 		{name: "match", m: Mapping{"example.com": "example.org"}, parts: types.NewEmailFromParts("john.doe", "example.com"), want: "example.org", has: true},
+		{name: "match unicode", m: Mapping{"xn--bcher-kva.example": "example.org"}, parts: types.NewEmailFromParts("john.doe", "bücher.example"), want: "example.org", has: true},
 		{name: "no match", m: Mapping{"a": "b"}, parts: types.NewEmailFromParts("john.doe", "example.org"), want: "example.org", has: false},
 	}
 	for _, tt := range tests {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/Dynom/ERI/cmd/web/persist"
@@ -15,8 +16,12 @@ import (
 	"github.com/Dynom/ERI/validator"
 	"github.com/Dynom/TySug/finder"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 )
 
+// perRecipientFlags are the validations that apply to an address, rather than to its domain
+const perRecipientFlags = validations.FSyntax | validations.FValidRCPT | validations.FRoleAccount
+
 func validatorContextTTLProxy(duration time.Duration, fn validator.CheckFn) validator.CheckFn {
 	return func(ctx context.Context, parts types.EmailParts, options ...validator.ArtifactFn) validator.Result {
 		afn := options
@@ -28,13 +33,90 @@ func validatorContextTTLProxy(duration time.Duration, fn validator.CheckFn) vali
 	}
 }
 
-// validatorHitListProxy Keeps HitList up-to-date and acts as a partial cache for the validator
+// validatorCoalesceProxy lets concurrent checks of the same domain share a single run of the domain's checks (e.g. the
+// DNS lookups). The per-recipient checks still run for each address, unless the very same address is being checked
+// concurrently, in which case the entire result is shared.
+func validatorCoalesceProxy(logger logrus.FieldLogger, fn validator.CheckFn) validator.CheckFn {
+	logger = logger.WithField("middleware", "coalesce_proxy")
+
+	var domains, addresses singleflight.Group
+	return func(ctx context.Context, parts types.EmailParts, options ...validator.ArtifactFn) validator.Result {
+		domain := strings.ToLower(parts.CanonicalDomain())
+
+		v, _, _ := addresses.Do(parts.Local+"@"+domain, func() (interface{}, error) {
+			// The result is shared, so it must not depend on this request staying around
+			ctx, cancel := detach(ctx)
+			defer cancel()
+
+			var ran bool
+			v, _, _ := domains.Do(domain, func() (interface{}, error) {
+				ran = true
+				return fn(ctx, parts, options...), nil
+			})
+
+			shared := v.(validator.Result)
+			if ran {
+				return shared, nil
+			}
+
+			// When the shared run timed out, its outcome isn't conclusive
+			if shared.Diagnostics.Reason == validator.ReasonTimeout {
+				return fn(ctx, parts, options...), nil
+			}
+
+			logger.WithFields(logrus.Fields{
+				handlers.RequestID.String(): ctx.Value(handlers.RequestID),
+				"domain":                    domain,
+			}).Debug("Running validator with the result of a concurrent check")
+
+			result := fn(ctx, parts, append(options, func(artifact *validator.Artifact) {
+				artifact.Steps = shared.Steps.RemoveFlag(perRecipientFlags)
+				artifact.Validations = shared.Validations.RemoveFlag(perRecipientFlags)
+			})...)
+
+			// The posture step is skipped, since it already ran for the domain
+			if result.Posture == nil {
+				result.Posture = shared.Posture
+			}
+
+			return result, nil
+		})
+
+		return v.(validator.Result)
+	}
+}
+
+// detachedContext holds the values of its parent, but not its deadline or cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// detach returns a context with the values and the deadline of ctx, that isn't canceled when ctx is
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := detachedContext{parent: ctx}
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+
+	return context.WithCancel(detached)
+}
+
+// validatorHitListProxy Keeps HitList up-to-date and acts as a partial cache for the validator. The aliases of a
+// provider (e.g. googlemail.com for gmail.com) share a single HitList entry, but they're different DNS zones. An alias
+// is therefore always checked in full and its result doesn't replace the result of the provider's domain.
 func validatorHitListProxy(hitList *hitlist.HitList, logger logrus.FieldLogger, fn validator.CheckFn) validator.CheckFn {
 	logger = logger.WithField("middleware", "cache_proxy")
 	return func(ctx context.Context, parts types.EmailParts, options ...validator.ArtifactFn) validator.Result {
 		afn := options
 
-		cvr, exists := hitList.GetDomainValidationDetails(hitlist.Domain(parts.Domain))
+		domain := hitlist.NewDomain(parts.Domain)
+		alias := string(domain) != parts.CanonicalDomain()
+		cvr, exists := hitList.GetDomainValidationDetails(domain)
 
 		logger := logger.WithFields(logrus.Fields{
 			handlers.RequestID.String(): ctx.Value(handlers.RequestID),
@@ -42,24 +124,53 @@ func validatorHitListProxy(hitList *hitlist.HitList, logger logrus.FieldLogger,
 			"valid_until":               cvr.ValidUntil.String(),
 		})
 
-		if exists {
-			if cvr.ValidUntil.After(time.Now()) {
-				afn = append(afn, func(artifact *validator.Artifact) {
-					logger.Debug("Running validator with cache from previous run")
-
-					// The cache allows us to skip expensive steps that we might be doing. However basic syntax validation should
-					// always be done. We're discriminating on domain, so we can't vouch for the entire address without a basic test
-					artifact.Steps = cvr.Steps.RemoveFlag(validations.FSyntax)
-					artifact.Validations = cvr.Validations.RemoveFlag(validations.FSyntax)
-				})
-			} else {
-				logger.Debug("Not using stale cache entry from previous run")
-			}
+		cached := exists && !alias && cvr.ValidUntil.After(time.Now())
+		switch {
+		case exists && alias:
+			logger.Debug("Not using the cache entry of another domain of the provider")
+
+		case cached:
+			afn = append(afn, func(artifact *validator.Artifact) {
+				logger.Debug("Running validator with cache from previous run")
+
+				// The cache allows us to skip expensive steps that we might be doing. However basic syntax validation should
+				// always be done. We're discriminating on domain, so we can't vouch for the entire address without a basic test
+				// and neither for a recipient probed or classified on a previous run. The disposable list is reloaded while
+				// results are cached, so the (cheap) lookup runs again as well.
+				uncached := perRecipientFlags | validations.FDisposable
+				if cvr.Posture == nil {
+					// E.g. a result that was loaded from storage, the posture runs again when it's part of the pipeline
+					uncached |= validations.FPosture
+				}
+
+				artifact.Steps = cvr.Steps.RemoveFlag(uncached)
+				artifact.Validations = cvr.Validations.RemoveFlag(uncached)
+			})
+
+		case exists:
+			logger.Debug("Not using stale cache entry from previous run")
 		}
 
 		vr := fn(ctx, parts, afn...)
 
-		err := hitList.Add(parts, vr)
+		// The posture step is skipped, since the HitList holds the posture of the domain
+		if cached && vr.Posture == nil {
+			vr.Posture = cvr.Posture
+		}
+
+		// A result that relied on the cache doesn't extend its validity
+		add := hitList.Add
+		if cached {
+			add = hitList.AddCached
+		}
+
+		// Only the recipient of an alias is added, the provider's domain keeps its own result
+		hvr := vr
+		if alias && exists {
+			add, hvr = hitList.AddCached, cvr.Result
+		}
+
+		err := add(parts, hvr)
 		if err != nil {
 			logger.WithFields(logrus.Fields{
 				"error": err,
@@ -77,7 +188,12 @@ func validatorPersistProxy(persister persist.Persister, hitList *hitlist.HitList
 	return func(ctx context.Context, parts types.EmailParts, options ...validator.ArtifactFn) validator.Result {
 		logger := logger.WithField(handlers.RequestID.String(), ctx.Value(handlers.RequestID))
 
-		_, existed := hitList.Has(parts)
+		domain, existed := hitList.Has(parts)
+		if hitList.RecipientStorage() == hitlist.StorageCount {
+			// Recipients are never known when they're only counted, storing every recipient of a known domain would mean a
+			// write for each check. Only the first recipient of a domain is stored.
+			existed = domain
+		}
 
 		vr := fn(ctx, parts, options...)
 
@@ -117,9 +233,10 @@ func validatorNotifyProxy(svc *gcp.PubSubSvc, _ *hitlist.HitList, logger logrus.
 
 		data := pubsub.Data{
 			Local:       parts.Local,
-			Domain:      parts.Domain,
+			Domain:      parts.CanonicalDomain(),
 			Validations: vr.Validations,
 			Steps:       vr.Steps,
+			Version:     validations.EncodingCurrent,
 		}
 
 		err := svc.Publish(ctx, data)
@@ -142,7 +259,7 @@ func validatorUpdateFinderProxy(finder *finder.Finder, hitList *hitlist.HitList,
 
 		vr := fn(ctx, parts, options...)
 
-		if vr.Validations.IsValidationsForValidDomain() && !finder.Exact(parts.Domain) {
+		if vr.Validations.IsValidationsForValidDomain() && !finder.Exact(string(hitlist.NewDomain(parts.Domain))) {
 			finder.Refresh(hitList.GetValidAndUsageSortedDomains())
 
 			logger.WithFields(logrus.Fields{
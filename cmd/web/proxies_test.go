@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Dynom/ERI/cmd/web/hitlist"
+	"github.com/Dynom/ERI/cmd/web/persist"
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator"
+	"github.com/Dynom/ERI/validator/validations"
+	"github.com/Dynom/TySug/finder"
+	testLog "github.com/sirupsen/logrus/hooks/test"
+)
+
+// lookupStub is a CheckFn that counts how often it ran the domain checks, the first run blocks until released
+type lookupStub struct {
+	calls       int32
+	lookups     int32
+	postures    int32
+	disposables int32
+	started     chan struct{}
+	release     chan struct{}
+	reason      validator.Reason
+	once        sync.Once
+}
+
+func newLookupStub() *lookupStub {
+	return &lookupStub{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (s *lookupStub) Check(ctx context.Context, _ types.EmailParts, options ...validator.ArtifactFn) validator.Result {
+	atomic.AddInt32(&s.calls, 1)
+
+	var a validator.Artifact
+	for _, o := range options {
+		o(&a)
+	}
+
+	s.once.Do(func() {
+		close(s.started)
+		<-s.release
+	})
+
+	r := validator.Result{
+		Steps:       a.Steps | validations.Steps(validations.FSyntax),
+		Validations: a.Validations,
+	}
+
+	// Like a recipient probe, the per-recipient checks fail when their context is done
+	if ctx.Err() == nil {
+		r.Validations |= validations.Validations(validations.FSyntax)
+	}
+
+	if !a.Steps.HasFlag(validations.FMXLookup) {
+		atomic.AddInt32(&s.lookups, 1)
+		r.Steps |= validations.Steps(validations.FMXLookup)
+
+		// Like the resolver, a lookup fails when its context is done
+		if ctx.Err() != nil {
+			r.Diagnostics.Reason = validator.ReasonMXLookup
+			return r
+		}
+
+		r.Validations |= validations.Validations(validations.FMXLookup)
+		r.Diagnostics.Reason = s.reason
+	}
+
+	if !a.Steps.HasFlag(validations.FDisposable) {
+		atomic.AddInt32(&s.disposables, 1)
+		r.Steps |= validations.Steps(validations.FDisposable)
+	}
+
+	if !a.Steps.HasFlag(validations.FPosture) {
+		atomic.AddInt32(&s.postures, 1)
+		r.Steps |= validations.Steps(validations.FPosture)
+		r.Validations |= validations.Validations(validations.FPosture)
+		r.Posture = &validator.Posture{}
+	}
+
+	return r
+}
+
+func Test_validatorCoalesceProxy(t *testing.T) {
+	tests := []struct {
+		name        string
+		addresses   []string
+		reason      validator.Reason
+		cancel      []int
+		wantCalls   int32
+		wantLookups int32
+	}{
+		{name: "same domain", addresses: []string{"john@example.org", "jane@Example.org", "jake@example.org"}, wantCalls: 3, wantLookups: 1},
+		{name: "same address", addresses: []string{"john@example.org", "john@example.org", "john@example.org"}, wantCalls: 1, wantLookups: 1},
+		{name: "different domains", addresses: []string{"john@example.org", "john@example.com"}, wantCalls: 2, wantLookups: 2},
+		{name: "timed out results aren't shared", addresses: []string{"john@example.org", "jane@example.org"}, reason: validator.ReasonTimeout, wantCalls: 2, wantLookups: 2},
+		{name: "first request went away", addresses: []string{"john@example.org", "jane@example.org"}, cancel: []int{0}, wantCalls: 2, wantLookups: 1},
+		{name: "first request of an address went away", addresses: []string{"john@example.org", "jane@example.org", "jane@example.org"}, cancel: []int{1}, wantCalls: 2, wantLookups: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := testLog.NewNullLogger()
+			stub := newLookupStub()
+			stub.reason = tt.reason
+			fn := validatorCoalesceProxy(logger, stub.Check)
+
+			ctxs := make([]context.Context, len(tt.addresses))
+			cancels := make([]context.CancelFunc, len(tt.addresses))
+			for i := range tt.addresses {
+				ctxs[i], cancels[i] = context.WithCancel(context.Background())
+				defer cancels[i]()
+			}
+
+			var wg sync.WaitGroup
+			results := make([]validator.Result, len(tt.addresses))
+			check := func(i int) {
+				defer wg.Done()
+
+				parts, _ := types.NewEmailParts(tt.addresses[i])
+				results[i] = fn(ctxs[i], parts)
+			}
+
+			wg.Add(len(tt.addresses))
+			go check(0)
+			<-stub.started
+
+			// Start the other checks in order, so the first request of an address is the one joining the domain's run
+			for i := 1; i < len(tt.addresses); i++ {
+				go check(i)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			// Give the other checks the time to join the first one, before their requests go away
+			time.Sleep(50 * time.Millisecond)
+			for _, i := range tt.cancel {
+				cancels[i]()
+			}
+
+			close(stub.release)
+			wg.Wait()
+
+			if calls := atomic.LoadInt32(&stub.calls); calls != tt.wantCalls {
+				t.Errorf("Expected %d call(s), got %d", tt.wantCalls, calls)
+			}
+
+			if lookups := atomic.LoadInt32(&stub.lookups); lookups != tt.wantLookups {
+				t.Errorf("Expected %d lookup(s), got %d", tt.wantLookups, lookups)
+			}
+
+			for i, r := range results {
+				if !r.Validations.HasFlag(validations.FMXLookup) || !r.Validations.HasFlag(validations.FSyntax) {
+					t.Errorf("Expected %q to receive a result, got %s", tt.addresses[i], r.Validations)
+				}
+
+				if r.Posture == nil {
+					t.Errorf("Expected %q to receive the posture of the domain", tt.addresses[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_validatorHitListProxy(t *testing.T) {
+	storedVR := validator.Result{
+		Steps:       validations.Steps(validations.FSyntax | validations.FMXLookup | validations.FDisposable | validations.FPosture),
+		Validations: validations.Validations(validations.FValid | validations.FSyntax | validations.FMXLookup | validations.FPosture),
+	}
+
+	tests := []struct {
+		name           string
+		addresses      []string
+		stored         bool
+		wantLookups    int32
+		wantPostures   int32
+		wantRecipients uint64
+	}{
+		{name: "posture is cached", addresses: []string{"john@example.org", "jane@example.org"}, wantLookups: 1, wantPostures: 1, wantRecipients: 2},
+		{name: "stored result without a posture", addresses: []string{"john@example.org", "jane@example.org"}, stored: true, wantLookups: 0, wantPostures: 1, wantRecipients: 3},
+		{name: "aliases aren't cached", addresses: []string{"john@gmail.com", "jane@googlemail.com"}, wantLookups: 2, wantPostures: 2, wantRecipients: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := testLog.NewNullLogger()
+			keys, _ := hitlist.NewKeys(1, []byte("00000000000000000000000000000000"))
+			hitList := hitlist.New(keys, time.Hour)
+
+			if tt.stored {
+				_ = hitList.AddInternalParts("example.org", keys.Recipient("jake"), storedVR)
+			}
+
+			stub := newLookupStub()
+			close(stub.release)
+			fn := validatorHitListProxy(hitList, logger, stub.Check)
+
+			for _, address := range tt.addresses {
+				parts, _ := types.NewEmailParts(address)
+				if r := fn(context.Background(), parts); r.Posture == nil {
+					t.Errorf("Expected %q to receive the posture of the domain", address)
+				}
+			}
+
+			if lookups := atomic.LoadInt32(&stub.lookups); lookups != tt.wantLookups {
+				t.Errorf("Expected %d lookup(s), got %d", tt.wantLookups, lookups)
+			}
+
+			if postures := atomic.LoadInt32(&stub.postures); postures != tt.wantPostures {
+				t.Errorf("Expected the posture to be looked up %d time(s), got %d", tt.wantPostures, postures)
+			}
+
+			// The disposable list can be reloaded while the domain is cached, so it's never taken from the cache
+			if disposables := atomic.LoadInt32(&stub.disposables); disposables != int32(len(tt.addresses)) {
+				t.Errorf("Expected the disposable list to be consulted %d time(s), got %d", len(tt.addresses), disposables)
+			}
+
+			parts, _ := types.NewEmailParts(tt.addresses[0])
+			if got := hitList.GetRecipientCount(hitlist.NewDomain(parts.Domain)); got != tt.wantRecipients {
+				t.Errorf("Expected %d recipient(s), got %d", tt.wantRecipients, got)
+			}
+		})
+	}
+}
+
+func Test_validatorUpdateFinderProxy(t *testing.T) {
+	logger, _ := testLog.NewNullLogger()
+	keys, _ := hitlist.NewKeys(1, []byte("00000000000000000000000000000000"))
+
+	f, err := finder.New([]string{"gmail.com"}, finder.WithAlgorithm(finder.NewJaroWinklerDefaults()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub := newLookupStub()
+	close(stub.release)
+	fn := validatorUpdateFinderProxy(f, hitlist.New(keys, time.Hour), logger, stub.Check)
+
+	// Refreshing replaces the list with the domains of the empty HitList
+	parts, _ := types.NewEmailParts("john@googlemail.com")
+	if _ = fn(context.Background(), parts); !f.Exact("gmail.com") {
+		t.Errorf("Expected an alias of a known domain not to refresh the finder")
+	}
+
+	parts, _ = types.NewEmailParts("john@example.org")
+	if _ = fn(context.Background(), parts); f.Exact("gmail.com") {
+		t.Errorf("Expected an unknown domain to refresh the finder")
+	}
+}
+
+// storeCounter counts the results stored by the Persister it wraps
+type storeCounter struct {
+	persist.Persister
+	stored int32
+}
+
+func (s *storeCounter) Store(ctx context.Context, d hitlist.Domain, r hitlist.Recipient, vr validator.Result) error {
+	atomic.AddInt32(&s.stored, 1)
+	return s.Persister.Store(ctx, d, r, vr)
+}
+
+func Test_validatorPersistProxy(t *testing.T) {
+	addresses := []string{"john@example.org", "john@example.org", "jane@example.org", "john@example.com"}
+
+	tests := []struct {
+		name       string
+		storage    hitlist.RecipientStorage
+		wantStored int32
+	}{
+		{name: "map", storage: hitlist.StorageMap, wantStored: 3},
+		{name: "compact", storage: hitlist.StorageCompact, wantStored: 3},
+		{name: "count", storage: hitlist.StorageCount, wantStored: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := testLog.NewNullLogger()
+			keys, _ := hitlist.NewKeys(1, []byte("00000000000000000000000000000000"))
+			hitList := hitlist.New(keys, time.Hour, hitlist.WithRecipientStorage(tt.storage))
+			persister := &storeCounter{Persister: persist.NewMemory()}
+
+			stub := newLookupStub()
+			close(stub.release)
+			fn := validatorPersistProxy(persister, hitList, logger, validatorHitListProxy(hitList, logger, stub.Check))
+
+			for _, address := range addresses {
+				parts, _ := types.NewEmailParts(address)
+				_ = fn(context.Background(), parts)
+			}
+
+			if stored := atomic.LoadInt32(&persister.stored); stored != tt.wantStored {
+				t.Errorf("Expected %d stored result(s), got %d", tt.wantStored, stored)
+			}
+		})
+	}
+}
@@ -8,8 +8,30 @@ type Notification struct {
 }
 
 type Data struct {
-	Local       string                  `json:"local"`
-	Domain      string                  `json:"domain"`
-	Validations validations.Validations `json:"v"`
-	Steps       validations.Steps       `json:"s"`
+	Local       string                      `json:"local"`
+	Domain      string                      `json:"domain"`
+	Validations validations.Validations     `json:"v"`
+	Steps       validations.Steps           `json:"s"`
+	Version     validations.EncodingVersion `json:"ver,omitempty"`
+}
+
+// Decode returns the Validations and Steps, after verifying they fit the encoding version they were published with.
+// Data without a version predates the versioning and is treated as validations.EncodingV1
+func (d Data) Decode() (validations.Validations, validations.Steps, error) {
+	version := d.Version
+	if version == 0 {
+		version = validations.EncodingV1
+	}
+
+	v, err := validations.Decode(int64(d.Validations), version)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	s, err := validations.Decode(int64(d.Steps), version)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return validations.Validations(v), validations.Steps(s), nil
 }
@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+func TestData_Decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wantV   validations.Validations
+		wantS   validations.Steps
+		wantErr bool
+	}{
+		{
+			name:    "unversioned (v1) payload",
+			payload: `{"local":"john","domain":"example.org","v":67,"s":71}`,
+			wantV:   validations.Validations(validations.FValid | validations.FSyntax | validations.FDisposable),
+			wantS:   validations.Steps(validations.FValid | validations.FSyntax | validations.FMXLookup | validations.FDisposable),
+		},
+		{
+			name:    "v2 payload beyond 8 bits",
+			payload: `{"local":"john","domain":"example.org","v":257,"s":258,"ver":2}`,
+			wantV:   validations.Validations(1<<8 | validations.FValid),
+			wantS:   validations.Steps(1<<8 | validations.FSyntax),
+		},
+		{
+			name:    "unversioned payload beyond 8 bits",
+			payload: `{"local":"john","domain":"example.org","v":257,"s":2}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown version",
+			payload: `{"local":"john","domain":"example.org","v":1,"s":1,"ver":200}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Data
+			if err := json.Unmarshal([]byte(tt.payload), &d); err != nil {
+				t.Errorf("json.Unmarshal() unexpected error %s", err)
+				t.FailNow()
+			}
+
+			v, s, err := d.Decode()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if v != tt.wantV || s != tt.wantS {
+				t.Errorf("Decode() got = %s, %s, want %s, %s", v, s, tt.wantV, tt.wantS)
+			}
+		})
+	}
+}
@@ -126,7 +126,8 @@ func TestAutocompleteSvc_Autocomplete(t *testing.T) {
 		},
 	}
 
-	hl := hitlist.New(&testutil.MockHasher{}, 1*time.Hour)
+	keys, _ := hitlist.NewKeys(1, []byte("00000000000000000000000000000000"))
+	hl := hitlist.New(keys, 1*time.Hour)
 	_ = hl.AddDomain("example", validator.Result{
 		Validations: validations.Validations(validations.FSyntax | validations.FMXLookup | validations.FValid),
 		Steps:       validations.Steps(validations.FSyntax),
@@ -179,7 +180,8 @@ func TestAutocompleteSvc_filter(t *testing.T) {
 	ctxExpired, cancel := context.WithTimeout(context.Background(), -1*time.Hour)
 	cancel()
 
-	hl := hitlist.New(&testutil.MockHasher{}, 1*time.Hour)
+	keys, _ := hitlist.NewKeys(1, []byte("00000000000000000000000000000000"))
+	hl := hitlist.New(keys, 1*time.Hour)
 	_ = hl.AddDomain("example", validator.Result{
 		Validations: validations.Validations(validations.FSyntax | validations.FMXLookup | validations.FValid),
 		Steps:       validations.Steps(validations.FSyntax),
@@ -39,6 +39,18 @@ type SuggestSvc struct {
 type SuggestResult struct {
 	Alternatives []string
 	HasValidMX   bool
+	Disposable   bool
+	RoleAccount  bool
+	Recipient    validator.RecipientStatus
+
+	// UnknownTLD is true when the top-level domain of the input doesn't exist, see validator.WithTLDList
+	UnknownTLD bool
+
+	// Canonical is the address in its canonical form, see types.Canonicalizer
+	Canonical string
+
+	// Posture is nil, unless validator.StepPosture is part of the pipeline
+	Posture *validator.Posture
 }
 
 // @todo make this configurable and Algorithm dependent
@@ -48,6 +60,7 @@ func (c *SuggestSvc) Suggest(ctx context.Context, email string) (SuggestResult,
 	emailStrLower := strings.ToLower(email)
 	sr := SuggestResult{
 		Alternatives: []string{email},
+		Recipient:    validator.RecipientUnknown,
 	}
 
 	log := c.logger.WithFields(logrus.Fields{
@@ -77,6 +90,11 @@ func (c *SuggestSvc) Suggest(ctx context.Context, email string) (SuggestResult,
 	}
 
 	if !vr.Validations.IsValid() {
+		log.WithFields(logrus.Fields{
+			"reason":      vr.Diagnostics.Reason,
+			"failed_step": vr.Diagnostics.FailedStep,
+			"detail":      vr.Diagnostics.Detail,
+		}).Debug("Input didn't pass validation")
 
 		// No result so far, proceeding with finding domain alternatives
 		alts := c.getAlternatives(ctx, parts)
@@ -102,13 +120,22 @@ func (c *SuggestSvc) Suggest(ctx context.Context, email string) (SuggestResult,
 	}
 
 	sr.HasValidMX = vr.Validations.HasFlag(validations.FMXDomainHasIP | validations.FMXLookup)
+	sr.Disposable = vr.Validations.HasFlag(validations.FDisposable)
+	sr.RoleAccount = vr.IsRoleAccount()
+	sr.Recipient = vr.RecipientStatus()
+	sr.UnknownTLD = vr.Diagnostics.Reason == validator.ReasonUnknownTLD
+	sr.Posture = vr.Posture
 	sr.Alternatives = alts
 
+	if err == nil {
+		sr.Canonical = types.DefaultCanonicalizer.Address(parts).Address
+	}
+
 	return sr, err
 }
 
 func (c *SuggestSvc) getAlternatives(ctx context.Context, parts types.EmailParts) []string {
-	alt, score, exact := c.finder.FindCtx(ctx, parts.Domain)
+	alt, score, exact := c.finder.FindCtx(ctx, parts.CanonicalDomain())
 
 	c.logger.WithFields(logrus.Fields{
 		handlers.RequestID.String(): ctx.Value(handlers.RequestID),
@@ -120,6 +147,11 @@ func (c *SuggestSvc) getAlternatives(ctx context.Context, parts types.EmailParts
 	}).Debug("Used Finder")
 
 	if score > finderThreshold {
+		// Finder holds the ASCII form, responses keep the script of the input
+		if strings.ToLower(parts.Domain) != parts.CanonicalDomain() {
+			_, alt = types.NormalizeDomain(alt)
+		}
+
 		parts = types.NewEmailFromParts(parts.Local, alt)
 	}
 
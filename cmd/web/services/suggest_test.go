@@ -54,7 +54,16 @@ func TestSuggestSvc_Suggest(t *testing.T) {
 		{
 			name:       "All good",
 			email:      "john.doe@example.org",
-			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}},
+			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}, Recipient: validator.RecipientUnknown, Canonical: "john.doe@example.org"},
+			wantErr:    false,
+			validator:  createMockValidator(validations.FSyntax|validations.FValid, validations.FSyntax|validations.FValid),
+			finderList: []string{},
+			ctx:        context.Background(),
+		},
+		{
+			name:       "Canonical address of a provider alias",
+			email:      "John.Doe+news@googlemail.com",
+			want:       SuggestResult{Alternatives: []string{"John.Doe+news@googlemail.com"}, Recipient: validator.RecipientUnknown, Canonical: "johndoe@gmail.com"},
 			wantErr:    false,
 			validator:  createMockValidator(validations.FSyntax|validations.FValid, validations.FSyntax|validations.FValid),
 			finderList: []string{},
@@ -63,7 +72,7 @@ func TestSuggestSvc_Suggest(t *testing.T) {
 		{
 			name:       "Including preferred",
 			email:      "john.doe@example.com",
-			want:       SuggestResult{Alternatives: []string{"john.doe@example.org", "john.doe@example.com"}},
+			want:       SuggestResult{Alternatives: []string{"john.doe@example.org", "john.doe@example.com"}, Recipient: validator.RecipientUnknown, Canonical: "john.doe@example.com"},
 			wantErr:    false,
 			validator:  createMockValidator(validations.FSyntax|validations.FValid, validations.FSyntax|validations.FValid),
 			finderList: []string{"example.com", "example.org"},
@@ -73,7 +82,7 @@ func TestSuggestSvc_Suggest(t *testing.T) {
 		{
 			name:       "Invalid domain, should fall back on finder",
 			email:      "john.doe@example.or",
-			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}},
+			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}, Recipient: validator.RecipientUnknown, Canonical: "john.doe@example.or"},
 			wantErr:    false,
 			validator:  createMockValidator(validations.FSyntax, validations.FSyntax),
 			finderList: []string{"example.org"},
@@ -82,17 +91,26 @@ func TestSuggestSvc_Suggest(t *testing.T) {
 		{
 			name:       "Invalid domain, should fall back on finder and be corrected by preferrer",
 			email:      "john.doe@example.cm",
-			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}},
+			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}, Recipient: validator.RecipientUnknown, Canonical: "john.doe@example.cm"},
 			wantErr:    false,
 			validator:  createMockValidator(validations.FSyntax, validations.FSyntax),
 			finderList: []string{"example.org"},
 			preferMap:  preferrer.Mapping{"example.com": "example.org"},
 			ctx:        context.Background(),
 		},
+		{
+			name:       "Invalid internationalised domain, alternative keeps the script of the input",
+			email:      "john.doe@bücher.exampl",
+			want:       SuggestResult{Alternatives: []string{"john.doe@bücher.example"}, Recipient: validator.RecipientUnknown, Canonical: "john.doe@xn--bcher-kva.exampl"},
+			wantErr:    false,
+			validator:  createMockValidator(validations.FSyntax, validations.FSyntax),
+			finderList: []string{"xn--bcher-kva.example"},
+			ctx:        context.Background(),
+		},
 		{
 			name:       "Invalid domain, finder has no alternative",
 			email:      "john.doe@example.or",
-			want:       SuggestResult{Alternatives: []string{"john.doe@example.or"}},
+			want:       SuggestResult{Alternatives: []string{"john.doe@example.or"}, Recipient: validator.RecipientUnknown, Canonical: "john.doe@example.or"},
 			wantErr:    false,
 			validator:  createMockValidator(validations.FSyntax, validations.FSyntax),
 			finderList: []string{"be"}, // Note: Violates the finder.WithLengthTolerance filter, so won't be used
@@ -101,7 +119,7 @@ func TestSuggestSvc_Suggest(t *testing.T) {
 		{
 			name:        "Malformed",
 			email:       " john.doe@example.org", // leading space
-			want:        SuggestResult{Alternatives: []string{" john.doe@example.org"}},
+			want:        SuggestResult{Alternatives: []string{" john.doe@example.org"}, Recipient: validator.RecipientUnknown},
 			wantErr:     true,
 			validator:   createMockValidator(0, validations.FSyntax),
 			finderList:  []string{},
@@ -111,17 +129,58 @@ func TestSuggestSvc_Suggest(t *testing.T) {
 		{
 			name:        "Malformed",
 			email:       "john.doe#example.org", // Missing @, fails a sanity check, earlier than validator
-			want:        SuggestResult{Alternatives: []string{"john.doe#example.org"}},
+			want:        SuggestResult{Alternatives: []string{"john.doe#example.org"}, Recipient: validator.RecipientUnknown},
 			wantErr:     true,
 			validator:   nil, // Validator should never be reached
 			finderList:  []string{},
 			logContains: "Unable to split input",
 			ctx:         context.Background(),
 		},
+		{
+			name:  "Unknown TLD",
+			email: "john.doe@example.cmo",
+			want:  SuggestResult{Alternatives: []string{"john.doe@example.com"}, Recipient: validator.RecipientUnknown, UnknownTLD: true},
+			validator: func(ctx context.Context, parts types.EmailParts, options ...validator.ArtifactFn) validator.Result {
+				return validator.Result{
+					Steps:       validations.Steps(validations.FSyntax),
+					Diagnostics: validator.Diagnostics{Reason: validator.ReasonUnknownTLD},
+				}
+			},
+			wantErr:    true,
+			finderList: []string{"example.com"},
+			ctx:        context.Background(),
+		},
+		{
+			name:       "Disposable",
+			email:      "john.doe@mailinator.com",
+			want:       SuggestResult{Alternatives: []string{"john.doe@mailinator.com"}, HasValidMX: true, Disposable: true, Recipient: validator.RecipientUnknown, Canonical: "john.doe@mailinator.com"},
+			wantErr:    false,
+			validator:  createMockValidator(validations.FSyntax|validations.FMXLookup|validations.FDisposable|validations.FValid, validations.FSyntax|validations.FMXLookup|validations.FDisposable|validations.FValid),
+			finderList: []string{},
+			ctx:        context.Background(),
+		},
+		{
+			name:       "Role account",
+			email:      "info@example.org",
+			want:       SuggestResult{Alternatives: []string{"info@example.org"}, HasValidMX: true, RoleAccount: true, Recipient: validator.RecipientUnknown, Canonical: "info@example.org"},
+			wantErr:    false,
+			validator:  createMockValidator(validations.FSyntax|validations.FMXLookup|validations.FMXDomainHasIP|validations.FRoleAccount|validations.FValid, validations.FSyntax|validations.FMXLookup|validations.FMXDomainHasIP|validations.FRoleAccount|validations.FValid),
+			finderList: []string{},
+			ctx:        context.Background(),
+		},
+		{
+			name:       "Domain accepts all recipients",
+			email:      "john.doe@example.org",
+			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}, HasValidMX: true, Recipient: validator.RecipientAcceptAll, Canonical: "john.doe@example.org"},
+			wantErr:    false,
+			validator:  createMockValidator(validations.FSyntax|validations.FMXLookup|validations.FMXDomainHasIP|validations.FAcceptAll|validations.FValid, validations.FSyntax|validations.FMXLookup|validations.FMXDomainHasIP|validations.FAcceptAll|validations.FValid),
+			finderList: []string{},
+			ctx:        context.Background(),
+		},
 		{
 			name:       "Canceled CTX",
 			email:      "john.doe@example.org",
-			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}},
+			want:       SuggestResult{Alternatives: []string{"john.doe@example.org"}, Recipient: validator.RecipientUnknown},
 			wantErr:    true,
 			validator:  createMockValidator(validations.FSyntax|validations.FValid, validations.FSyntax|validations.FValid),
 			finderList: []string{},
@@ -188,7 +247,9 @@ func containsLogWhileExpected(expected string, entries []logrus.Entry) (found bo
 func Test_didDeadlineExpire(t *testing.T) {
 	ctx := context.Background()
 
-	ctxExpired, _ := context.WithDeadline(ctx, time.Now())
+	ctxExpired, cancel := context.WithDeadline(ctx, time.Now())
+	defer cancel()
+
 	ctxCanceled, c := context.WithCancel(ctx)
 	c()
 
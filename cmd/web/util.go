@@ -22,6 +22,8 @@ import (
 	"github.com/Dynom/ERI/runtimer"
 	"github.com/Dynom/ERI/types"
 	"github.com/Dynom/ERI/validator"
+	"github.com/Dynom/ERI/validator/resolver"
+	"github.com/Dynom/ERI/validator/validations"
 	"github.com/Dynom/TySug/finder"
 	"google.golang.org/api/option"
 
@@ -83,17 +85,72 @@ func registerProfileHandler(mux *http.ServeMux, conf config.Config) {
 	mux.HandleFunc(`/`+prefix+`/pprof/trace`, pprof.Trace)
 }
 
-func setCustomResolver(dialer *net.Dialer, host string) {
-	if dialer.Resolver == nil {
-		dialer.Resolver = &net.Resolver{
-			PreferGo: true,
+// setCustomResolver makes the dialer resolve host names using pool
+func setCustomResolver(dialer *net.Dialer, pool resolver.Transport) {
+	dialer.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial:     resolver.DialFunc(pool),
+	}
+}
+
+// createResolverPool creates a pool of the configured resolvers, it returns nil when none are configured
+func createResolverPool(conf config.Config) (*resolver.Pool, error) {
+	addresses := conf.ResolverAddresses()
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	var options []resolver.PoolOption
+	if conf.Validator.ResolverStrategy != "" {
+		strategy, err := resolver.ParseStrategy(conf.Validator.ResolverStrategy)
+		if err != nil {
+			return nil, err
 		}
+
+		options = append(options, resolver.WithStrategy(strategy))
+	}
+
+	return resolver.NewPoolFromAddresses(addresses, options...)
+}
+
+// createMXAddressConfig returns which addresses of MX hosts are accepted, besides public addresses
+func createMXAddressConfig(conf config.Config) (validator.MXAddressConfig, error) {
+	allow, err := validator.ParseIPClasses(conf.Validator.MXAddress.Allow)
+	if err != nil {
+		return validator.MXAddressConfig{}, err
+	}
+
+	sinkholes, err := validator.ParseSinkholes(conf.Validator.MXAddress.Sinkholes)
+	if err != nil {
+		return validator.MXAddressConfig{}, err
 	}
 
-	dialer.Resolver.Dial = func(ctx context.Context, network, address string) (conn net.Conn, e error) {
-		d := net.Dialer{}
-		return d.DialContext(ctx, network, net.JoinHostPort(host, `53`))
+	return validator.MXAddressConfig{Allow: allow, Sinkholes: sinkholes}, nil
+}
+
+// createCachingResolver creates a resolver that caches answers for as long as their TTL allows. The configured resolvers
+// are queried directly, since only then the TTLs are known. Without them, the system's resolver is used with a fixed TTL.
+func createCachingResolver(conf config.Config, pool *resolver.Pool) *resolver.Cache {
+	var upstream resolver.Upstream
+	if pool != nil {
+		upstream = resolver.NewClient(pool)
+	} else {
+		upstream = resolver.FixedTTL(net.DefaultResolver, resolver.DefaultTTL)
+	}
+
+	options := []resolver.CacheOption{
+		resolver.WithNegativeTTL(conf.Validator.Cache.NegativeTTL.AsDuration()),
+	}
+
+	if n := conf.Validator.Cache.MaxEntries; n > 0 {
+		options = append(options, resolver.WithMaxEntries(n))
+	}
+
+	if ttl := conf.Validator.Cache.MaxTTL.AsDuration(); ttl > 0 {
+		options = append(options, resolver.WithMaxTTL(ttl))
 	}
+
+	return resolver.NewCache(upstream, options...)
 }
 
 func deferClose(toClose io.Closer, log logrus.FieldLogger) {
@@ -112,31 +169,61 @@ func deferClose(toClose io.Closer, log logrus.FieldLogger) {
 	}
 }
 
-func mapValidatorTypeToValidatorFn(vt config.ValidatorType, v validator.EmailValidator) validator.CheckFn {
+func mapValidatorTypeToPipeline(vt config.ValidatorType) validator.Pipeline {
 	switch vt {
 	case config.VTLookup:
-		return v.CheckWithLookup
+		return validator.LookupPipeline()
 	case config.VTStructure:
-		return v.CheckWithSyntax
+		return validator.SyntaxPipeline()
 	}
 
 	panic(fmt.Sprintf("Incorrect validator %q configured.", vt))
 }
 
-func createProxiedValidator(conf config.Config, logger logrus.FieldLogger, hitList *hitlist.HitList, myFinder *finder.Finder, pubSubSvc *gcp.PubSubSvc, persister persist.Persister) validator.CheckFn {
+func createProxiedValidator(conf config.Config, logger logrus.FieldLogger, hitList *hitlist.HitList, myFinder *finder.Finder, pubSubSvc *gcp.PubSubSvc, persister persist.Persister, disposable *validator.DisposableList, tlds *validator.TLDList, mxAddress validator.MXAddressConfig, pool *resolver.Pool, dnsCache *resolver.Cache) validator.CheckFn {
 	dialer := &net.Dialer{}
-	if conf.Validator.Resolver != "" {
-		setCustomResolver(dialer, conf.Validator.Resolver)
+	if pool != nil {
+		setCustomResolver(dialer, pool)
+	}
+
+	var options []validator.Option
+	if disposable != nil {
+		options = append(options, validator.WithDisposableList(disposable))
+	}
+
+	if tlds != nil {
+		options = append(options, validator.WithTLDList(tlds))
+	}
+
+	options = append(options, validator.WithMXAddressConfig(mxAddress))
+
+	// Pick the pipeline we want to use
+	pipeline := mapValidatorTypeToPipeline(conf.Validator.SuggestValidator)
+	if conf.Validator.Posture.Enable {
+		pipeline = pipeline.InsertAfter(validations.FMXDomainHasIP, validator.StepPosture)
+		if pool != nil {
+			// The resolver of the dialer can't look up TLSA records
+			options = append(options, validator.WithPostureConfig(validator.PostureConfig{Resolver: resolver.NewRecords(pool)}))
+		}
+	}
+
+	if len(conf.Validator.RoleAccounts) > 0 {
+		options = append(options, validator.WithRoleAccounts(conf.Validator.RoleAccounts))
+	}
+
+	if dnsCache != nil {
+		options = append(options, validator.WithResolver(dnsCache))
 	}
 
-	val := validator.NewEmailAddressValidator(dialer)
+	val := validator.NewEmailAddressValidator(dialer, options...)
 
-	// Pick the validator we want to use
-	checkValidator := mapValidatorTypeToValidatorFn(conf.Validator.SuggestValidator, val)
+	checkValidator := val.CheckWithPipeline(pipeline)
 
 	// Last in the chain, so that the duration only applies to the actual validation call
 	checkValidator = validatorContextTTLProxy(conf.Server.NetTTL.AsDuration(), checkValidator)
 
+	// Concurrent checks that missed the HitList share their lookups
+	checkValidator = validatorCoalesceProxy(logger, checkValidator)
 	checkValidator = validatorHitListProxy(hitList, logger, checkValidator)
 	checkValidator = validatorUpdateFinderProxy(myFinder, hitList, logger, checkValidator)
 
@@ -152,6 +239,149 @@ func createProxiedValidator(conf config.Config, logger logrus.FieldLogger, hitLi
 	return checkValidator
 }
 
+// createDisposableList loads the configured list of disposable domains and, when a refresh interval is defined,
+// keeps reloading it in the background until ctx is done. A nil list is returned when none is configured.
+func createDisposableList(ctx context.Context, conf config.Config, logger logrus.FieldLogger) (*validator.DisposableList, error) {
+	fileName := conf.Validator.Disposable.List
+	if fileName == "" {
+		logger.Info("Not checking for disposable domains, no list defined")
+		return nil, nil
+	}
+
+	logger = logger.WithField("disposable_list", fileName)
+
+	list := validator.NewDisposableList(nil, nil)
+	err := loadList(list, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, mxHosts := list.Len()
+	logger.WithFields(logrus.Fields{
+		"domains":  domains,
+		"mx_hosts": mxHosts,
+	}).Info("Loaded disposable list")
+
+	refreshList(ctx, list, fileName, conf.Validator.Disposable.Refresh.AsDuration(), logger)
+
+	return list, nil
+}
+
+// createTLDList returns the list of top-level domains, the embedded list is replaced by the configured one. A nil list
+// is returned when the check is disabled.
+func createTLDList(ctx context.Context, conf config.Config, logger logrus.FieldLogger) (*validator.TLDList, error) {
+	if !conf.Validator.TLD.Enable {
+		logger.Info("Not checking for unknown top-level domains")
+		return nil, nil
+	}
+
+	list := validator.NewTLDList()
+
+	fileName := conf.Validator.TLD.List
+	if fileName != "" {
+		logger = logger.WithField("tld_list", fileName)
+		if err := loadList(list, fileName); err != nil {
+			return nil, err
+		}
+
+		refreshList(ctx, list, fileName, conf.Validator.TLD.Refresh.AsDuration(), logger)
+	}
+
+	logger.WithField("tlds", list.Len()).Info("Loaded TLD list")
+	return list, nil
+}
+
+// createSuffixList returns the Public Suffix List, the embedded list is replaced by the configured one
+func createSuffixList(ctx context.Context, conf config.Config, logger logrus.FieldLogger) (*validator.SuffixList, error) {
+	list := validator.NewSuffixList()
+
+	fileName := conf.Validator.PublicSuffix.List
+	if fileName != "" {
+		logger = logger.WithField("public_suffix_list", fileName)
+		if err := loadList(list, fileName); err != nil {
+			return nil, err
+		}
+
+		refreshList(ctx, list, fileName, conf.Validator.PublicSuffix.Refresh.AsDuration(), logger)
+	}
+
+	logger.WithField("rules", list.Len()).Info("Loaded Public Suffix List")
+	return list, nil
+}
+
+type listLoader interface {
+	Load(r io.Reader) error
+}
+
+func loadList(list listLoader, fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+
+	defer deferClose(f, nil)
+
+	return list.Load(f)
+}
+
+// refreshList keeps reloading the list in the background until ctx is done, when interval is positive
+func refreshList(ctx context.Context, list listLoader, fileName string, interval time.Duration, logger logrus.FieldLogger) {
+	every(ctx, interval, func() {
+		if err := loadList(list, fileName); err != nil {
+			logger.WithError(err).Warn("Unable to reload the list, keeping the previous version")
+		}
+	})
+}
+
+// sweepHitList removes the expired domains and recipients from the HitList in the background, until ctx is done
+func sweepHitList(ctx context.Context, hitList *hitlist.HitList, interval time.Duration, logger logrus.FieldLogger) {
+	every(ctx, interval, func() {
+		domains, recipients := hitList.Sweep()
+		logger.WithFields(logrus.Fields{
+			"domains":    domains,
+			"recipients": recipients,
+		}).Debug("Swept the HitList")
+	})
+}
+
+// every calls fn each interval in the background, until ctx is done. A zero or negative interval disables it.
+func every(ctx context.Context, interval time.Duration, fn func()) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// logHitListStats logs the size of the HitList, with its domains rolled up to their registrable domain
+func logHitListStats(hitList *hitlist.HitList, suffixes *validator.SuffixList, logger logrus.FieldLogger) {
+	const top = 10
+
+	stats := hitList.GetRegistrableDomainStats(suffixes)
+
+	var popular []string
+	for i := 0; i < len(stats) && i < top; i++ {
+		popular = append(popular, fmt.Sprintf("%s (%d)", stats[i].Domain, stats[i].Recipients))
+	}
+
+	logger.WithFields(logrus.Fields{
+		"registrable_domains": len(stats),
+		"popular":             popular,
+	}).Info("HitList statistics")
+}
+
 func registerHealthHandler(mux *http.ServeMux, logger logrus.FieldLogger) {
 	healthHandler := NewHealthHandler(logger)
 
@@ -170,12 +400,21 @@ func pubSubNotificationHandler(hitList *hitlist.HitList, logger logrus.FieldLogg
 			return
 		}
 
+		v, s, err := notification.Data.Decode()
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"error": err,
+				"data":  notification.Data,
+			}).Warn("Ignoring notification, unable to decode the data")
+			return
+		}
+
 		vr := validator.Result{
-			Validations: notification.Data.Validations,
-			Steps:       notification.Data.Steps,
+			Validations: v,
+			Steps:       s,
 		}
 
-		err := hitList.Add(parts, vr)
+		err = hitList.Add(parts, vr)
 		if err != nil {
 			logger.WithFields(logrus.Fields{
 				"error": err,
@@ -184,13 +423,59 @@ func pubSubNotificationHandler(hitList *hitlist.HitList, logger logrus.FieldLogg
 			}).Error("Unable to add to hitlist")
 		}
 
-		if vr.Validations.IsValidationsForValidDomain() && !myFinder.Exact(parts.Domain) {
+		if vr.Validations.IsValidationsForValidDomain() && !myFinder.Exact(string(hitlist.NewDomain(parts.Domain))) {
 			myFinder.Refresh(hitList.GetValidAndUsageSortedDomains())
 		}
 	}
 }
 
-func createPersister(conf config.Config, logger logrus.FieldLogger, hitList *hitlist.HitList) (persist.Persister, error) {
+func mapRecipientStorage(rs config.RecipientStorage) hitlist.RecipientStorage {
+	switch rs {
+	case config.RSCompact:
+		return hitlist.StorageCompact
+	case config.RSCount:
+		return hitlist.StorageCount
+	}
+
+	return hitlist.StorageMap
+}
+
+// createHitListOptions configures the limits and TTLs of the HitList. With a DNS cache, domains don't remain valid
+// for longer than their MX records.
+func createHitListOptions(conf config.Config, dnsCache *resolver.Cache) []hitlist.Option {
+	options := []hitlist.Option{
+		hitlist.WithRecipientStorage(mapRecipientStorage(conf.HitList.RecipientStorage)),
+		hitlist.WithMaxDomains(conf.HitList.MaxDomains),
+		hitlist.WithMaxRecipients(conf.HitList.MaxRecipients),
+		hitlist.WithTTLs(hitlist.TTLs{
+			Valid:     conf.HitList.TTL.Valid.AsDuration(),
+			Invalid:   conf.HitList.TTL.Invalid.AsDuration(),
+			Recipient: conf.HitList.TTL.Recipient.AsDuration(),
+		}),
+	}
+
+	if conf.HitList.TTL.FollowDNS && dnsCache != nil {
+		options = append(options, hitlist.WithDNSTTL(dnsCache.MXTTL))
+	}
+
+	return options
+}
+
+// createKeys creates the keys recipients are pseudonymised with, see [hash] in the configuration
+func createKeys(conf config.Config) (*hitlist.Keys, error) {
+	id := conf.Hash.KeyID
+	if id == 0 {
+		id = 1
+	}
+
+	if id < 0 || id > int(hitlist.MaxKeyID) {
+		return nil, hitlist.ErrInvalidKeyID
+	}
+
+	return hitlist.NewKeys(hitlist.KeyID(id), []byte(conf.Hash.Key))
+}
+
+func createPersister(conf config.Config, logger logrus.FieldLogger, hitList *hitlist.HitList, keys *hitlist.Keys) (persist.Persister, error) {
 	driver := conf.Backend.Driver
 	var backend persist.Persister
 
@@ -215,10 +500,38 @@ func createPersister(conf config.Config, logger logrus.FieldLogger, hitList *hit
 		return nil, fmt.Errorf("unsupported backend driver %q", driver)
 	}
 
-	var added uint64
+	if conf.Hash.Rekey || conf.Hash.DropRetired {
+		legacyKey := conf.Hash.LegacyKey
+		if legacyKey == "" {
+			legacyKey = conf.Hash.Key
+		}
+
+		rekey, err := keys.Rekeyer([]byte(legacyKey), conf.Hash.DropRetired)
+		if err != nil {
+			return nil, err
+		}
+
+		rekeyed, err := backend.Rekey(context.Background(), rekey)
+		if err != nil {
+			logger.WithError(err).Warn("Unable to re-key the recipients")
+			return nil, err
+		}
+
+		logger.WithField("rekeyed", rekeyed).Info("Re-keyed the persisted recipients")
+	}
+
+	var added, retired uint64
 	logger.Debug("Backend defined, starting read and building memory structures")
 	err := backend.Range(context.Background(), func(d hitlist.Domain, r hitlist.Recipient, vr validator.Result) error {
-		err := hitList.AddInternalParts(d, r, vr)
+		// A recipient of a previous key can't be matched to the current key, it would be counted twice when it returns.
+		// Only the result of its domain is used.
+		if id, ok := keys.KeyID(r); ok && id != keys.ID() {
+			r = nil
+			retired++
+		}
+
+		// Older records might hold the Unicode form of a domain, or an alias of a provider
+		err := hitList.AddInternalParts(hitlist.NewDomain(string(d)), r, vr)
 		if err != nil {
 			logger.WithError(err).Warn("Unable to hydrate hitList")
 		}
@@ -231,7 +544,10 @@ func createPersister(conf config.Config, logger logrus.FieldLogger, hitList *hit
 		return nil, err
 	}
 
-	logger.WithField("added", added).Info("Hydrated hitList")
+	logger.WithFields(logrus.Fields{
+		"added":   added,
+		"retired": retired,
+	}).Info("Hydrated hitList")
 	return backend, nil
 }
 
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/Dynom/ERI/cmd/web/erihttp"
 	testLog "github.com/sirupsen/logrus/hooks/test"
@@ -35,3 +38,31 @@ func (b *brokenResponseWriter) Write(bytes []byte) (int, error) {
 
 	return len(bytes), b.writeErr
 }
+
+func Test_every(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int32
+	every(ctx, time.Millisecond, func() {
+		atomic.AddInt32(&calls, 1)
+	})
+
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	// A tick that was already underway might still finish
+	time.Sleep(10 * time.Millisecond)
+	stopped := atomic.LoadInt32(&calls)
+
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != stopped {
+		t.Errorf("Expected no more calls once the context is done, got %d more", got-stopped)
+	}
+
+	every(context.Background(), 0, func() {
+		t.Errorf("Expected a zero interval to disable it")
+	})
+}
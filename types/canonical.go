@@ -0,0 +1,112 @@
+package types
+
+import "strings"
+
+// ProviderRule describes how a mail provider delivers mail, so that the different forms of a single mailbox can be
+// recognised as one.
+type ProviderRule struct {
+	// Domains are aliases of each other, the first one is the canonical domain
+	Domains []string
+
+	// IgnoreDots is set when dots in the local part don't matter, e.g.: "john.doe" and "johndoe" are the same mailbox
+	IgnoreDots bool
+
+	// SubAddressSeparator starts a sub-address (e.g.: the "+" in "john+news") that is stripped, empty disables stripping
+	SubAddressSeparator string
+}
+
+// DefaultProviderRules are the rules of commonly used mail providers
+var DefaultProviderRules = []ProviderRule{
+	{Domains: []string{"gmail.com", "googlemail.com"}, IgnoreDots: true, SubAddressSeparator: "+"},
+	{Domains: []string{"outlook.com"}, SubAddressSeparator: "+"},
+	{Domains: []string{"hotmail.com"}, SubAddressSeparator: "+"},
+	{Domains: []string{"live.com"}, SubAddressSeparator: "+"},
+	{Domains: []string{"icloud.com"}, SubAddressSeparator: "+"},
+	{Domains: []string{"fastmail.com"}, SubAddressSeparator: "+"},
+	{Domains: []string{"protonmail.com", "protonmail.ch", "proton.me", "pm.me"}, SubAddressSeparator: "+"},
+}
+
+// DefaultCanonicalizer uses the DefaultProviderRules
+var DefaultCanonicalizer = NewCanonicalizer(DefaultProviderRules)
+
+// NewCanonicalizer creates a Canonicalizer for the rules. When rules share a domain, the last one wins.
+func NewCanonicalizer(rules []ProviderRule) *Canonicalizer {
+	c := Canonicalizer{
+		rules:     make(map[string]ProviderRule, len(rules)),
+		canonical: make(map[string]string, len(rules)),
+	}
+
+	for _, rule := range rules {
+		if len(rule.Domains) == 0 {
+			continue
+		}
+
+		canonical, _ := NormalizeDomain(rule.Domains[0])
+		for _, domain := range rule.Domains {
+			domain, _ = NormalizeDomain(domain)
+			c.rules[domain] = rule
+			c.canonical[domain] = canonical
+		}
+	}
+
+	return &c
+}
+
+// Canonicalizer reduces addresses to the form that identifies a mailbox. A nil Canonicalizer has no provider rules.
+type Canonicalizer struct {
+	rules     map[string]ProviderRule
+	canonical map[string]string
+}
+
+// Address returns the canonical form of an address: the local part is lower-cased, the domain is in its ASCII form
+// and the rules of the provider are applied. E.g.: "John.Doe+news@GoogleMail.com" becomes "johndoe@gmail.com".
+// Quoted local parts are only lower-cased.
+func (c *Canonicalizer) Address(p EmailParts) EmailParts {
+	ascii := p.CanonicalDomain()
+	local := strings.ToLower(p.Local)
+
+	var rule ProviderRule
+	if c != nil {
+		rule = c.rules[ascii]
+	}
+
+	if local != "" && !strings.HasPrefix(local, `"`) {
+		local = applyProviderRule(rule, local)
+	}
+
+	return NewEmailFromParts(local, c.Domain(ascii))
+}
+
+// Domain returns the canonical form of a domain, the ASCII form of the canonical domain of its alias group
+func (c *Canonicalizer) Domain(domain string) string {
+	ascii, _ := NormalizeDomain(domain)
+	if c == nil {
+		return ascii
+	}
+
+	if canonical, ok := c.canonical[ascii]; ok {
+		return canonical
+	}
+
+	return ascii
+}
+
+func applyProviderRule(rule ProviderRule, local string) string {
+	canonical := local
+	if rule.SubAddressSeparator != "" {
+		if i := strings.Index(canonical, rule.SubAddressSeparator); i > 0 {
+			canonical = canonical[:i]
+		}
+	}
+
+	if rule.IgnoreDots {
+		canonical = strings.ReplaceAll(canonical, ".", "")
+	}
+
+	// Nothing meaningful is left, e.g. for "..." on a provider that ignores dots
+	if canonical == "" {
+		return local
+	}
+
+	return canonical
+}
@@ -0,0 +1,63 @@
+package types
+
+import "testing"
+
+func TestCanonicalizer_Address(t *testing.T) {
+	tests := []struct {
+		name  string
+		c     *Canonicalizer
+		parts EmailParts
+		want  string
+	}{
+		{name: "dots and sub-address", c: DefaultCanonicalizer, parts: NewEmailFromParts("John.Doe+news", "gmail.com"), want: "johndoe@gmail.com"},
+		{name: "domain alias", c: DefaultCanonicalizer, parts: NewEmailFromParts("johndoe", "GoogleMail.com"), want: "johndoe@gmail.com"},
+		{name: "sub-address only", c: DefaultCanonicalizer, parts: NewEmailFromParts("john.doe+news", "outlook.com"), want: "john.doe@outlook.com"},
+		{name: "separator first", c: DefaultCanonicalizer, parts: NewEmailFromParts("+news", "outlook.com"), want: "+news@outlook.com"},
+		{name: "nothing left", c: DefaultCanonicalizer, parts: NewEmailFromParts("...", "gmail.com"), want: "...@gmail.com"},
+		{name: "quoted", c: DefaultCanonicalizer, parts: NewEmailFromParts(`"John.Doe+news"`, "gmail.com"), want: `"john.doe+news"@gmail.com`},
+		{name: "unknown provider", c: DefaultCanonicalizer, parts: NewEmailFromParts("John.Doe+news", "Example.org"), want: "john.doe+news@example.org"},
+		{name: "internationalised domain", c: DefaultCanonicalizer, parts: NewEmailFromParts("john", "Bücher.example"), want: "john@xn--bcher-kva.example"},
+		{name: "domain only", c: DefaultCanonicalizer, parts: NewEmailFromParts("", "googlemail.com"), want: "@gmail.com"},
+		{name: "nil", parts: NewEmailFromParts("John.Doe+news", "googlemail.com"), want: "john.doe+news@googlemail.com"},
+		{
+			name:  "custom rule",
+			c:     NewCanonicalizer([]ProviderRule{{Domains: []string{"example.org", "example.com"}, SubAddressSeparator: "-"}}),
+			parts: NewEmailFromParts("john-news", "example.com"),
+			want:  "john@example.org",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.c.Address(tt.parts)
+			if got.Address != tt.want {
+				t.Errorf("Address() = %q, want %q", got.Address, tt.want)
+			}
+
+			if got.CanonicalDomain() != got.Domain {
+				t.Errorf("Expected the domain to be in its canonical form, got %q", got.Domain)
+			}
+		})
+	}
+}
+
+func TestCanonicalizer_Domain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{domain: "gmail.com", want: "gmail.com"},
+		{domain: "GoogleMail.com", want: "gmail.com"},
+		{domain: "pm.me", want: "protonmail.com"},
+		{domain: "example.org", want: "example.org"},
+		{domain: "bücher.example", want: "xn--bcher-kva.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := DefaultCanonicalizer.Domain(tt.domain); got != tt.want {
+				t.Errorf("Domain(%q) = %q, want %q", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
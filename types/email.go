@@ -3,27 +3,39 @@ package types
 import (
 	"errors"
 	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
 )
 
 var ErrInvalidEmailAddress = errors.New("invalid e-mail address, address is missing @")
 
 // NewEmailFromParts reconstructs EmailParts from two parts
 func NewEmailFromParts(local, domain string) EmailParts {
+	local = norm.NFC.String(local)
+	domain = norm.NFC.String(domain)
+	ascii, unicode := NormalizeDomain(domain)
+
 	return EmailParts{
-		Address: local + "@" + domain,
-		Local:   local,
-		Domain:  domain,
+		Address:       local + "@" + domain,
+		Local:         local,
+		Domain:        domain,
+		DomainASCII:   ascii,
+		DomainUnicode: unicode,
 	}
 }
 
 // NewEmailParts takes an e-mail address and returns it lower-cased and in parts. It performs only the most minimal form
 // of syntax validation. An error is returned when the address doesn't contain an @, or when the input size is abnormal.
+// The address is normalised to Unicode NFC, the script of the input is kept.
 func NewEmailParts(emailAddress string) (EmailParts, error) {
-	p, err := splitLocalAndDomain(emailAddress)
+	p, err := splitLocalAndDomain(norm.NFC.String(emailAddress))
 	if err != nil {
 		return EmailParts{}, err
 	}
 
+	p.DomainASCII, p.DomainUnicode = NormalizeDomain(p.Domain)
+
 	return p, nil
 }
 
@@ -31,6 +43,39 @@ type EmailParts struct {
 	Address string
 	Local   string
 	Domain  string
+
+	// DomainASCII is the lower-cased UTS-46 ASCII form of Domain (e.g.: "xn--zckzah.xn--zckzah"), DomainUnicode is the
+	// Unicode form (e.g.: "テスト.テスト"). Both are equal to the lower-cased Domain when it can't be converted.
+	DomainASCII   string
+	DomainUnicode string
+}
+
+// CanonicalDomain returns the form of the domain to use for DNS lookups and as key, see NormalizeDomain
+func (p EmailParts) CanonicalDomain() string {
+	if p.DomainASCII != "" {
+		return p.DomainASCII
+	}
+
+	ascii, _ := NormalizeDomain(p.Domain)
+	return ascii
+}
+
+// NormalizeDomain returns the lower-cased UTS-46 ASCII and Unicode forms of a domain. When the domain isn't a valid
+// IDNA domain (e.g. when it contains an underscore), both are the lower-cased input.
+func NormalizeDomain(domain string) (ascii, unicode string) {
+	domain = strings.ToLower(norm.NFC.String(domain))
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || ascii == "" {
+		return domain, domain
+	}
+
+	unicode, err = idna.Lookup.ToUnicode(ascii)
+	if err != nil {
+		return ascii, domain
+	}
+
+	return ascii, unicode
 }
 
 func splitLocalAndDomain(input string) (EmailParts, error) {
@@ -22,3 +22,88 @@ func Test_splitLocalAndDomain(t *testing.T) {
 		}
 	}
 }
+
+func TestNewEmailParts(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             string
+		wantLocal         string
+		wantDomain        string
+		wantDomainASCII   string
+		wantDomainUnicode string
+	}{
+		{
+			name:              "ASCII",
+			input:             "john@Example.org",
+			wantLocal:         "john",
+			wantDomain:        "Example.org",
+			wantDomainASCII:   "example.org",
+			wantDomainUnicode: "example.org",
+		},
+		{
+			name:              "Unicode domain",
+			input:             "john@テスト.テスト",
+			wantLocal:         "john",
+			wantDomain:        "テスト.テスト",
+			wantDomainASCII:   "xn--zckzah.xn--zckzah",
+			wantDomainUnicode: "テスト.テスト",
+		},
+		{
+			name:              "Punycode domain",
+			input:             "john@XN--zckzah.xn--zckzah",
+			wantLocal:         "john",
+			wantDomain:        "XN--zckzah.xn--zckzah",
+			wantDomainASCII:   "xn--zckzah.xn--zckzah",
+			wantDomainUnicode: "テスト.テスト",
+		},
+		{
+			name:              "NFD input is composed",
+			input:             "joe\u0301@bu\u0308cher.example",
+			wantLocal:         "jo\u00e9",
+			wantDomain:        "b\u00fccher.example",
+			wantDomainASCII:   "xn--bcher-kva.example",
+			wantDomainUnicode: "b\u00fccher.example",
+		},
+		{
+			name:              "not a valid IDNA domain",
+			input:             "john@Exa_mple.org",
+			wantLocal:         "john",
+			wantDomain:        "Exa_mple.org",
+			wantDomainASCII:   "exa_mple.org",
+			wantDomainUnicode: "exa_mple.org",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewEmailParts(tt.input)
+			if err != nil {
+				t.Fatalf("NewEmailParts() unexpected error %s", err)
+			}
+
+			if got.Local != tt.wantLocal || got.Domain != tt.wantDomain {
+				t.Errorf("Expected %q @ %q, got %q @ %q", tt.wantLocal, tt.wantDomain, got.Local, got.Domain)
+			}
+
+			if got.DomainASCII != tt.wantDomainASCII || got.DomainUnicode != tt.wantDomainUnicode {
+				t.Errorf("Expected the domain forms %q and %q, got %q and %q", tt.wantDomainASCII, tt.wantDomainUnicode, got.DomainASCII, got.DomainUnicode)
+			}
+
+			if got.CanonicalDomain() != tt.wantDomainASCII {
+				t.Errorf("Expected the canonical domain %q, got %q", tt.wantDomainASCII, got.CanonicalDomain())
+			}
+
+			if fromParts := NewEmailFromParts(got.Local, got.Domain); fromParts != got {
+				t.Errorf("Expected NewEmailFromParts to be equal to NewEmailParts, got %+v and %+v", fromParts, got)
+			}
+		})
+	}
+}
+
+func TestEmailParts_CanonicalDomain(t *testing.T) {
+	// EmailParts created as literal, lack the pre-computed forms
+	p := EmailParts{Address: "テスト.テスト", Domain: "テスト.テスト"}
+	if got := p.CanonicalDomain(); got != "xn--zckzah.xn--zckzah" {
+		t.Errorf("CanonicalDomain() = %q, want %q", got, "xn--zckzah.xn--zckzah")
+	}
+}
@@ -0,0 +1,388 @@
+package validator
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+const (
+	DefaultBatchConcurrency = 10
+
+	// batchWindow times the concurrency is the number of addresses that can be read ahead of the oldest address that
+	// hasn't been returned yet
+	batchWindow = 4
+)
+
+// domainFlags are the built-in validations that apply to the domain of an address, rather than to the address itself
+const domainFlags = validations.FMXLookup | validations.FNullMX | validations.FDisposable | validations.FMXDomainHasIP | validations.FHostConnect | validations.FAcceptAll | validations.FPosture
+
+// BatchConfig defines how CheckBatch and CheckStream check many addresses
+type BatchConfig struct {
+	// Pipeline defines the depth of the checks, defaults to LookupPipeline
+	Pipeline Pipeline
+
+	// Concurrency bounds the number of checks that run at once, defaults to DefaultBatchConcurrency
+	Concurrency int
+
+	// Timeout limits a single check, including the checks of a domain. When zero, only the context limits the checks
+	Timeout time.Duration
+}
+
+func (c BatchConfig) withDefaults() BatchConfig {
+	if len(c.Pipeline.steps) == 0 {
+		c.Pipeline = LookupPipeline()
+	}
+
+	if c.Concurrency < 1 {
+		c.Concurrency = DefaultBatchConcurrency
+	}
+
+	return c
+}
+
+// BatchResult is the result of the address at Index of the input
+type BatchResult struct {
+	Index  int
+	Parts  types.EmailParts
+	Result Result
+}
+
+// CheckBatch checks all addresses and returns the results in the order of parts. See CheckStream for how addresses of
+// the same domain are checked. When ctx is done before an address could be checked, its result describes the error of
+// ctx.
+func (v *EmailValidator) CheckBatch(ctx context.Context, parts []types.EmailParts, conf BatchConfig, options ...ArtifactFn) []Result {
+	in := make(chan types.EmailParts)
+	go func() {
+		defer close(in)
+		for _, p := range parts {
+			select {
+			case in <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make([]Result, len(parts))
+	checked := make([]bool, len(parts))
+	for r := range v.CheckStream(ctx, in, conf, options...) {
+		results[r.Index] = r.Result
+		checked[r.Index] = true
+	}
+
+	for i := range results {
+		if !checked[i] {
+			results[i].Diagnostics = newDiagnostics(Artifact{}, ctx.Err())
+		}
+	}
+
+	return results
+}
+
+// CheckStream checks the addresses read from parts, with at most conf.Concurrency checks at once. The results are sent
+// in the order the addresses were read, the returned channel is closed once parts is closed and all addresses are
+// checked. The channel must be drained, reading from parts stops when results aren't picked up.
+//
+// Addresses are grouped by domain. The domain steps of the pipeline (e.g. the MX lookup) run once per domain and their
+// outcome is shared with the addresses of that domain that are in progress, the other steps run for every address.
+// When RCPT probes are part of the pipeline, every address connects on its own and whether the domain accepts all
+// recipients is shared with the addresses that are checked afterwards. A domain check that timed out isn't shared.
+// When ctx is done, no more addresses are read from parts.
+func (v *EmailValidator) CheckStream(ctx context.Context, parts <-chan types.EmailParts, conf BatchConfig, options ...ArtifactFn) <-chan BatchResult {
+	conf = conf.withDefaults()
+	b := &batch{
+		v:              v,
+		conf:           conf,
+		options:        options,
+		sequence:       conf.Pipeline.sequence(),
+		domainSequence: domainPipeline(conf.Pipeline).sequence(),
+		workers:        make(chan struct{}, conf.Concurrency),
+		domains:        make(map[string]*batchDomain),
+	}
+
+	window := make(chan struct{}, conf.Concurrency*batchWindow)
+	results := make(chan BatchResult)
+	out := make(chan BatchResult)
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for i := 0; ; i++ {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			var p types.EmailParts
+			var ok bool
+			select {
+			case p, ok = <-parts:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			d := b.domain(ctx, p)
+
+			wg.Add(1)
+			go func(i int, p types.EmailParts) {
+				defer wg.Done()
+				results <- BatchResult{Index: i, Parts: p, Result: b.check(ctx, p, d)}
+			}(i, p)
+		}
+	}()
+
+	go func() {
+		defer close(out)
+
+		pending := make(map[int]BatchResult)
+		var next int
+		for r := range results {
+			pending[r.Index] = r
+			for r, ok := pending[next]; ok; r, ok = pending[next] {
+				delete(pending, next)
+				out <- r
+				<-window
+				next++
+			}
+		}
+	}()
+
+	return out
+}
+
+// domainPipeline returns the steps of p that check the domain, it's empty when there are none. Connections are only
+// shared when no recipients are probed on them.
+func domainPipeline(p Pipeline) Pipeline {
+	flags := domainFlags
+	if p.indexOf(validations.FValidRCPT) != -1 {
+		flags &^= validations.FHostConnect
+	}
+
+	var steps []Step
+	for _, s := range p.steps {
+		if s.builtin && s.Flag&flags != 0 {
+			steps = append(steps, s)
+		}
+	}
+
+	if len(steps) == 0 {
+		return Pipeline{}
+	}
+
+	return NewPipeline(append([]Step{StepSyntax}, steps...)...)
+}
+
+type batch struct {
+	v              *EmailValidator
+	conf           BatchConfig
+	options        []ArtifactFn
+	sequence       []stateFn
+	domainSequence []stateFn
+
+	// workers bounds the number of checks running at once
+	workers chan struct{}
+
+	lock    sync.Mutex
+	domains map[string]*batchDomain
+}
+
+// batchDomain holds the outcome of the domain check, ready is closed once it's known. A nil seed means every address
+// runs all steps itself.
+type batchDomain struct {
+	key     string
+	ready   chan struct{}
+	seed    *domainSeed
+	pending int
+}
+
+// domainSeed is the part of a domain check that's shared with the addresses of the domain. Whether the domain accepts
+// all recipients is only known after the first probe of one of its addresses, it's added to the seed for the addresses
+// checked afterwards.
+type domainSeed struct {
+	steps            validations.Steps
+	validations      validations.Validations
+	mx               []string
+	mxFound          []string
+	mxAddresses      map[string][]net.IP
+	connectedMX      string
+	connectedAddress string
+	timings          Timings
+	posture          *Posture
+	diagnostics      Diagnostics
+}
+
+func (s *domainSeed) apply(a *Artifact) {
+	a.Steps.SetFlag(validations.Flag(s.steps))
+	a.Validations.SetFlag(validations.Flag(s.validations))
+	a.mx = append([]string(nil), s.mx...)
+	a.mxFound = s.mxFound
+	a.mxAddresses = s.mxAddresses
+	a.Timings = append(a.Timings, s.timings...)
+	a.posture = s.posture
+
+	if s.steps.HasFlag(validations.FHostConnect) {
+		a.connectedMX = s.connectedMX
+		a.connectedAddress = s.connectedAddress
+	}
+}
+
+// domain returns the domain of p, starting its check when p is the first address of the domain in progress
+func (b *batch) domain(ctx context.Context, p types.EmailParts) *batchDomain {
+	key := p.CanonicalDomain()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	d, exists := b.domains[key]
+	if !exists {
+		d = &batchDomain{key: key, ready: make(chan struct{})}
+		b.domains[key] = d
+
+		if len(b.domainSequence) == 0 {
+			close(d.ready)
+		} else {
+			go b.checkDomain(ctx, p, d)
+		}
+	}
+
+	d.pending++
+	return d
+}
+
+// done forgets the domain, once none of its addresses are in progress
+func (b *batch) done(d *batchDomain) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	d.pending--
+	if d.pending == 0 && b.domains[d.key] == d {
+		delete(b.domains, d.key)
+	}
+}
+
+func (b *batch) checkDomain(ctx context.Context, p types.EmailParts, d *batchDomain) {
+	defer close(d.ready)
+
+	b.workers <- struct{}{}
+	defer func() { <-b.workers }()
+
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	domain := p
+	domain.Local = ""
+	domain.Address = p.Domain
+
+	artifact, result := b.v.run(ctx, b.domainSequence, domain, b.options)
+	if artifact.conn != nil {
+		_ = artifact.conn.Close()
+	}
+
+	// The addresses of the domain might have more luck on their own
+	if result.Diagnostics.Reason == ReasonTimeout || ctx.Err() != nil {
+		return
+	}
+
+	d.seed = &domainSeed{
+		steps:            artifact.Steps & validations.Steps(domainFlags),
+		validations:      artifact.Validations & validations.Validations(domainFlags),
+		mx:               artifact.mx,
+		mxFound:          artifact.mxFound,
+		mxAddresses:      artifact.mxAddresses,
+		connectedMX:      artifact.connectedMX,
+		connectedAddress: artifact.connectedAddress,
+		timings:          artifact.Timings,
+		posture:          artifact.posture,
+		diagnostics:      result.Diagnostics,
+	}
+}
+
+func (b *batch) check(ctx context.Context, p types.EmailParts, d *batchDomain) Result {
+	defer b.done(d)
+
+	var ready bool
+	select {
+	case <-d.ready:
+		ready = true
+	case <-ctx.Done():
+	}
+
+	b.workers <- struct{}{}
+	defer func() { <-b.workers }()
+
+	// Taken once it's our turn, it might have learned of the catch-all by then
+	var seed *domainSeed
+	if ready {
+		seed = b.seed(d)
+	}
+
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	options := b.options
+	if seed != nil {
+		options = append(append([]ArtifactFn(nil), b.options...), seed.apply)
+	}
+
+	_, result := b.v.run(ctx, b.sequence, p, options)
+
+	if seed != nil && result.Steps.HasFlag(validations.FAcceptAll) {
+		b.acceptAll(d, result)
+	}
+
+	// A failure of the domain check is repeated from the seed, its details are only known to the domain check
+	if seed != nil && seed.diagnostics.FailedStep != "" && result.Diagnostics.FailedStep == seed.diagnostics.FailedStep {
+		result.Diagnostics.Reason = seed.diagnostics.Reason
+		result.Diagnostics.Detail = seed.diagnostics.Detail
+	}
+
+	return result
+}
+
+// seed returns a copy of the seed of the domain, nil when there is none
+func (b *batch) seed(d *batchDomain) *domainSeed {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if d.seed == nil {
+		return nil
+	}
+
+	seed := *d.seed
+	return &seed
+}
+
+// acceptAll adds the outcome of the catch-all probe of result to the seed of the domain, so that the other addresses
+// of the domain aren't probed for it again
+func (b *batch) acceptAll(d *batchDomain, result Result) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if d.seed == nil {
+		return
+	}
+
+	d.seed.steps |= validations.Steps(validations.FAcceptAll)
+	d.seed.validations |= result.Validations & validations.Validations(validations.FAcceptAll)
+}
+
+func (b *batch) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.conf.Timeout > 0 {
+		return context.WithTimeout(ctx, b.conf.Timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,320 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+// countingResolver answers with a single MX host for every domain, except for the failing ones. It counts the lookups
+// per domain and takes delay to answer, long enough for a batch to read all of its input.
+type countingResolver struct {
+	failing map[string]error
+	delay   time.Duration
+	hang    bool   // Block until the context is done
+	ip      net.IP // The address of every MX host, defaults to 192.0.2.1
+
+	lock    sync.Mutex
+	lookups map[string]int
+}
+
+func (r *countingResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	r.lock.Lock()
+	if r.lookups == nil {
+		r.lookups = make(map[string]int)
+	}
+	r.lookups[domain]++
+	r.lock.Unlock()
+
+	if r.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	time.Sleep(r.delay)
+	if err, ok := r.failing[domain]; ok {
+		return nil, err
+	}
+
+	return []*net.MX{{Host: "mx." + domain + "."}}, nil
+}
+
+func (r *countingResolver) LookupIPAddr(_ context.Context, _ string) ([]net.IPAddr, error) {
+	if r.ip != nil {
+		return []net.IPAddr{{IP: r.ip}}, nil
+	}
+
+	return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}}, nil
+}
+
+func (r *countingResolver) Lookups() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var total int
+	for _, n := range r.lookups {
+		total += n
+	}
+
+	return total
+}
+
+func newBatchParts(t *testing.T, addresses []string) []types.EmailParts {
+	t.Helper()
+
+	parts := make([]types.EmailParts, len(addresses))
+	for i, address := range addresses {
+		p, err := types.NewEmailParts(address)
+		if err != nil {
+			t.Fatalf("Unable to parse %q %s", address, err)
+		}
+
+		parts[i] = p
+	}
+
+	return parts
+}
+
+func TestEmailValidator_CheckBatch(t *testing.T) {
+	mxErr := &net.DNSError{Err: "server misbehaving", Name: "broken.example"}
+
+	tests := []struct {
+		name        string
+		addresses   []string
+		wantReasons []Reason
+		wantLookups int
+	}{
+		{
+			name:        "domains are looked up once",
+			addresses:   []string{"john@example.org", "jane@example.com", "jake@Example.org", "jill@example.org"},
+			wantReasons: []Reason{ReasonNone, ReasonNone, ReasonNone, ReasonNone},
+			wantLookups: 2,
+		},
+		{
+			name:        "results are in input order",
+			addresses:   []string{"john@example.org", "john..doe@example.org", "jane@broken.example", "jake@example.org"},
+			wantReasons: []Reason{ReasonNone, ReasonSyntax, ReasonMXLookup, ReasonNone},
+			wantLookups: 2,
+		},
+		{
+			name:        "failures of the domain are shared",
+			addresses:   []string{"john@broken.example", "jane@broken.example"},
+			wantReasons: []Reason{ReasonMXLookup, ReasonMXLookup},
+			wantLookups: 1,
+		},
+		{
+			name:        "invalid domains aren't looked up",
+			addresses:   []string{"john@exa_mple.org", "jane@exa_mple.org"},
+			wantReasons: []Reason{ReasonSyntax, ReasonSyntax},
+			wantLookups: 0,
+		},
+		{
+			name:      "nothing to check",
+			addresses: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &countingResolver{failing: map[string]error{"broken.example": mxErr}, delay: 20 * time.Millisecond}
+			v := NewEmailAddressValidator(nil, WithResolver(r), withDocumentationMX)
+
+			results := v.CheckBatch(context.Background(), newBatchParts(t, tt.addresses), BatchConfig{Concurrency: 2})
+			if len(results) != len(tt.addresses) {
+				t.Fatalf("Expected %d results, got %d", len(tt.addresses), len(results))
+			}
+
+			for i, result := range results {
+				if result.Diagnostics.Reason != tt.wantReasons[i] {
+					t.Errorf("Expected %q to fail with %q, got %q (%s)", tt.addresses[i], tt.wantReasons[i], result.Diagnostics.Reason, result.Diagnostics.Detail)
+				}
+
+				if result.Diagnostics.Reason == ReasonNone && !result.Validations.HasFlag(validations.FMXDomainHasIP) {
+					t.Errorf("Expected %q to pass the domain checks, got %s", tt.addresses[i], result.Validations)
+				}
+
+				if result.Diagnostics.Reason == ReasonMXLookup && !strings.Contains(result.Diagnostics.Detail, mxErr.Error()) {
+					t.Errorf("Expected the details of the lookup of %q, got %q", tt.addresses[i], result.Diagnostics.Detail)
+				}
+			}
+
+			if lookups := r.Lookups(); lookups != tt.wantLookups {
+				t.Errorf("Expected %d lookup(s), got %d", tt.wantLookups, lookups)
+			}
+		})
+	}
+}
+
+func TestEmailValidator_CheckBatch_timeout(t *testing.T) {
+	r := &countingResolver{hang: true}
+	v := NewEmailAddressValidator(nil, WithResolver(r), withDocumentationMX)
+
+	parts := newBatchParts(t, []string{"john@example.org", "jane@example.org"})
+	results := v.CheckBatch(context.Background(), parts, BatchConfig{Timeout: 20 * time.Millisecond})
+
+	for i, result := range results {
+		if result.Diagnostics.Reason != ReasonTimeout {
+			t.Errorf("Expected %q to time out, got %q", parts[i].Address, result.Diagnostics.Reason)
+		}
+	}
+
+	// A domain check that timed out isn't shared, each address gets a chance of its own
+	if lookups := r.Lookups(); lookups != 3 {
+		t.Errorf("Expected 3 lookups, got %d", lookups)
+	}
+}
+
+func TestEmailValidator_CheckBatch_cancelled(t *testing.T) {
+	v := NewEmailAddressValidator(nil, WithResolver(&countingResolver{}), withDocumentationMX)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := v.CheckBatch(ctx, newBatchParts(t, []string{"john@example.org"}), BatchConfig{})
+	if len(results) != 1 || results[0].Validations.IsValid() || results[0].Diagnostics.Detail == "" {
+		t.Errorf("Expected the address to be reported as not checked, got %+v", results)
+	}
+}
+
+func TestEmailValidator_CheckStream(t *testing.T) {
+	const concurrency = 3
+
+	var running, maxRunning int32
+	slow := NewStep("slow", validations.FUserDefined, func(a *Artifact) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for m := atomic.LoadInt32(&maxRunning); n > m; m = atomic.LoadInt32(&maxRunning) {
+			if atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+
+		// Later addresses finish first
+		time.Sleep(time.Duration(50-len(a.Email().Local)) * time.Millisecond)
+		return nil
+	})
+
+	v := NewEmailAddressValidator(nil, WithResolver(&countingResolver{}), withDocumentationMX)
+	conf := BatchConfig{
+		Pipeline:    LookupPipeline().Append(slow),
+		Concurrency: concurrency,
+	}
+
+	in := make(chan types.EmailParts)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 20; i++ {
+			in <- types.NewEmailFromParts(strings.Repeat("j", i), "example.org")
+		}
+	}()
+
+	var next int
+	for r := range v.CheckStream(context.Background(), in, conf) {
+		if r.Index != next || len(r.Parts.Local) != next+1 {
+			t.Errorf("Expected result %d, got %d (%q)", next, r.Index, r.Parts.Address)
+		}
+
+		next++
+	}
+
+	if next != 20 {
+		t.Errorf("Expected 20 results, got %d", next)
+	}
+
+	if maxRunning > concurrency {
+		t.Errorf("Expected at most %d checks at once, got %d", concurrency, maxRunning)
+	}
+}
+
+func TestEmailValidator_CheckBatch_acceptAll(t *testing.T) {
+	server := &stubSMTPServer{fallback: "250 OK"}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen %s", err)
+	}
+
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_ = server.serve(conn)
+			}()
+		}
+	}()
+
+	v := NewEmailAddressValidator(nil,
+		WithResolver(&countingResolver{ip: net.ParseIP("127.0.0.1"), delay: 20 * time.Millisecond}),
+		WithMXAddressConfig(MXAddressConfig{Allow: IPLoopback}),
+		WithConnectConfig(ConnectConfig{Ports: []uint16{uint16(l.Addr().(*net.TCPAddr).Port)}}),
+	)
+
+	addresses := []string{"john@example.org", "jane@example.org", "jake@example.org"}
+	results := v.CheckBatch(context.Background(), newBatchParts(t, addresses), BatchConfig{Pipeline: RCPTPipeline(), Concurrency: 1, Timeout: 5 * time.Second})
+
+	for i, result := range results {
+		if !result.Validations.HasFlag(validations.FAcceptAll) || result.Validations.HasFlag(validations.FValidRCPT) {
+			t.Errorf("Expected %q to be of a catch-all domain, got %s (%s)", addresses[i], result.Validations, result.Diagnostics.Detail)
+		}
+	}
+
+	// The address that was checked first and a random one, the other addresses aren't probed
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	if len(server.tried) != 2 {
+		t.Errorf("Expected only a single address to be probed, got %v", server.tried)
+	}
+}
+
+func Test_domainPipeline(t *testing.T) {
+	custom := NewStep("custom", validations.FUserDefined, func(a *Artifact) error {
+		return errors.New("b0rk")
+	})
+
+	tests := []struct {
+		name     string
+		pipeline Pipeline
+		want     []validations.Flag
+	}{
+		{name: "syntax", pipeline: SyntaxPipeline(), want: []validations.Flag{validations.FSyntax, validations.FDisposable}},
+		{name: "lookup", pipeline: LookupPipeline(), want: []validations.Flag{validations.FSyntax, validations.FMXLookup, validations.FDisposable, validations.FMXDomainHasIP}},
+		{name: "connect", pipeline: ConnectPipeline(), want: []validations.Flag{validations.FSyntax, validations.FMXLookup, validations.FDisposable, validations.FMXDomainHasIP, validations.FHostConnect}},
+		{name: "rcpt connects per address", pipeline: RCPTPipeline(), want: []validations.Flag{validations.FSyntax, validations.FMXLookup, validations.FDisposable, validations.FMXDomainHasIP}},
+		{name: "user-defined steps", pipeline: NewPipeline(StepSyntax, StepRoleAccount, custom)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []validations.Flag
+			for _, s := range domainPipeline(tt.pipeline).Steps() {
+				got = append(got, s.Flag)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected the steps %v, got %v", tt.want, got)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected the steps %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/mail"
-	"net/smtp"
 	"time"
 
 	"github.com/Dynom/ERI/validator/validations"
@@ -15,6 +16,11 @@ type ValidationError struct {
 	error
 }
 
+// Unwrap returns the public error, allowing errors.Is() to match on e.g. ErrNullMX
+func (e ValidationError) Unwrap() error {
+	return e.error
+}
+
 // checkEmailAddressSyntax checks for "common sense" e-mail address syntax. It doesn't try to be fully compliant.
 func checkEmailAddressSyntax(a *Artifact) error {
 	a.Steps.SetFlag(validations.FSyntax)
@@ -22,19 +28,24 @@ func checkEmailAddressSyntax(a *Artifact) error {
 	var err error
 
 	start := time.Now()
-	defer a.Timings.Add("checkEmailAddressSyntax", time.Since(start))
+	defer func() {
+		a.Timings.Add("checkEmailAddressSyntax", time.Since(start))
+	}()
 
-	_, err = mail.ParseAddress(a.email.Address)
-	if err != nil {
-		return ValidationError{
-			Validator: "checkEmailAddressSyntax",
-			Internal:  err,
-			error:     ErrEmailAddressSyntax,
+	// Address literals are validated on their own, net/mail doesn't support them on all Go versions
+	if !a.isAddressLiteral() {
+		_, err = mail.ParseAddress(a.email.Address)
+		if err != nil {
+			return ValidationError{
+				Validator: "checkEmailAddressSyntax",
+				Internal:  err,
+				error:     ErrEmailAddressSyntax,
+			}
 		}
 	}
 
 	// Perform additional checks to weed out commonly occurring errors (see tests for details)
-	if !looksLikeValidLocalPart(a.email.Local) {
+	if !a.looksLikeValidLocalPart() {
 		return ValidationError{
 			Validator: "checkEmailAddressSyntax",
 			Internal:  fmt.Errorf("local part '%s' has invalid syntax", a.email.Local),
@@ -42,7 +53,7 @@ func checkEmailAddressSyntax(a *Artifact) error {
 		}
 	}
 
-	if !looksLikeValidDomain(a.email.Domain) {
+	if !a.looksLikeValidDomain() {
 		return ValidationError{
 			Validator: "checkEmailAddressSyntax",
 			Internal:  fmt.Errorf("domain part '%s' has invalid syntax", a.email.Domain),
@@ -50,6 +61,10 @@ func checkEmailAddressSyntax(a *Artifact) error {
 		}
 	}
 
+	if err := a.checkTLD("checkEmailAddressSyntax"); err != nil {
+		return err
+	}
+
 	a.Validations = a.Validations.SetFlag(validations.FSyntax)
 	return nil
 }
@@ -59,9 +74,11 @@ func checkDomainSyntax(a *Artifact) error {
 	a.Steps.SetFlag(validations.FSyntax)
 
 	start := time.Now()
-	defer a.Timings.Add("checkDomainSyntax", time.Since(start))
+	defer func() {
+		a.Timings.Add("checkDomainSyntax", time.Since(start))
+	}()
 
-	if !looksLikeValidDomain(a.email.Domain) {
+	if !a.looksLikeValidDomain() {
 		return ValidationError{
 			Validator: "checkDomainSyntax",
 			Internal:  fmt.Errorf("domain part '%s' has invalid syntax", a.email.Domain),
@@ -69,13 +86,70 @@ func checkDomainSyntax(a *Artifact) error {
 		}
 	}
 
+	if err := a.checkTLD("checkDomainSyntax"); err != nil {
+		return err
+	}
+
 	a.Validations.SetFlag(validations.FSyntax)
 	return nil
 }
 
+// checkTLD rejects domains with a top-level domain that isn't on the TLD list, address literals have none
+func (a *Artifact) checkTLD(step string) error {
+	if a.tlds == nil || a.isAddressLiteral() || a.tlds.HasTLD(a.email.CanonicalDomain()) {
+		return nil
+	}
+
+	return ValidationError{
+		Validator: step,
+		Internal:  fmt.Errorf("top-level domain '%s' is unknown", topLevelDomain(a.email.CanonicalDomain())),
+		error:     ErrUnknownTLD,
+	}
+}
+
+// looksLikeValidLocalPart checks the local part, quoted local parts are only accepted with SyntaxRFC5322
+func (a *Artifact) looksLikeValidLocalPart() bool {
+	if a.syntaxMode == SyntaxRFC5322 && isQuotedLocalPart(a.email.Local) {
+		return looksLikeValidQuotedLocalPart(a.email.Local)
+	}
+
+	return looksLikeValidLocalPart(a.email.Local)
+}
+
+// looksLikeValidDomain checks the domain, address literals are only accepted with SyntaxRFC5322
+func (a *Artifact) looksLikeValidDomain() bool {
+	if a.isAddressLiteral() {
+		_, ok := parseAddressLiteral(a.email.Domain)
+		return ok
+	}
+
+	return looksLikeValidDomain(a.email.Domain)
+}
+
+// isAddressLiteral returns true when the domain is in the address literal form and SyntaxRFC5322 is used
+func (a *Artifact) isAddressLiteral() bool {
+	return a.syntaxMode == SyntaxRFC5322 && isAddressLiteral(a.email.Domain)
+}
+
+// addressLiteral returns the IP of an address literal domain, see isAddressLiteral
+func (a *Artifact) addressLiteral() (net.IP, bool) {
+	if !a.isAddressLiteral() {
+		return nil, false
+	}
+
+	return parseAddressLiteral(a.email.Domain)
+}
+
 // checkIfDomainHasMX performs a DNS lookup and fetches MX records.
 func checkIfDomainHasMX(a *Artifact) error {
 	if a.Steps.HasFlag(validations.FMXLookup) {
+		if a.Validations.HasFlag(validations.FNullMX) {
+			return ValidationError{
+				Validator: "checkIfDomainHasMX",
+				error:     ErrNullMX,
+			}
+		}
+
 		if !a.Validations.HasFlag(validations.FMXLookup) {
 			return ValidationError{
 				Validator: "checkIfDomainHasMX",
@@ -88,10 +162,27 @@ func checkIfDomainHasMX(a *Artifact) error {
 
 	a.Steps.SetFlag(validations.FMXLookup)
 
+	// An address literal is the mail host itself, there is nothing to look up
+	if ip, ok := a.addressLiteral(); ok {
+		a.mx = []string{ip.String()}
+		a.mxFound = []string{ip.String()}
+		a.Validations.SetFlag(validations.FMXLookup)
+		return nil
+	}
+
 	start := time.Now()
-	mxs, err := fetchMXHosts(a.ctx, a.resolver, a.email.Domain)
+	mxs, err := fetchMXHosts(a.ctx, a.resolver, a.email.CanonicalDomain())
 	a.Timings.Add("checkIfDomainHasMX", time.Since(start))
 
+	if errors.Is(err, ErrNullMX) {
+		a.Validations.SetFlag(validations.FNullMX)
+		return ValidationError{
+			Validator: "checkIfDomainHasMX",
+			Internal:  err,
+			error:     ErrNullMX,
+		}
+	}
+
 	if err != nil {
 		return ValidationError{
 			Validator: "checkIfDomainHasMX",
@@ -101,12 +192,67 @@ func checkIfDomainHasMX(a *Artifact) error {
 	}
 
 	a.mx = mxs
+	a.mxFound = append([]string(nil), mxs...)
 	a.Validations.SetFlag(validations.FMXLookup)
 	return nil
 }
 
-// checkIfMXHasIP performs a NS lookup and fetches the IP addresses of the MX hosts.
-// expects to run after checkIfDomainHasMX()
+// checkIfDisposable checks the domain and, when known, its MX hosts against the list of disposable domains. A match
+// marks the Validations with FDisposable, but isn't considered a failure. It's a no-op when no list is configured.
+func checkIfDisposable(a *Artifact) error {
+	if a.disposable == nil || a.Steps.HasFlag(validations.FDisposable) {
+		return nil
+	}
+
+	a.Steps.SetFlag(validations.FDisposable)
+
+	start := time.Now()
+	defer func() {
+		a.Timings.Add("checkIfDisposable", time.Since(start))
+	}()
+
+	if a.disposable.HasDomain(a.email.CanonicalDomain()) {
+		a.Validations.SetFlag(validations.FDisposable)
+		return nil
+	}
+
+	mxs := a.mx
+	if len(mxs) == 0 && a.Validations.HasFlag(validations.FMXLookup) && a.resolver != nil {
+		// The lookup is taken from a previous run, which doesn't hold the MX hosts. Only worth it when there is something
+		// to match.
+		if _, mxHosts := a.disposable.Len(); mxHosts > 0 {
+			mxs, _ = fetchMXHosts(a.ctx, a.resolver, a.email.CanonicalDomain())
+		}
+	}
+
+	for _, mx := range mxs {
+		if mx != "" && a.disposable.HasMXHost(mx) {
+			a.Validations.SetFlag(validations.FDisposable)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// checkIfRoleAccount marks the Validations with FRoleAccount when the local part belongs to a role or system account.
+// Like checkIfDisposable it's informational, the address can still be valid. It's a no-op for domain-only input.
+func checkIfRoleAccount(a *Artifact) error {
+	if a.email.Local == "" || a.Steps.HasFlag(validations.FRoleAccount) {
+		return nil
+	}
+
+	a.Steps.SetFlag(validations.FRoleAccount)
+	if looksLikeRoleAccount(a.roleAccounts, a.email.Local) {
+		a.Validations.SetFlag(validations.FRoleAccount)
+	}
+
+	return nil
+}
+
+// checkIfMXHasIP resolves the A and AAAA records of the MX hosts. Addresses that mail can't be delivered to, such as
+// loopback or private addresses, are dropped (see MXAddressConfig). MX hosts without any address are cleared, the
+// check passes when at least one MX host resolves. Expects to run after checkIfDomainHasMX()
 func checkIfMXHasIP(a *Artifact) error {
 	if a.Steps.HasFlag(validations.FMXDomainHasIP) {
 		if !a.Validations.HasFlag(validations.FMXDomainHasIP) {
@@ -122,25 +268,71 @@ func checkIfMXHasIP(a *Artifact) error {
 	a.Steps.SetFlag(validations.FMXDomainHasIP)
 
 	var err error
+	var hosts, resolved, unroutable int
 	for i, domain := range a.mx {
-		start := time.Now()
-		ips, innerErr := a.resolver.LookupMX(a.ctx, domain)
-		a.Timings.Add("checkIfMXHasIP "+domain, time.Since(start))
+		if domain == "" {
+			continue
+		}
+
+		hosts++
+
+		// The MX of an address literal is an IP already. It's the address the sender asked for, rather than one published
+		// by a domain, so it's not classified (e.g. "john@[10.0.0.25]" on an internal network).
+		var routable []net.IP
+		if ip, ok := a.addressLiteral(); ok && ip.String() == domain {
+			routable = []net.IP{ip}
+		} else {
+			start := time.Now()
+			addrs, innerErr := a.resolver.LookupIPAddr(a.ctx, domain)
+			a.Timings.Add("checkIfMXHasIP "+domain, time.Since(start))
 
-		if innerErr != nil || len(ips) == 0 {
-			a.mx[i] = ""
+			if innerErr != nil || len(addrs) == 0 {
+				a.mx[i] = ""
+
+				if innerErr != nil {
+					err = wrapError(err, innerErr)
+				}
+
+				continue
+			}
 
-			if innerErr != nil {
+			ips := make([]net.IP, 0, len(addrs))
+			for _, addr := range addrs {
+				ips = append(ips, addr.IP)
+			}
+
+			routable, innerErr = routableMXAddresses(a.mxAddress, domain, ips)
+			if len(routable) == 0 {
+				a.mx[i] = ""
 				err = wrapError(err, innerErr)
+				unroutable++
+				continue
 			}
 		}
+
+		if a.mxAddresses == nil {
+			a.mxAddresses = make(map[string][]net.IP, len(a.mx))
+		}
+
+		a.mxAddresses[domain] = append(a.mxAddresses[domain], routable...)
+		resolved++
 	}
 
-	if err != nil {
+	if resolved == 0 {
+		if err == nil {
+			err = fmt.Errorf("none of the %d MX host(s) resolved %w", len(a.mx), ErrInvalidHost)
+		}
+
+		// Only when all MX hosts point at unroutable addresses, mail can never be delivered
+		public := ErrEmailAddressSyntax
+		if unroutable > 0 && unroutable == hosts {
+			public = ErrUnroutableMX
+		}
+
 		return ValidationError{
 			Validator: "checkIfMXHasIP",
 			Internal:  err,
-			error:     ErrEmailAddressSyntax,
+			error:     public,
 		}
 	}
 
@@ -148,8 +340,33 @@ func checkIfMXHasIP(a *Artifact) error {
 	return nil
 }
 
-// checkMXAcceptsConnect checks if an MX host accepts connections. Expensive and requires a valid PTR setup for most
-// real world applications
+// routableMXAddresses returns the addresses of host that mail can be delivered to. When there are none, the error
+// describes the first address that was dropped.
+func routableMXAddresses(conf MXAddressConfig, host string, ips []net.IP) ([]net.IP, error) {
+	routable := make([]net.IP, 0, len(ips))
+
+	var err error
+	for _, ip := range ips {
+		class, ok := conf.accepts(ip)
+		if ok {
+			routable = append(routable, ip)
+			continue
+		}
+
+		if err == nil {
+			err = fmt.Errorf("MX host %s resolves to %s address %s %w", host, class, ip, ErrUnroutableMX)
+		}
+	}
+
+	if len(routable) > 0 {
+		return routable, nil
+	}
+
+	return nil, err
+}
+
+// checkMXAcceptsConnect checks if an MX host accepts connections, see ConnectConfig for how hosts and ports are tried.
+// Expensive and requires a valid PTR setup for most real world applications
 func checkMXAcceptsConnect(a *Artifact) error {
 	if a.Steps.HasFlag(validations.FHostConnect) {
 		if !a.Validations.HasFlag(validations.FHostConnect) {
@@ -164,16 +381,15 @@ func checkMXAcceptsConnect(a *Artifact) error {
 
 	a.Steps.SetFlag(validations.FHostConnect)
 
-	start := time.Now()
-	var mxToCheck string
-	for _, domain := range a.mx {
-		if domain != "" {
-			mxToCheck = domain
-			break
+	var targets []mxTarget
+	for _, host := range a.mx {
+		if host != "" {
+			targets = append(targets, mxTarget{host: host, ips: a.mxAddresses[host]})
 		}
 	}
 
-	conn, err := getConnection(a.ctx, a.dialer, mxToCheck)
+	start := time.Now()
+	c, err := connectToMX(a.ctx, a.dialer, targets, a.connect)
 	a.Timings.Add("checkMXAcceptsConnect", time.Since(start))
 
 	if err != nil {
@@ -184,60 +400,152 @@ func checkMXAcceptsConnect(a *Artifact) error {
 		}
 	}
 
-	a.conn = conn
+	a.conn = c.conn
+	a.connectedMX = c.host
+	a.connectedAddress = c.address
 	a.Validations.SetFlag(validations.FHostConnect)
 	return nil
 }
 
 // checkRCPT issues mail commands to the mail server, asking politely if a recipient inbox exists. High chance of false
-// positives on real world applications, due to security reasons.
+// positives on real world applications, due to security reasons. Only a definite answer (2xx or 5xx) is recorded as
+// step, temporary failures (e.g. greylisting) are left for a future run. When the recipient is accepted, the same
+// connection is used to find out if the domain accepts any recipient (see checkAcceptAll).
 func checkRCPT(a *Artifact) error {
 	if a.Steps.HasFlag(validations.FValidRCPT) {
 		if !a.Validations.HasFlag(validations.FValidRCPT) {
 			return ValidationError{
 				Validator: "checkRCPT",
-				error:     ErrEmailAddressSyntax,
+				error:     ErrRCPTRejected,
 			}
 		}
 
 		return nil
 	}
 
+	// Probing recipients of a domain that accepts everything doesn't tell us anything
+	if a.Validations.HasFlag(validations.FAcceptAll) {
+		return nil
+	}
+
 	a.Steps.SetFlag(validations.FValidRCPT)
 
 	if a.Validations.HasFlag(validations.FValidRCPT) {
 		return nil
 	}
 
-	var client *smtp.Client
-	var start time.Time
-	var err error
+	if a.conn == nil {
+		if err := reconnect(a); err != nil {
+			a.Steps.RemoveFlag(validations.FValidRCPT)
+			return ValidationError{
+				Validator: "checkRCPT",
+				Internal:  fmt.Errorf("no connection to probe with %w", err),
+				error:     ErrInvalidHost,
+			}
+		}
+	}
 
-	client, err = smtp.NewClient(a.conn, a.email.Domain)
+	start := time.Now()
+	defer func() {
+		a.Timings.Add("checkRCPT", time.Since(start))
+	}()
 
+	probe, err := newSMTPProbe(a.ctx, a.conn, a.connectedMX, a.probe)
 	if err != nil {
+		var cmdErr smtpCommandError
+		if errors.As(err, &cmdErr) {
+			if reply, ok := newSMTPReply(cmdErr.command, cmdErr.err); ok {
+				a.smtpReplies = append(a.smtpReplies, reply)
+			}
+		}
+
+		a.Steps.RemoveFlag(validations.FValidRCPT)
 		return ValidationError{
 			Validator: "checkRCPT",
 			Internal:  err,
-			error:     ErrEmailAddressSyntax,
+			error:     ErrRCPTTemporary,
 		}
 	}
 
 	defer func() {
-		_ = client.Quit()
+		a.smtpReplies = append(a.smtpReplies, probe.replies...)
+		_ = probe.Close()
 	}()
 
-	start = time.Now()
-	err = client.Verify(a.email.Address)
-	a.Timings.Add("checkRCPT", time.Since(start))
+	// Mail servers without SMTPUTF8 support only understand the ASCII form of the domain
+	err = probe.Rcpt(a.email.Local + "@" + a.email.CanonicalDomain())
+	if errors.Is(err, ErrRCPTRejected) {
+		return ValidationError{
+			Validator: "checkRCPT",
+			Internal:  err,
+			error:     ErrRCPTRejected,
+		}
+	}
+
+	if err != nil {
+		a.Steps.RemoveFlag(validations.FValidRCPT)
+		return ValidationError{
+			Validator: "checkRCPT",
+			Internal:  err,
+			error:     ErrRCPTTemporary,
+		}
+	}
+
+	// Whether the domain accepts any recipient might be known already, e.g. from an earlier address of the domain
+	if !a.Steps.HasFlag(validations.FAcceptAll) && checkAcceptAll(a, probe) {
+		// The recipient was accepted, but so would any other. We can't vouch for this specific recipient.
+		a.Steps.RemoveFlag(validations.FValidRCPT)
+		return nil
+	}
+
+	a.Validations.SetFlag(validations.FValidRCPT)
+	return nil
+}
+
+// reconnect connects to an MX host, for when the connection step passed on an earlier run (e.g. from a cache) and the
+// connection isn't held. The MX hosts and their addresses are looked up again, when the artifact doesn't know them.
+func reconnect(a *Artifact) error {
+	steps, v := a.Steps, a.Validations
+
+	redo := validations.FHostConnect
+	if len(a.mx) == 0 {
+		redo |= validations.FMXLookup | validations.FMXDomainHasIP
+	} else if len(a.mxAddresses) == 0 {
+		redo |= validations.FMXDomainHasIP
+	}
+
+	a.Steps.RemoveFlag(redo)
+	for _, check := range []func(*Artifact) error{checkIfDomainHasMX, checkIfMXHasIP, checkMXAcceptsConnect} {
+		if err := check(a); err != nil {
+			a.Steps, a.Validations = steps, v
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAcceptAll probes a random, unguessable, recipient on the domain. When it's accepted, the domain is marked with
+// FAcceptAll. Only a definite answer is recorded as step. Returns true when the domain accepts all recipients.
+func checkAcceptAll(a *Artifact, probe *smtpProbe) bool {
+	local, err := randomLocalPart()
+	if err != nil {
+		return false
+	}
+
+	start := time.Now()
+	err = probe.Rcpt(local + "@" + a.email.CanonicalDomain())
+	a.Timings.Add("checkAcceptAll", time.Since(start))
 
 	if err == nil {
-		a.Validations.SetFlag(validations.FValidRCPT)
+		a.Steps.SetFlag(validations.FAcceptAll)
+		a.Validations.SetFlag(validations.FAcceptAll)
+		return true
 	}
 
-	return ValidationError{
-		Validator: "checkRCPT",
-		Internal:  err,
-		error:     ErrEmailAddressSyntax,
+	if errors.Is(err, ErrRCPTRejected) {
+		a.Steps.SetFlag(validations.FAcceptAll)
 	}
+
+	return false
 }
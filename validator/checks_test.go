@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net"
 	"net/mail"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -17,6 +18,7 @@ func Test_checkEmailAddressSyntax(t *testing.T) {
 	tests := []struct {
 		name    string
 		email   string
+		mode    SyntaxMode
 		wantErr bool
 	}{
 		// All good
@@ -39,6 +41,22 @@ func Test_checkEmailAddressSyntax(t *testing.T) {
 		{name: "Invalid characters (NBSP)", email: "js@example.org\u00a0", wantErr: true},
 		{name: "Invalid characters (NL)", email: "john.doe@example.org\njane@foo", wantErr: true},
 		{name: "Invalid characters (NL) with valid e-mail suffix", email: "john.doe@example.org\njane@example.org", wantErr: true},
+
+		// Only valid with SyntaxRFC5322
+		{name: "quoted local, strict", email: `"john doe"@example.org`, wantErr: true},
+		{name: "address literal, strict", email: "john@[192.0.2.1]", wantErr: true},
+		{name: "quoted local", email: `"john doe"@example.org`, mode: SyntaxRFC5322},
+		{name: "quoted local, escaped quote", email: `"john\"doe"@example.org`, mode: SyntaxRFC5322},
+		{name: "IPv4 address literal", email: "john@[192.0.2.1]", mode: SyntaxRFC5322},
+		{name: "IPv6 address literal", email: "john@[IPv6:2001:db8::1]", mode: SyntaxRFC5322},
+		{name: "quoted local and address literal", email: `"john doe"@[192.0.2.1]`, mode: SyntaxRFC5322},
+		{name: "regular address", email: "john.doe@example.org", mode: SyntaxRFC5322},
+		{name: "quoted local, unbalanced", email: `"john doe@example.org`, mode: SyntaxRFC5322, wantErr: true},
+		{name: "quoted local, control character", email: "\"john\x00doe\"@example.org", mode: SyntaxRFC5322, wantErr: true},
+		{name: "address literal, bad IPv4", email: "john@[192.0.2.256]", mode: SyntaxRFC5322, wantErr: true},
+		{name: "address literal, IPv6 without tag", email: "john@[2001:db8::1]", mode: SyntaxRFC5322, wantErr: true},
+		{name: "address literal, general tag", email: "john@[x400:c=us]", mode: SyntaxRFC5322, wantErr: true},
+		{name: "space in dot-atom local", email: "joh n@example.org", mode: SyntaxRFC5322, wantErr: true},
 	}
 
 	for _, tt := range tests {
@@ -48,6 +66,7 @@ func Test_checkEmailAddressSyntax(t *testing.T) {
 			a := &Artifact{
 				Validations: 0,
 				Timings:     make(Timings, 10),
+				syntaxMode:  tt.mode,
 			}
 
 			a.email, err = types.NewEmailParts(tt.email)
@@ -87,18 +106,27 @@ func Test_checkEmailAddressSyntax(t *testing.T) {
 }
 
 func Test_checkDomainSyntax(t *testing.T) {
+	tlds := NewTLDList()
+
 	tests := []struct {
 		name    string
 		domain  string
+		tlds    *TLDList
 		wantErr bool
 	}{
 		// All good
 		{name: "valid but short", domain: "wx.yz"},
 		{name: "with sub-domain", domain: "doe.example.org"},
 		{name: "wrong tld, but valid syntax", domain: "example.mail"},
+		{name: "known tld", domain: "doe.example.org", tlds: tlds},
+		{name: "known Unicode tld", domain: "อชนิค.ไทย", tlds: tlds},
 
 		{name: "Unicode", domain: "อชนิค.ไทย"},
 
+		// Unknown TLDs
+		{name: "unknown tld", domain: "example.mail", tlds: tlds, wantErr: true},
+		{name: "misspelled tld", domain: "example.cmo", tlds: tlds, wantErr: true},
+
 		// All bad
 		{name: "Invalid visible character", domain: "d.org>", wantErr: true},
 		{name: "ending on a dot", domain: "example.org.", wantErr: true},
@@ -119,6 +147,7 @@ func Test_checkDomainSyntax(t *testing.T) {
 					Local:   "",
 					Domain:  tt.domain,
 				},
+				tlds: tt.tlds,
 			}
 
 			if err := checkDomainSyntax(a); (err != nil) != tt.wantErr {
@@ -185,6 +214,112 @@ func Test_looksLikeValidLocalPart(t *testing.T) {
 	}
 }
 
+func Test_looksLikeValidQuotedLocalPart(t *testing.T) {
+	tests := []struct {
+		local string
+		want  bool
+	}{
+		// The good
+		{want: true, local: `"john doe"`},
+		{want: true, local: `"john..doe"`},
+		{want: true, local: `"john@doe"`},
+		{want: true, local: `"john\"doe"`},
+		{want: true, local: `"john\\doe"`},
+		{want: true, local: "\"john\tdoe\""},
+		{want: true, local: `"Dörte"`},
+
+		// The bad
+		{local: `""`},
+		{local: `"`},
+		{local: `john doe`},
+		{local: `"john doe`},
+		{local: `"john"doe"`},
+		{local: `"john doe\"`},
+		{local: "\"john\ndoe\""},
+		{local: "\"john\x7fdoe\""},
+		{local: "\"john\xffdoe\""}, // Invalid UTF-8
+		{local: `"` + strings.Repeat("a", 63) + `"`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run("testing "+tt.local, func(t *testing.T) {
+			if got := looksLikeValidQuotedLocalPart(tt.local); got != tt.want {
+				t.Errorf("looksLikeValidQuotedLocalPart(%q) = %v, want %v", tt.local, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseAddressLiteral(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   net.IP
+	}{
+		// The good
+		{domain: "[192.0.2.1]", want: net.ParseIP("192.0.2.1")},
+		{domain: "[IPv6:2001:db8::1]", want: net.ParseIP("2001:db8::1")},
+		{domain: "[ipv6:2001:db8::1]", want: net.ParseIP("2001:db8::1")},
+		{domain: "[IPv6:::ffff:192.0.2.1]", want: net.ParseIP("192.0.2.1")},
+
+		// The bad
+		{domain: "example.org"},
+		{domain: "[]"},
+		{domain: "[192.0.2.1"},
+		{domain: "[192.0.2]"},
+		{domain: "[2001:db8::1]"},
+		{domain: "[IPv6:192.0.2.1]"},
+		{domain: "[IPv6:]"},
+		{domain: "[x400:c=us]"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run("testing "+tt.domain, func(t *testing.T) {
+			got, ok := parseAddressLiteral(tt.domain)
+			if ok != (tt.want != nil) || !got.Equal(tt.want) {
+				t.Errorf("parseAddressLiteral(%q) = %v, %t, want %v", tt.domain, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func Test_looksLikeRoleAccount(t *testing.T) {
+	set := newRoleAccountSet(DefaultRoleAccounts)
+
+	tests := []struct {
+		local string
+		want  bool
+	}{
+		{want: true, local: "info"},
+		{want: true, local: "Postmaster"},
+		{want: true, local: "no-reply"},
+		{want: true, local: "no_reply"},
+		{want: true, local: "No.Reply"},
+		{want: true, local: "support+orders"},
+
+		{local: "john.doe"},
+		{local: "information"},
+		{local: "+info"},
+		{local: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run("testing "+tt.local, func(t *testing.T) {
+			if got := looksLikeRoleAccount(set, tt.local); got != tt.want {
+				t.Errorf("looksLikeRoleAccount(%q) = %v, want %v", tt.local, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("empty set", func(t *testing.T) {
+		if looksLikeRoleAccount(newRoleAccountSet(nil), "info") {
+			t.Errorf("Expected an empty set to never match")
+		}
+	})
+}
+
 func Test_looksLikeValidDomain(t *testing.T) {
 	const (
 
@@ -260,10 +395,14 @@ func Test_looksLikeValidDomain(t *testing.T) {
 
 func Test_checkIfDomainHasMX(t *testing.T) {
 	tests := []struct {
-		name     string
-		resolver LookupMX
-		steps    validations.Steps
-		wantErr  bool
+		name        string
+		resolver    Resolver
+		steps       validations.Steps
+		validations validations.Validations
+		email       types.EmailParts
+		mode        SyntaxMode
+		wantErr     bool
+		wantNullMX  bool
 	}{
 		{
 			name:     "all good",
@@ -291,6 +430,31 @@ func Test_checkIfDomainHasMX(t *testing.T) {
 			steps:    validations.Steps(validations.FMXLookup),
 			wantErr:  true,
 		},
+		{
+			name:       "null MX",
+			resolver:   buildLookupMX([]string{"."}, nil),
+			wantErr:    true,
+			wantNullMX: true,
+		},
+		{
+			name:        "Step already defined, null MX",
+			steps:       validations.Steps(validations.FMXLookup),
+			validations: validations.Validations(validations.FNullMX),
+			wantErr:     true,
+			wantNullMX:  true,
+		},
+		{
+			name:     "address literal, no lookup",
+			resolver: buildLookupMX(nil, errors.New("the resolver should not be used")),
+			email:    types.NewEmailFromParts("john", "[192.0.2.1]"),
+			mode:     SyntaxRFC5322,
+		},
+		{
+			name:     "address literal, strict",
+			resolver: buildLookupMX(nil, errors.New("no such host")),
+			email:    types.NewEmailFromParts("john", "[192.0.2.1]"),
+			wantErr:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -299,13 +463,24 @@ func Test_checkIfDomainHasMX(t *testing.T) {
 			a := &Artifact{
 				resolver:    tt.resolver,
 				Steps:       tt.steps,
-				Validations: 0,
+				Validations: tt.validations,
+				email:       tt.email,
+				syntaxMode:  tt.mode,
 			}
 
-			if err := checkIfDomainHasMX(a); (err != nil) != tt.wantErr {
+			err := checkIfDomainHasMX(a)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("checkIfDomainHasMX() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
+			if got := errors.Is(err, ErrNullMX); got != tt.wantNullMX {
+				t.Errorf("checkIfDomainHasMX() error = %v, expected a null MX error: %t", err, tt.wantNullMX)
+			}
+
+			if got := a.Validations.HasFlag(validations.FNullMX); got != tt.wantNullMX {
+				t.Errorf("Expected the FNullMX flag to be specified: %t, got %+v", tt.wantNullMX, a.Validations)
+			}
+
 			// Since we run an MX check, the step should always be defined
 			if !a.Steps.HasFlag(validations.FMXLookup) {
 				t.Errorf("Expected the flag to be specified as Step %+v", a.Steps)
@@ -325,18 +500,94 @@ func Test_checkIfMXHasIP(t *testing.T) {
 	expiredCtx, cancel := context.WithDeadline(context.Background(), time.Now())
 	cancel()
 
+	// The addresses reserved for documentation are unroutable as well, they're allowed to keep the examples readable
+	allowDocumentation := MXAddressConfig{Allow: IPDocumentation}
+	sinkholes, _ := ParseSinkholes([]string{"192.0.2.53"})
+
 	tests := []struct {
-		name     string
-		resolver LookupMX
-		steps    validations.Steps
-		mx       []string
-		ctx      context.Context
-		wantErr  bool
+		name      string
+		resolver  Resolver
+		steps     validations.Steps
+		mx        []string
+		ctx       context.Context
+		mxAddress MXAddressConfig
+		wantMX    []string
+		wantErr   bool
+		wantErrIs error
 	}{
 		{
-			name:     "all good",
-			resolver: buildLookupMX([]string{"example.org"}, nil),
-			wantErr:  false,
+			name:      "all good",
+			resolver:  buildResolver(nil, map[string][]net.IPAddr{"mx.example.org": {{IP: net.IPv4(192, 0, 2, 1)}}}, nil),
+			mx:        []string{"mx.example.org"},
+			mxAddress: allowDocumentation,
+			wantErr:   false,
+		},
+		{
+			name: "one of the MX hosts resolves",
+			resolver: buildResolver(nil, map[string][]net.IPAddr{
+				"mx2.example.org": {{IP: net.ParseIP("2001:db8::1")}},
+			}, nil),
+			mx:        []string{"mx1.example.org", "", "mx2.example.org"},
+			mxAddress: allowDocumentation,
+			wantErr:   false,
+		},
+		{
+			name:      "loopback",
+			resolver:  buildResolver(nil, map[string][]net.IPAddr{"mx.example.org": {{IP: net.IPv4(127, 0, 0, 1)}}}, nil),
+			mx:        []string{"mx.example.org"},
+			wantErr:   true,
+			wantErrIs: ErrUnroutableMX,
+		},
+		{
+			name:      "private",
+			resolver:  buildResolver(nil, map[string][]net.IPAddr{"mx.example.org": {{IP: net.IPv4(10, 0, 0, 25)}, {IP: net.ParseIP("fd00::25")}}}, nil),
+			mx:        []string{"mx.example.org"},
+			wantErr:   true,
+			wantErrIs: ErrUnroutableMX,
+		},
+		{
+			name:      "private, allowed",
+			resolver:  buildResolver(nil, map[string][]net.IPAddr{"mx.example.org": {{IP: net.IPv4(10, 0, 0, 25)}}}, nil),
+			mx:        []string{"mx.example.org"},
+			mxAddress: MXAddressConfig{Allow: IPPrivate},
+			wantErr:   false,
+		},
+		{
+			name:      "sinkhole",
+			resolver:  buildResolver(nil, map[string][]net.IPAddr{"mx.example.org": {{IP: net.IPv4(192, 0, 2, 53)}}}, nil),
+			mx:        []string{"mx.example.org"},
+			mxAddress: MXAddressConfig{Allow: IPDocumentation, Sinkholes: sinkholes},
+			wantErr:   true,
+			wantErrIs: ErrUnroutableMX,
+		},
+		{
+			name: "unroutable MX hosts are cleared",
+			resolver: buildResolver(nil, map[string][]net.IPAddr{
+				"mx1.example.org": {{IP: net.IPv4(169, 254, 0, 25)}},
+				"mx2.example.org": {{IP: net.IPv4(192, 0, 2, 1)}},
+			}, nil),
+			mx:        []string{"mx1.example.org", "mx2.example.org"},
+			mxAddress: allowDocumentation,
+			wantMX:    []string{"", "mx2.example.org"},
+			wantErr:   false,
+		},
+		{
+			name:      "unroutable and unresolvable MX hosts",
+			resolver:  buildResolver(nil, map[string][]net.IPAddr{"mx2.example.org": {{IP: net.IPv4(0, 0, 0, 0)}}}, nil),
+			mx:        []string{"mx1.example.org", "mx2.example.org"},
+			wantErr:   true,
+			wantErrIs: ErrEmailAddressSyntax,
+		},
+		{
+			name:     "no MX host resolves",
+			resolver: buildResolver(nil, map[string][]net.IPAddr{"mx2.example.org": {}}, nil),
+			mx:       []string{"mx1.example.org", "mx2.example.org"},
+			wantErr:  true,
+		},
+		{
+			name:     "no MX hosts",
+			resolver: buildResolver(nil, nil, nil),
+			wantErr:  true,
 		},
 		{
 			name:     "lookup fail",
@@ -370,6 +621,7 @@ func Test_checkIfMXHasIP(t *testing.T) {
 				Steps:       tt.steps,
 				mx:          tt.mx,
 				ctx:         tt.ctx,
+				mxAddress:   tt.mxAddress,
 				Validations: 0,
 			}
 
@@ -377,6 +629,102 @@ func Test_checkIfMXHasIP(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Errorf("checkIfMXHasIP() error = %v, wantErr %v", err, tt.wantErr)
 			}
+
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("checkIfMXHasIP() error = %v, want %v", err, tt.wantErrIs)
+			}
+
+			if tt.wantMX != nil && !reflect.DeepEqual(a.mx, tt.wantMX) {
+				t.Errorf("Expected the MX hosts %q, got %q", tt.wantMX, a.mx)
+			}
+		})
+	}
+}
+
+func Test_checkIfDisposable(t *testing.T) {
+	list := NewDisposableList([]string{"mailinator.com"}, []string{"mx.trap.example.org"})
+
+	tests := []struct {
+		name           string
+		list           *DisposableList
+		domain         string
+		mx             []string
+		steps          validations.Steps
+		validations    validations.Validations
+		resolver       Resolver
+		wantStep       bool
+		wantDisposable bool
+	}{
+		{name: "disposable domain", list: list, domain: "mailinator.com", wantStep: true, wantDisposable: true},
+		{name: "disposable MX host", list: list, domain: "example.org", mx: []string{"", "mx.trap.example.org."}, wantStep: true, wantDisposable: true},
+		{name: "regular domain", list: list, domain: "example.org", mx: []string{"mx.example.org."}, wantStep: true},
+		{name: "MX hosts of a previous run", list: list, domain: "example.org", steps: validations.Steps(validations.FMXLookup), validations: validations.Validations(validations.FMXLookup), resolver: buildResolver([]string{"mx.trap.example.org."}, nil, nil), wantStep: true, wantDisposable: true},
+		{name: "no list configured", domain: "mailinator.com"},
+		{name: "step already defined", list: list, domain: "mailinator.com", steps: validations.Steps(validations.FDisposable), wantStep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Artifact{
+				Steps:       tt.steps,
+				Validations: tt.validations,
+				ctx:         context.Background(),
+				resolver:    tt.resolver,
+				email:       types.NewEmailFromParts("john", tt.domain),
+				mx:          tt.mx,
+				disposable:  tt.list,
+			}
+
+			if err := checkIfDisposable(a); err != nil {
+				t.Errorf("checkIfDisposable() unexpected error = %v", err)
+			}
+
+			if got := a.Steps.HasFlag(validations.FDisposable); got != tt.wantStep {
+				t.Errorf("Expected the step to be defined: %t, got: %t", tt.wantStep, got)
+			}
+
+			if got := a.Validations.HasFlag(validations.FDisposable); got != tt.wantDisposable {
+				t.Errorf("Expected the validation to be defined: %t, got: %t", tt.wantDisposable, got)
+			}
+		})
+	}
+}
+
+func Test_checkIfRoleAccount(t *testing.T) {
+	set := newRoleAccountSet([]string{"info"})
+
+	tests := []struct {
+		name     string
+		local    string
+		steps    validations.Steps
+		wantStep bool
+		wantRole bool
+	}{
+		{name: "role account", local: "info", wantStep: true, wantRole: true},
+		{name: "personal account", local: "john", wantStep: true},
+		{name: "domain only", local: ""},
+		{name: "step already defined", local: "info", steps: validations.Steps(validations.FRoleAccount), wantStep: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Artifact{
+				Steps:        tt.steps,
+				email:        types.NewEmailFromParts(tt.local, "example.org"),
+				roleAccounts: set,
+			}
+
+			if err := checkIfRoleAccount(a); err != nil {
+				t.Errorf("checkIfRoleAccount() unexpected error = %v", err)
+			}
+
+			if got := a.Steps.HasFlag(validations.FRoleAccount); got != tt.wantStep {
+				t.Errorf("Expected the step to be defined: %t, got: %t", tt.wantStep, got)
+			}
+
+			if got := a.Validations.HasFlag(validations.FRoleAccount); got != tt.wantRole {
+				t.Errorf("Expected the validation to be defined: %t, got: %t", tt.wantRole, got)
+			}
 		})
 	}
 }
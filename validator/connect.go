@@ -0,0 +1,279 @@
+package validator
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultConnectPorts are tried in order on each MX host. Port 465 expects TLS straight away (implicit TLS, RFC 8314),
+// a connection there only counts when the TLS handshake succeeds.
+var DefaultConnectPorts = []uint16{25, 587, 2525, 465}
+
+const (
+	DefaultConnectConcurrency = 2
+	DefaultHostDelay          = 2 * time.Second
+	DefaultAddressDelay       = 250 * time.Millisecond
+	DefaultAttemptTimeout     = 10 * time.Second
+
+	implicitTLSPort = 465
+)
+
+// ConnectConfig defines how StepConnect connects to the MX hosts of a domain. MX hosts are tried in order of
+// preference, a host that doesn't connect in time gets company of the next host, up to Concurrency hosts at once. The
+// addresses of a host are tried alternating IPv6 and IPv4, known as happy eyeballs (RFC 8305).
+type ConnectConfig struct {
+	// Ports are tried in order on each MX host, defaults to DefaultConnectPorts
+	Ports []uint16
+
+	// Concurrency bounds the number of MX hosts that are connected to at once, defaults to DefaultConnectConcurrency
+	Concurrency int
+
+	// HostDelay is the head start an MX host gets, before the next one is tried as well. Defaults to DefaultHostDelay
+	HostDelay time.Duration
+
+	// AddressDelay is the head start an address gets, before the next address of the same host is tried as well.
+	// Defaults to DefaultAddressDelay
+	AddressDelay time.Duration
+
+	// AttemptTimeout limits a single connection attempt to an address and port, defaults to DefaultAttemptTimeout
+	AttemptTimeout time.Duration
+
+	// TLSConfig is used for implicit TLS. When nil, a config with the MX host as ServerName is used
+	TLSConfig *tls.Config
+}
+
+func (c ConnectConfig) withDefaults() ConnectConfig {
+	if len(c.Ports) == 0 {
+		c.Ports = DefaultConnectPorts
+	}
+
+	if c.Concurrency < 1 {
+		c.Concurrency = DefaultConnectConcurrency
+	}
+
+	if c.HostDelay <= 0 {
+		c.HostDelay = DefaultHostDelay
+	}
+
+	if c.AddressDelay <= 0 {
+		c.AddressDelay = DefaultAddressDelay
+	}
+
+	if c.AttemptTimeout <= 0 {
+		c.AttemptTimeout = DefaultAttemptTimeout
+	}
+
+	return c
+}
+
+// mxTarget is an MX host and its addresses. Without addresses, the host name is dialed.
+type mxTarget struct {
+	host string
+	ips  []net.IP
+}
+
+// mxConnection is a connection to an MX host, address is the IP (or host name) and port that was dialed
+type mxConnection struct {
+	conn    net.Conn
+	host    string
+	address string
+}
+
+type connectAttempt func(ctx context.Context) (mxConnection, error)
+
+// connectToMX connects to one of the targets, as described by ConnectConfig. The errors of all failed attempts are
+// combined, when no connection could be made.
+func connectToMX(ctx context.Context, dialer DialContext, targets []mxTarget, conf ConnectConfig) (mxConnection, error) {
+	conf = conf.withDefaults()
+	if len(targets) == 0 {
+		return mxConnection{}, fmt.Errorf("no MX host to connect to %w", ErrInvalidHost)
+	}
+
+	attempts := make([]connectAttempt, 0, len(targets))
+	for _, target := range targets {
+		target := target
+		attempts = append(attempts, func(ctx context.Context) (mxConnection, error) {
+			return connectToHost(ctx, dialer, target, conf)
+		})
+	}
+
+	c, err := staggered(ctx, attempts, conf.Concurrency, conf.HostDelay)
+	if err != nil {
+		return c, wrapError(err, fmt.Errorf("no connection possible %w", ErrInvalidHost))
+	}
+
+	return c, nil
+}
+
+// connectToHost tries the ports in order. For each port, the addresses are raced with happy eyeballs.
+func connectToHost(ctx context.Context, dialer DialContext, target mxTarget, conf ConnectConfig) (mxConnection, error) {
+	hosts := []string{target.host}
+	if len(target.ips) > 0 {
+		hosts = hosts[:0]
+		for _, ip := range interleaveFamilies(target.ips) {
+			hosts = append(hosts, ip.String())
+		}
+	}
+
+	var err error
+	for _, port := range conf.Ports {
+		port := port
+
+		attempts := make([]connectAttempt, 0, len(hosts))
+		for _, host := range hosts {
+			address := net.JoinHostPort(host, strconv.Itoa(int(port)))
+			attempts = append(attempts, func(ctx context.Context) (mxConnection, error) {
+				conn, err := dial(ctx, dialer, target.host, address, port, conf)
+				if err != nil {
+					return mxConnection{}, fmt.Errorf("%s (%s) %w", strings.TrimSuffix(target.host, "."), address, err)
+				}
+
+				return mxConnection{conn: conn, host: target.host, address: address}, nil
+			})
+		}
+
+		c, portErr := staggered(ctx, attempts, len(attempts), conf.AddressDelay)
+		if portErr == nil {
+			return c, nil
+		}
+
+		err = wrapError(err, portErr)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return mxConnection{}, err
+}
+
+// dial connects to address and, on the implicit TLS port, completes the TLS handshake
+func dial(ctx context.Context, dialer DialContext, mxHost, address string, port uint16, conf ConnectConfig) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, conf.AttemptTimeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if port != implicitTLSPort {
+		return conn, nil
+	}
+
+	tlsConfig := conf.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: strings.TrimSuffix(mxHost, "."), MinVersion: tls.VersionTLS12}
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("TLS handshake failed %w", err)
+	}
+
+	return tlsConn, nil
+}
+
+// staggered runs the attempts in order, with at most limit running at once. The next attempt starts as soon as a
+// running one fails, or when the latest one didn't finish within delay. The first connection wins, the other attempts
+// are cancelled and connections that are made regardless are closed.
+func staggered(ctx context.Context, attempts []connectAttempt, limit int, delay time.Duration) (mxConnection, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		c   mxConnection
+		err error
+	}
+
+	results := make(chan result, len(attempts))
+	var next, running int
+	var err error
+	var nextStart time.Time
+
+	for running > 0 || (next < len(attempts) && ctx.Err() == nil) {
+		canStart := next < len(attempts) && running < limit && ctx.Err() == nil
+		if canStart && (running == 0 || !time.Now().Before(nextStart)) {
+			attempt := attempts[next]
+			go func() {
+				c, err := attempt(ctx)
+				results <- result{c: c, err: err}
+			}()
+
+			next++
+			running++
+			nextStart = time.Now().Add(delay)
+			continue
+		}
+
+		var wait <-chan time.Time
+		var timer *time.Timer
+		if canStart {
+			timer = time.NewTimer(time.Until(nextStart))
+			wait = timer.C
+		}
+
+		select {
+		case r := <-results:
+			running--
+			if r.err == nil {
+				cancel()
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						if r := <-results; r.err == nil {
+							_ = r.c.conn.Close()
+						}
+					}
+				}(running)
+
+				return r.c, nil
+			}
+
+			err = wrapError(err, r.err)
+
+			// A failure makes room for the next attempt straight away
+			nextStart = time.Now()
+		case <-wait:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	return mxConnection{}, err
+}
+
+// interleaveFamilies orders addresses alternating between IPv6 and IPv4, starting with IPv6 (RFC 8305 §4)
+func interleaveFamilies(ips []net.IP) []net.IP {
+	var v6, v4 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	result := make([]net.IP, 0, len(ips))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			result = append(result, v6[i])
+		}
+
+		if i < len(v4) {
+			result = append(result, v4[i])
+		}
+	}
+
+	return result
+}
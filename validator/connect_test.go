@@ -0,0 +1,253 @@
+package validator
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// dialOutcome scripts what happens when an address is dialed
+type dialOutcome struct {
+	err       error
+	hang      bool // Block until the context is done
+	delay     time.Duration
+	serverTLS *tls.Config // Perform a TLS handshake as server
+}
+
+// scriptedDialer dials according to a script, addresses without an outcome refuse the connection
+type scriptedDialer struct {
+	script map[string]dialOutcome
+
+	lock   sync.Mutex
+	dialed []string
+}
+
+func (d *scriptedDialer) DialContext(ctx context.Context, _, address string) (net.Conn, error) {
+	d.lock.Lock()
+	d.dialed = append(d.dialed, address)
+	d.lock.Unlock()
+
+	outcome, ok := d.script[address]
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+
+	if outcome.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	select {
+	case <-time.After(outcome.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if outcome.err != nil {
+		return nil, outcome.err
+	}
+
+	client, server := net.Pipe()
+	go func() {
+		if outcome.serverTLS != nil {
+			_ = tls.Server(server, outcome.serverTLS).Handshake()
+		}
+
+		_ = server.Close()
+	}()
+
+	return client, nil
+}
+
+func (d *scriptedDialer) Dialed() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return append([]string(nil), d.dialed...)
+}
+
+func Test_connectToMX(t *testing.T) {
+	mx1 := mxTarget{host: "mx1.example.org.", ips: []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")}}
+	mx2 := mxTarget{host: "mx2.example.org.", ips: []net.IP{net.ParseIP("192.0.2.2")}}
+	ok := dialOutcome{}
+
+	tests := []struct {
+		name        string
+		targets     []mxTarget
+		ports       []uint16
+		script      map[string]dialOutcome
+		wantHost    string
+		wantAddress string
+		wantDialed  []string // The addresses that must have been dialed, in order
+		wantErr     string   // Part of the error message
+	}{
+		{
+			name:        "first host, first port",
+			targets:     []mxTarget{mx1, mx2},
+			script:      map[string]dialOutcome{"[2001:db8::1]:25": ok},
+			wantHost:    "mx1.example.org.",
+			wantAddress: "[2001:db8::1]:25",
+			wantDialed:  []string{"[2001:db8::1]:25"},
+		},
+		{
+			name:        "addresses are tried alternating IPv6 and IPv4",
+			targets:     []mxTarget{mx1},
+			script:      map[string]dialOutcome{"192.0.2.1:25": ok},
+			wantHost:    "mx1.example.org.",
+			wantAddress: "192.0.2.1:25",
+			wantDialed:  []string{"[2001:db8::1]:25", "192.0.2.1:25"},
+		},
+		{
+			name:        "a hanging address doesn't block the others",
+			targets:     []mxTarget{mx1},
+			script:      map[string]dialOutcome{"[2001:db8::1]:25": {hang: true}, "192.0.2.1:25": ok},
+			wantHost:    "mx1.example.org.",
+			wantAddress: "192.0.2.1:25",
+		},
+		{
+			name:        "ports are tried in order",
+			targets:     []mxTarget{mx2},
+			script:      map[string]dialOutcome{"192.0.2.2:587": ok, "192.0.2.2:2525": ok},
+			wantHost:    "mx2.example.org.",
+			wantAddress: "192.0.2.2:587",
+			wantDialed:  []string{"192.0.2.2:25", "192.0.2.2:587"},
+		},
+		{
+			name:        "falls back on the next host",
+			targets:     []mxTarget{mx1, mx2},
+			ports:       []uint16{25},
+			script:      map[string]dialOutcome{"192.0.2.2:25": ok},
+			wantHost:    "mx2.example.org.",
+			wantAddress: "192.0.2.2:25",
+		},
+		{
+			name:        "a slow first host gets company",
+			targets:     []mxTarget{mx2, {host: "mx3.example.org.", ips: []net.IP{net.ParseIP("192.0.2.3")}}},
+			ports:       []uint16{25},
+			script:      map[string]dialOutcome{"192.0.2.2:25": {hang: true}, "192.0.2.3:25": ok},
+			wantHost:    "mx3.example.org.",
+			wantAddress: "192.0.2.3:25",
+		},
+		{
+			name:        "hosts without addresses are dialed by name",
+			targets:     []mxTarget{{host: "mx.example.org"}},
+			script:      map[string]dialOutcome{"mx.example.org:25": ok},
+			wantHost:    "mx.example.org",
+			wantAddress: "mx.example.org:25",
+		},
+		{
+			name:    "all failures are reported",
+			targets: []mxTarget{mx2},
+			ports:   []uint16{25, 587},
+			script:  map[string]dialOutcome{"192.0.2.2:587": {err: errors.New("no route to host")}},
+			wantErr: "mx2.example.org (192.0.2.2:25) dial tcp: connection refused mx2.example.org (192.0.2.2:587) no route to host",
+		},
+		{
+			name:    "implicit TLS failures are reported",
+			targets: []mxTarget{mx2},
+			ports:   []uint16{465},
+			script:  map[string]dialOutcome{"192.0.2.2:465": ok},
+			wantErr: "mx2.example.org (192.0.2.2:465) TLS handshake failed",
+		},
+		{
+			name:        "implicit TLS",
+			targets:     []mxTarget{mx2},
+			ports:       []uint16{465},
+			script:      map[string]dialOutcome{"192.0.2.2:465": {serverTLS: newSelfSignedTLSConfig(t, "mx2.example.org")}},
+			wantHost:    "mx2.example.org.",
+			wantAddress: "192.0.2.2:465",
+		},
+		{
+			name:    "nothing to connect to",
+			wantErr: "no MX host to connect to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := &scriptedDialer{script: tt.script}
+			conf := ConnectConfig{
+				Ports:          tt.ports,
+				HostDelay:      20 * time.Millisecond,
+				AddressDelay:   10 * time.Millisecond,
+				AttemptTimeout: time.Second,
+				TLSConfig:      &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // Self-signed certificate in tests
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			c, err := connectToMX(ctx, dialer, tt.targets, conf)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.Is(err, ErrInvalidHost) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %s", err)
+			}
+
+			_ = c.conn.Close()
+
+			if time.Since(start) > time.Second {
+				t.Errorf("Expected hanging attempts not to be waited for, it took %s", time.Since(start))
+			}
+
+			if c.host != tt.wantHost || c.address != tt.wantAddress {
+				t.Errorf("Expected a connection to %s on %s, got %s on %s", tt.wantHost, tt.wantAddress, c.host, c.address)
+			}
+
+			if tt.wantDialed != nil && !reflect.DeepEqual(dialer.Dialed(), tt.wantDialed) {
+				t.Errorf("Expected %v to be dialed, got %v", tt.wantDialed, dialer.Dialed())
+			}
+		})
+	}
+}
+
+func Test_connectToMX_concurrency(t *testing.T) {
+	var targets []mxTarget
+	script := map[string]dialOutcome{}
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		targets = append(targets, mxTarget{host: ip, ips: []net.IP{net.ParseIP(ip)}})
+		script[ip+":25"] = dialOutcome{hang: true}
+	}
+
+	dialer := &scriptedDialer{script: script}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := connectToMX(ctx, dialer, targets, ConnectConfig{Ports: []uint16{25}, Concurrency: 2, HostDelay: time.Millisecond})
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if dialed := dialer.Dialed(); len(dialed) != 2 {
+		t.Errorf("Expected at most 2 hosts to be tried at once, got %v", dialed)
+	}
+}
+
+func Test_interleaveFamilies(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("192.0.2.2"),
+		net.ParseIP("192.0.2.3"),
+		net.ParseIP("2001:db8::1"),
+	}
+
+	want := []net.IP{ips[3], ips[0], ips[1], ips[2]}
+	if got := interleaveFamilies(ips); !reflect.DeepEqual(got, want) {
+		t.Errorf("interleaveFamilies() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+)
+
+// Reason is a machine-readable code, describing why a check failed
+type Reason string
+
+const (
+	ReasonNone           Reason = ""
+	ReasonSyntax         Reason = "syntax"
+	ReasonUnknownTLD     Reason = "unknown_tld"
+	ReasonNullMX         Reason = "null_mx"
+	ReasonMXLookup       Reason = "mx_lookup_failed"
+	ReasonMXUnresolvable Reason = "mx_unresolvable"
+	ReasonMXUnroutable   Reason = "mx_unroutable"
+	ReasonConnect        Reason = "connect_failed"
+	ReasonNoConnection   Reason = "no_connection"
+	ReasonRCPTRejected   Reason = "rcpt_rejected"
+	ReasonRCPTTemporary  Reason = "rcpt_temporary"
+	ReasonTimeout        Reason = "timeout"
+	ReasonStepFailed     Reason = "step_failed"
+	ReasonUnknown        Reason = "unknown"
+)
+
+// Diagnostics holds the evidence collected during a check, it explains why an address was (or wasn't) accepted
+type Diagnostics struct {
+	// FailedStep is the validator that stopped the check, empty when no step failed
+	FailedStep string `json:"failed_step,omitempty"`
+	Reason     Reason `json:"reason,omitempty"`
+
+	// Detail is the underlying error, e.g. the DNS or dial error
+	Detail string `json:"detail,omitempty"`
+
+	MXHosts     []string            `json:"mx_hosts,omitempty"`
+	MXAddresses map[string][]net.IP `json:"mx_addresses,omitempty"`
+	ConnectedMX string              `json:"connected_mx,omitempty"`
+
+	// ConnectedAddress is the address and port the connection to ConnectedMX was made on, e.g. "192.0.2.1:25"
+	ConnectedAddress string `json:"connected_address,omitempty"`
+
+	SMTPReplies []SMTPReply `json:"smtp_replies,omitempty"`
+	Timings     Timings     `json:"timings,omitempty"`
+}
+
+// SMTPReply is a reply of a mail server, to a command sent by the recipient probe. Since net/smtp hides the exact code
+// of positive replies, those are recorded as 250.
+type SMTPReply struct {
+	Command string `json:"command"`
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// newSMTPReply creates a reply from the outcome of a command. It returns false when err isn't an SMTP reply, e.g. on
+// network errors.
+func newSMTPReply(command string, err error) (SMTPReply, bool) {
+	if err == nil {
+		return SMTPReply{Command: command, Code: 250}, true
+	}
+
+	var tpErr *textproto.Error
+	if !errors.As(err, &tpErr) {
+		return SMTPReply{}, false
+	}
+
+	return SMTPReply{Command: command, Code: tpErr.Code, Message: tpErr.Msg}, true
+}
+
+// newDiagnostics collects the evidence from the artifact and describes err, the error that stopped the check
+func newDiagnostics(a Artifact, err error) Diagnostics {
+	d := Diagnostics{
+		MXHosts:          a.mxFound,
+		MXAddresses:      a.mxAddresses,
+		ConnectedMX:      a.connectedMX,
+		ConnectedAddress: a.connectedAddress,
+		SMTPReplies:      a.smtpReplies,
+		Timings:          a.Timings,
+	}
+
+	if err == nil {
+		return d
+	}
+
+	d.Reason = ReasonUnknown
+	d.Detail = err.Error()
+
+	var vErr ValidationError
+	if errors.As(err, &vErr) {
+		d.FailedStep = vErr.Validator
+		d.Reason = reasonForValidationError(vErr)
+
+		if vErr.Internal != nil {
+			d.Detail = vErr.Internal.Error()
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(vErr.Internal, context.DeadlineExceeded) {
+		d.Reason = ReasonTimeout
+	}
+
+	return d
+}
+
+// reasonForValidationError maps the errors of the built-in steps to a Reason. Most steps return ErrEmailAddressSyntax
+// for historic reasons, so the step is leading.
+func reasonForValidationError(err ValidationError) Reason {
+	switch err.Validator {
+	case "checkEmailAddressSyntax", "checkDomainSyntax":
+		if errors.Is(err, ErrUnknownTLD) {
+			return ReasonUnknownTLD
+		}
+
+		return ReasonSyntax
+	case "checkIfDomainHasMX":
+		if errors.Is(err, ErrNullMX) {
+			return ReasonNullMX
+		}
+
+		return ReasonMXLookup
+	case "checkIfMXHasIP":
+		if errors.Is(err, ErrUnroutableMX) {
+			return ReasonMXUnroutable
+		}
+
+		return ReasonMXUnresolvable
+	case "checkMXAcceptsConnect":
+		return ReasonConnect
+	case "checkRCPT":
+		switch {
+		case errors.Is(err, ErrRCPTRejected):
+			return ReasonRCPTRejected
+		case errors.Is(err, ErrInvalidHost):
+			return ReasonNoConnection
+		}
+
+		return ReasonRCPTTemporary
+	}
+
+	// User-defined steps, see NewStep
+	return ReasonStepFailed
+}
@@ -0,0 +1,178 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/Dynom/ERI/types"
+)
+
+func Test_newDiagnostics(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantReason     Reason
+		wantFailedStep string
+		wantDetail     string
+	}{
+		{name: "no error", wantReason: ReasonNone},
+		{
+			name:           "syntax",
+			err:            ValidationError{Validator: "checkEmailAddressSyntax", Internal: errors.New("local part 'a b' has invalid syntax"), error: ErrEmailAddressSyntax},
+			wantReason:     ReasonSyntax,
+			wantFailedStep: "checkEmailAddressSyntax",
+			wantDetail:     "local part 'a b' has invalid syntax",
+		},
+		{
+			name:           "unknown TLD",
+			err:            ValidationError{Validator: "checkDomainSyntax", Internal: errors.New("top-level domain 'cmo' is unknown"), error: ErrUnknownTLD},
+			wantReason:     ReasonUnknownTLD,
+			wantFailedStep: "checkDomainSyntax",
+			wantDetail:     "top-level domain 'cmo' is unknown",
+		},
+		{
+			name:           "null MX",
+			err:            ValidationError{Validator: "checkIfDomainHasMX", Internal: ErrNullMX, error: ErrNullMX},
+			wantReason:     ReasonNullMX,
+			wantFailedStep: "checkIfDomainHasMX",
+			wantDetail:     ErrNullMX.Error(),
+		},
+		{
+			name:           "MX lookup, cached",
+			err:            ValidationError{Validator: "checkIfDomainHasMX", error: ErrEmailAddressSyntax},
+			wantReason:     ReasonMXLookup,
+			wantFailedStep: "checkIfDomainHasMX",
+			wantDetail:     ErrEmailAddressSyntax.Error(),
+		},
+		{
+			name:           "MX without IP",
+			err:            ValidationError{Validator: "checkIfMXHasIP", error: ErrEmailAddressSyntax},
+			wantReason:     ReasonMXUnresolvable,
+			wantFailedStep: "checkIfMXHasIP",
+			wantDetail:     ErrEmailAddressSyntax.Error(),
+		},
+		{
+			name:           "MX with unroutable IP",
+			err:            ValidationError{Validator: "checkIfMXHasIP", Internal: errors.New("MX host mx.example.org resolves to loopback address 127.0.0.1"), error: ErrUnroutableMX},
+			wantReason:     ReasonMXUnroutable,
+			wantFailedStep: "checkIfMXHasIP",
+			wantDetail:     "MX host mx.example.org resolves to loopback address 127.0.0.1",
+		},
+		{
+			name:           "connect",
+			err:            ValidationError{Validator: "checkMXAcceptsConnect", Internal: errors.New("dial tcp: i/o timeout"), error: ErrEmailAddressSyntax},
+			wantReason:     ReasonConnect,
+			wantFailedStep: "checkMXAcceptsConnect",
+			wantDetail:     "dial tcp: i/o timeout",
+		},
+		{
+			name:           "connect, timeout",
+			err:            ValidationError{Validator: "checkMXAcceptsConnect", Internal: fmt.Errorf("dial %w", context.DeadlineExceeded), error: ErrEmailAddressSyntax},
+			wantReason:     ReasonTimeout,
+			wantFailedStep: "checkMXAcceptsConnect",
+			wantDetail:     "dial context deadline exceeded",
+		},
+		{
+			name:           "recipient rejected",
+			err:            ValidationError{Validator: "checkRCPT", error: ErrRCPTRejected},
+			wantReason:     ReasonRCPTRejected,
+			wantFailedStep: "checkRCPT",
+			wantDetail:     ErrRCPTRejected.Error(),
+		},
+		{
+			name:           "recipient temporary",
+			err:            ValidationError{Validator: "checkRCPT", error: ErrRCPTTemporary},
+			wantReason:     ReasonRCPTTemporary,
+			wantFailedStep: "checkRCPT",
+			wantDetail:     ErrRCPTTemporary.Error(),
+		},
+		{
+			name:           "recipient, no connection",
+			err:            ValidationError{Validator: "checkRCPT", error: ErrInvalidHost},
+			wantReason:     ReasonNoConnection,
+			wantFailedStep: "checkRCPT",
+			wantDetail:     ErrInvalidHost.Error(),
+		},
+		{
+			name:           "user-defined step",
+			err:            ValidationError{Validator: "custom", error: ErrStepFailed},
+			wantReason:     ReasonStepFailed,
+			wantFailedStep: "custom",
+			wantDetail:     ErrStepFailed.Error(),
+		},
+		{
+			name:       "sequence deadline",
+			err:        context.DeadlineExceeded,
+			wantReason: ReasonTimeout,
+			wantDetail: context.DeadlineExceeded.Error(),
+		},
+		{
+			name:       "unknown",
+			err:        errors.New("b0rk"),
+			wantReason: ReasonUnknown,
+			wantDetail: "b0rk",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newDiagnostics(Artifact{}, tt.err)
+
+			if got.Reason != tt.wantReason {
+				t.Errorf("Expected reason %q, got %q", tt.wantReason, got.Reason)
+			}
+
+			if got.FailedStep != tt.wantFailedStep {
+				t.Errorf("Expected failed step %q, got %q", tt.wantFailedStep, got.FailedStep)
+			}
+
+			if got.Detail != tt.wantDetail {
+				t.Errorf("Expected detail %q, got %q", tt.wantDetail, got.Detail)
+			}
+		})
+	}
+}
+
+func TestEmailValidator_CheckWithLookup_diagnostics(t *testing.T) {
+	resolver := buildResolver([]string{"mx1.example.org", "mx2.example.org"}, map[string][]net.IPAddr{
+		"mx2.example.org": {{IP: net.ParseIP("192.0.2.1")}, {IP: net.ParseIP("2001:db8::1")}},
+	}, nil)
+
+	v := NewEmailAddressValidator(nil, withDocumentationMX)
+	r := v.CheckWithLookup(context.Background(), types.NewEmailFromParts("john", "example.org"), func(artifact *Artifact) {
+		artifact.resolver = resolver
+	})
+
+	if !r.Validations.IsValid() {
+		t.Fatalf("Expected the result to be valid, got %+v", r.Diagnostics)
+	}
+
+	d := r.Diagnostics
+	if d.Reason != ReasonNone || d.FailedStep != "" {
+		t.Errorf("Expected no reason, got %q in step %q", d.Reason, d.FailedStep)
+	}
+
+	if want := []string{"mx1.example.org", "mx2.example.org"}; !reflect.DeepEqual(d.MXHosts, want) {
+		t.Errorf("Expected MX hosts %v, got %v", want, d.MXHosts)
+	}
+
+	want := map[string][]net.IP{"mx2.example.org": {net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")}}
+	if !reflect.DeepEqual(d.MXAddresses, want) {
+		t.Errorf("Expected MX addresses %v, got %v", want, d.MXAddresses)
+	}
+
+	if len(d.Timings) == 0 {
+		t.Errorf("Expected timings to be collected")
+	}
+
+	for _, timing := range d.Timings {
+		if timing.Label == "" {
+			t.Errorf("Expected only labeled timings, got %+v", d.Timings)
+			break
+		}
+	}
+}
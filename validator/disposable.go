@@ -0,0 +1,131 @@
+package validator
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+)
+
+// disposableMXPrefix marks an entry in a disposable list as an MX host, instead of a domain
+const disposableMXPrefix = "mx:"
+
+// NewDisposableList creates a list of disposable domains and the MX hosts that serve them. Entries are matched
+// case-insensitive and include their sub-domains, e.g.: "mailinator.com" also matches "eu.mailinator.com"
+func NewDisposableList(domains, mxHosts []string) *DisposableList {
+	l := &DisposableList{}
+	l.Replace(domains, mxHosts)
+
+	return l
+}
+
+// DisposableList holds disposable (throw-away) domains and MX hosts. It's safe for concurrent use and can be updated
+// at runtime.
+type DisposableList struct {
+	lock    sync.RWMutex
+	domains map[string]struct{}
+	mxHosts map[string]struct{}
+}
+
+// Replace atomically swaps the content of the list with the arguments
+func (l *DisposableList) Replace(domains, mxHosts []string) {
+	d := toLookupSet(domains)
+	mx := toLookupSet(mxHosts)
+
+	l.lock.Lock()
+	l.domains = d
+	l.mxHosts = mx
+	l.lock.Unlock()
+}
+
+// Load replaces the content of the list with what is read from r. It expects one entry per line, MX hosts are
+// prefixed with "mx:". Empty lines and lines starting with a "#" are ignored.
+func (l *DisposableList) Load(r io.Reader) error {
+	var domains, mxHosts []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, disposableMXPrefix) {
+			mxHosts = append(mxHosts, strings.TrimSpace(line[len(disposableMXPrefix):]))
+			continue
+		}
+
+		domains = append(domains, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	l.Replace(domains, mxHosts)
+	return nil
+}
+
+// Len returns the number of domains and MX hosts on the list
+func (l *DisposableList) Len() (domains, mxHosts int) {
+	l.lock.RLock()
+	domains, mxHosts = len(l.domains), len(l.mxHosts)
+	l.lock.RUnlock()
+
+	return
+}
+
+// HasDomain returns true if the domain, or one of its parent domains, is listed as disposable
+func (l *DisposableList) HasDomain(domain string) bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return hasDomainOrParent(l.domains, domain)
+}
+
+// HasMXHost returns true if the MX host, or one of its parent domains, is listed as serving disposable domains
+func (l *DisposableList) HasMXHost(host string) bool {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return hasDomainOrParent(l.mxHosts, host)
+}
+
+// hasDomainOrParent walks up the labels of domain and returns true on the first match in set
+func hasDomainOrParent(set map[string]struct{}, domain string) bool {
+	if len(set) == 0 {
+		return false
+	}
+
+	domain = normalizeListEntry(domain)
+	for domain != "" {
+		if _, ok := set[domain]; ok {
+			return true
+		}
+
+		i := strings.IndexByte(domain, '.')
+		if i == -1 {
+			break
+		}
+
+		domain = domain[i+1:]
+	}
+
+	return false
+}
+
+func toLookupSet(entries []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		if e = normalizeListEntry(e); e != "" {
+			set[e] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// normalizeListEntry lower-cases and removes the trailing root label, as commonly found on MX records
+func normalizeListEntry(e string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(e)), ".")
+}
@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisposableList_Load(t *testing.T) {
+	input := `
+# Comments and empty lines are ignored
+mailinator.com
+  Guerrillamail.com
+mx:mail.mailinator.com.
+`
+
+	l := NewDisposableList(nil, nil)
+	if err := l.Load(strings.NewReader(input)); err != nil {
+		t.Errorf("Load() unexpected error %s", err)
+		t.FailNow()
+	}
+
+	if domains, mxHosts := l.Len(); domains != 2 || mxHosts != 1 {
+		t.Errorf("Len() = %d, %d, want 2, 1", domains, mxHosts)
+	}
+
+	if !l.HasDomain("guerrillamail.com") {
+		t.Errorf("Expected the domain to be normalised while loading")
+	}
+
+	if !l.HasMXHost("MAIL.mailinator.com") {
+		t.Errorf("Expected the MX host to be normalised while loading")
+	}
+
+	// Loading replaces the entire list
+	if err := l.Load(strings.NewReader("example.org")); err != nil {
+		t.Errorf("Load() unexpected error %s", err)
+	}
+
+	if l.HasDomain("mailinator.com") {
+		t.Errorf("Expected the previous entries to be replaced")
+	}
+}
+
+func TestDisposableList_HasDomain(t *testing.T) {
+	l := NewDisposableList([]string{"mailinator.com", "grr.la"}, []string{"mx.trap.example.org"})
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{want: true, domain: "mailinator.com"},
+		{want: true, domain: "MailInator.com"},
+		{want: true, domain: "eu.mailinator.com"},
+		{want: true, domain: "grr.la."},
+
+		{domain: "example.org"},
+		{domain: "notmailinator.com"},
+		{domain: "com"},
+		{domain: ""},
+
+		// MX hosts aren't domains
+		{domain: "mx.trap.example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := l.HasDomain(tt.domain); got != tt.want {
+				t.Errorf("HasDomain(%q) = %t, want %t", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisposableList_HasMXHost(t *testing.T) {
+	l := NewDisposableList(nil, []string{"mailinator.com"})
+
+	if !l.HasMXHost("mail2.mailinator.com.") {
+		t.Errorf("Expected the MX host to match on its parent domain")
+	}
+
+	if l.HasMXHost("mx.example.org.") {
+		t.Errorf("Expected the MX host not to match")
+	}
+}
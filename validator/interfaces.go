@@ -9,6 +9,27 @@ type LookupMX interface {
 	LookupMX(ctx context.Context, domain string) ([]*net.MX, error)
 }
 
+// LookupIPAddr resolves the A and AAAA records of a host
+type LookupIPAddr interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Resolver combines the DNS lookups the validator performs. It's satisfied by *net.Resolver
+type Resolver interface {
+	LookupMX
+	LookupIPAddr
+}
+
+// LookupTXT resolves the TXT records of a name, it's satisfied by *net.Resolver
+type LookupTXT interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// LookupTLSA resolves the TLSA records of a name in presentation format, see resolver.Records
+type LookupTLSA interface {
+	LookupTLSA(ctx context.Context, name string) ([]string, error)
+}
+
 type DialContext interface {
 	DialContext(ctx context.Context, network, address string) (net.Conn, error)
 }
@@ -0,0 +1,163 @@
+package validator
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IPClass describes the kind of network an address belongs to. Mail can't be delivered to most of them, from the
+// perspective of the Internet.
+type IPClass uint8
+
+// IPPublic is the class of addresses that don't belong to any of the other classes
+const IPPublic IPClass = 0
+
+const (
+	IPLoopback      IPClass = 1 << iota // 127.0.0.0/8 and ::1
+	IPPrivate                           // RFC 1918 and RFC 4193 (fc00::/7)
+	IPLinkLocal                         // 169.254.0.0/16 and fe80::/10
+	IPDocumentation                     // RFC 5737 and RFC 3849 (2001:db8::/32)
+	IPUnspecified                       // 0.0.0.0 and ::
+	IPSinkhole                          // See MXAddressConfig.Sinkholes
+)
+
+var ipClassNames = []struct {
+	class IPClass
+	name  string
+}{
+	{IPLoopback, "loopback"},
+	{IPPrivate, "private"},
+	{IPLinkLocal, "link_local"},
+	{IPDocumentation, "documentation"},
+	{IPUnspecified, "unspecified"},
+	{IPSinkhole, "sinkhole"},
+}
+
+// documentationNets are the ranges reserved for use in examples
+var documentationNets = mustParseCIDRs("192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32")
+
+func (c IPClass) String() string {
+	if c == IPPublic {
+		return "public"
+	}
+
+	var names []string
+	for _, n := range ipClassNames {
+		if c&n.class != 0 {
+			names = append(names, n.name)
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+// ParseIPClasses returns the classes by their name, e.g.: "loopback" or "link_local"
+func ParseIPClasses(names []string) (IPClass, error) {
+	var classes IPClass
+
+next:
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, n := range ipClassNames {
+			if n.name == name {
+				classes |= n.class
+				continue next
+			}
+		}
+
+		return 0, fmt.Errorf("unknown class of IP address %q", name)
+	}
+
+	return classes, nil
+}
+
+// ParseSinkholes parses IP addresses (e.g.: "192.0.2.1") and networks in CIDR notation (e.g.: "192.0.2.0/24")
+func ParseSinkholes(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid sinkhole address %q", entry)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sinkhole network %w", err)
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// MXAddressConfig defines which addresses of MX hosts StepMXHasIP accepts. By default, only public addresses are
+// accepted. A domain of which none of the MX hosts has an acceptable address fails with ReasonMXUnroutable.
+type MXAddressConfig struct {
+	// Allow are the classes of addresses that are accepted, besides public addresses. E.g. IPPrivate, when checking
+	// addresses of an internal network.
+	Allow IPClass
+
+	// Sinkholes are networks known to absorb traffic of blocked or seized domains
+	Sinkholes []*net.IPNet
+}
+
+// classify returns the class of ip
+func (c MXAddressConfig) classify(ip net.IP) IPClass {
+	for _, n := range c.Sinkholes {
+		if n.Contains(ip) {
+			return IPSinkhole
+		}
+	}
+
+	switch {
+	case ip.IsLoopback():
+		return IPLoopback
+	case ip.IsPrivate():
+		return IPPrivate
+	case ip.IsLinkLocalUnicast():
+		return IPLinkLocal
+	case ip.IsUnspecified():
+		return IPUnspecified
+	}
+
+	for _, n := range documentationNets {
+		if n.Contains(ip) {
+			return IPDocumentation
+		}
+	}
+
+	return IPPublic
+}
+
+// accepts returns the class of ip and whether mail can be delivered to it
+func (c MXAddressConfig) accepts(ip net.IP) (IPClass, bool) {
+	class := c.classify(ip)
+	return class, class == IPPublic || c.Allow&class != 0
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		nets[i] = n
+	}
+
+	return nets
+}
@@ -0,0 +1,141 @@
+package validator
+
+import (
+	"net"
+	"testing"
+)
+
+func TestMXAddressConfig_classify(t *testing.T) {
+	sinkholes, err := ParseSinkholes([]string{"198.51.100.0/24", "2001:db8::53"})
+	if err != nil {
+		t.Fatalf("Unable to parse the sinkholes %s", err)
+	}
+
+	conf := MXAddressConfig{Sinkholes: sinkholes}
+
+	tests := []struct {
+		ip   string
+		want IPClass
+	}{
+		{ip: "8.8.8.8", want: IPPublic},
+		{ip: "2a00:1450:4001::1b", want: IPPublic},
+		{ip: "127.0.0.1", want: IPLoopback},
+		{ip: "127.1.2.3", want: IPLoopback},
+		{ip: "::1", want: IPLoopback},
+		{ip: "10.0.0.25", want: IPPrivate},
+		{ip: "172.16.0.25", want: IPPrivate},
+		{ip: "192.168.1.25", want: IPPrivate},
+		{ip: "fd00::25", want: IPPrivate},
+		{ip: "169.254.0.25", want: IPLinkLocal},
+		{ip: "fe80::25", want: IPLinkLocal},
+		{ip: "192.0.2.25", want: IPDocumentation},
+		{ip: "203.0.113.25", want: IPDocumentation},
+		{ip: "2001:db8::25", want: IPDocumentation},
+		{ip: "0.0.0.0", want: IPUnspecified},
+		{ip: "::", want: IPUnspecified},
+		{ip: "198.51.100.25", want: IPSinkhole},
+		{ip: "2001:db8::53", want: IPSinkhole},
+		{ip: "::ffff:127.0.0.1", want: IPLoopback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := conf.classify(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("Expected %s to be %s, got %s", tt.ip, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMXAddressConfig_accepts(t *testing.T) {
+	conf := MXAddressConfig{Allow: IPPrivate | IPLoopback}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "8.8.8.8", want: true},
+		{ip: "10.0.0.25", want: true},
+		{ip: "127.0.0.1", want: true},
+		{ip: "169.254.0.25", want: false},
+		{ip: "192.0.2.25", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if _, got := conf.accepts(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("Expected %s to be accepted: %t, got %t", tt.ip, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseIPClasses(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    IPClass
+		wantErr bool
+	}{
+		{name: "none", want: IPPublic},
+		{name: "single", names: []string{"private"}, want: IPPrivate},
+		{name: "multiple", names: []string{"Loopback", " link_local"}, want: IPLoopback | IPLinkLocal},
+		{name: "unknown", names: []string{"private", "intranet"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIPClasses(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIPClasses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseSinkholes(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "addresses", entries: []string{"192.0.2.1", "2001:db8::1"}, want: []string{"192.0.2.1/32", "2001:db8::1/128"}},
+		{name: "networks", entries: []string{"192.0.2.0/24", " 2001:db8::/32"}, want: []string{"192.0.2.0/24", "2001:db8::/32"}},
+		{name: "invalid address", entries: []string{"192.0.2"}, wantErr: true},
+		{name: "invalid network", entries: []string{"192.0.2.0/33"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSinkholes(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSinkholes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+
+			for i, n := range got {
+				if n.String() != tt.want[i] {
+					t.Errorf("Expected %s, got %s", tt.want[i], n)
+				}
+			}
+		})
+	}
+}
+
+func TestIPClass_String(t *testing.T) {
+	if got := IPPublic.String(); got != "public" {
+		t.Errorf("Expected public, got %q", got)
+	}
+
+	if got := (IPLoopback | IPSinkhole).String(); got != "loopback,sinkhole" {
+		t.Errorf("Expected loopback,sinkhole, got %q", got)
+	}
+}
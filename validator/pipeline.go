@@ -0,0 +1,274 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net"
+	"time"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+// ErrStepFailed is returned for a user-defined step that failed on a previous run, see NewStep
+var ErrStepFailed = errors.New("validation step failed")
+
+// StepFn performs a single validation. Returning nil means the validation passed.
+type StepFn func(a *Artifact) error
+
+// Step is a single, named, validation in a Pipeline. Each step records its outcome under its own flag.
+type Step struct {
+	Name string
+	Flag validations.Flag
+
+	fn      stateFn
+	builtin bool
+}
+
+// NewStep creates a user-defined step. The Flag should be unique within a pipeline and must be a single flag at or
+// above validations.FUserDefined, NewStep panics otherwise since it would collide with the (reserved) built-in flags.
+//
+// When the artifact's Steps already contain flag (e.g. from a cached result), fn isn't called and the outcome of the
+// previous run is used instead. Otherwise, fn is called and the step is recorded. A nil error marks the Validations
+// with flag, an error stops the pipeline.
+func NewStep(name string, flag validations.Flag, fn StepFn) Step {
+	if flag < validations.FUserDefined || bits.OnesCount64(uint64(flag)) != 1 {
+		panic(fmt.Sprintf("validator: step %q must use a single flag at or above FUserDefined, got %#x", name, uint64(flag)))
+	}
+
+	return Step{
+		Name: name,
+		Flag: flag,
+		fn: func(a *Artifact) error {
+			if a.Steps.HasFlag(flag) {
+				if !a.Validations.HasFlag(flag) {
+					return ValidationError{Validator: name, error: ErrStepFailed}
+				}
+
+				return nil
+			}
+
+			a.Steps.SetFlag(flag)
+
+			start := time.Now()
+			err := fn(a)
+			a.Timings.Add(name, time.Since(start))
+
+			if err != nil {
+				var vErr ValidationError
+				if errors.As(err, &vErr) {
+					return err
+				}
+
+				return ValidationError{Validator: name, error: err}
+			}
+
+			a.Validations.SetFlag(flag)
+			return nil
+		},
+	}
+}
+
+// The built-in steps, they manage their own Steps and Validations flags and honour results from previous runs.
+var (
+	// StepSyntax checks the syntax of the address, or of the domain when no local part is present. It always runs.
+	StepSyntax = builtinStep("syntax", validations.FSyntax, checkSyntax)
+
+	// StepRoleAccount marks local parts of role or system accounts, it never fails. See WithRoleAccounts
+	StepRoleAccount = builtinStep("roleAccount", validations.FRoleAccount, checkIfRoleAccount)
+
+	// StepMXLookup looks up the MX hosts of the domain
+	StepMXLookup = builtinStep("mxLookup", validations.FMXLookup, checkIfDomainHasMX)
+
+	// StepDisposable marks disposable domains, it never fails. See WithDisposableList
+	StepDisposable = builtinStep("disposable", validations.FDisposable, checkIfDisposable)
+
+	// StepMXHasIP resolves the MX hosts, expects to run after StepMXLookup
+	StepMXHasIP = builtinStep("mxHasIP", validations.FMXDomainHasIP, checkIfMXHasIP)
+
+	// StepConnect connects to one of the MX hosts, expects to run after StepMXHasIP
+	StepConnect = builtinStep("connect", validations.FHostConnect, checkMXAcceptsConnect)
+
+	// StepPosture looks up the SPF, DMARC, MTA-STS and DANE records of the domain, it never fails. It's not part of the
+	// default pipelines and expects to run after StepMXHasIP. See WithPostureConfig and Result.Posture
+	StepPosture = builtinStep("posture", validations.FPosture, checkPosture)
+
+	// StepRCPT probes the recipient, expects to run after StepConnect. See WithProbeConfig
+	StepRCPT = builtinStep("rcpt", validations.FValidRCPT, checkRCPT)
+)
+
+func builtinStep(name string, flag validations.Flag, fn stateFn) Step {
+	return Step{
+		Name:    name,
+		Flag:    flag,
+		fn:      fn,
+		builtin: true,
+	}
+}
+
+// NewPipeline creates a pipeline that runs the steps in order, stopping at the first failing step
+func NewPipeline(steps ...Step) Pipeline {
+	return Pipeline{
+		steps: append([]Step(nil), steps...),
+	}
+}
+
+// Pipeline is an ordered list of steps. It's immutable, every method returns a modified copy.
+type Pipeline struct {
+	steps []Step
+}
+
+// SyntaxPipeline returns the steps used by CheckWithSyntax
+func SyntaxPipeline() Pipeline {
+	return NewPipeline(StepSyntax, StepRoleAccount, StepDisposable)
+}
+
+// LookupPipeline returns the steps used by CheckWithLookup
+func LookupPipeline() Pipeline {
+	return NewPipeline(StepSyntax, StepRoleAccount, StepMXLookup, StepDisposable, StepMXHasIP)
+}
+
+// ConnectPipeline returns the steps used by CheckWithConnect
+func ConnectPipeline() Pipeline {
+	return LookupPipeline().Append(StepConnect)
+}
+
+// RCPTPipeline returns the steps used by CheckWithRCPT
+func RCPTPipeline() Pipeline {
+	return ConnectPipeline().Append(StepRCPT)
+}
+
+// Steps returns a copy of the steps in the pipeline
+func (p Pipeline) Steps() []Step {
+	return append([]Step(nil), p.steps...)
+}
+
+// Append adds steps to the end of the pipeline
+func (p Pipeline) Append(steps ...Step) Pipeline {
+	return p.insert(len(p.steps), steps)
+}
+
+// InsertBefore adds steps before the first step with flag. When no such step exists, they're appended.
+func (p Pipeline) InsertBefore(flag validations.Flag, steps ...Step) Pipeline {
+	i := p.indexOf(flag)
+	if i == -1 {
+		i = len(p.steps)
+	}
+
+	return p.insert(i, steps)
+}
+
+// InsertAfter adds steps after the first step with flag. When no such step exists, they're appended.
+func (p Pipeline) InsertAfter(flag validations.Flag, steps ...Step) Pipeline {
+	i := p.indexOf(flag)
+	if i == -1 {
+		i = len(p.steps) - 1
+	}
+
+	return p.insert(i+1, steps)
+}
+
+// Without removes all steps with flag
+func (p Pipeline) Without(flag validations.Flag) Pipeline {
+	steps := make([]Step, 0, len(p.steps))
+	for _, s := range p.steps {
+		if s.Flag != flag {
+			steps = append(steps, s)
+		}
+	}
+
+	return Pipeline{steps: steps}
+}
+
+func (p Pipeline) insert(i int, steps []Step) Pipeline {
+	result := make([]Step, 0, len(p.steps)+len(steps))
+	result = append(result, p.steps[:i]...)
+	result = append(result, steps...)
+	result = append(result, p.steps[i:]...)
+
+	return Pipeline{steps: result}
+}
+
+func (p Pipeline) indexOf(flag validations.Flag) int {
+	for i, s := range p.steps {
+		if s.Flag == flag {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (p Pipeline) sequence() []stateFn {
+	sequence := make([]stateFn, 0, len(p.steps))
+	for _, s := range p.steps {
+		if s.fn != nil {
+			sequence = append(sequence, s.fn)
+		}
+	}
+
+	return sequence
+}
+
+// CheckWithPipeline returns a CheckFn that runs the steps of p, using the validator's configuration
+func (v *EmailValidator) CheckWithPipeline(p Pipeline) CheckFn {
+	sequence := p.sequence()
+	return func(ctx context.Context, emailParts types.EmailParts, options ...ArtifactFn) Result {
+		_, result := v.run(ctx, sequence, emailParts, options)
+		return result
+	}
+}
+
+// run validates emailParts with sequence and returns the resulting artifact, next to the result
+func (v *EmailValidator) run(ctx context.Context, sequence []stateFn, emailParts types.EmailParts, options []ArtifactFn) (Artifact, Result) {
+	artifact, err := validateSequence(ctx,
+		getNewArtifact(ctx, emailParts, v.artifactOptions(options)...),
+		sequence,
+	)
+
+	result := createResult(artifact)
+	result.Diagnostics = newDiagnostics(artifact, err)
+
+	return artifact, result
+}
+
+// checkSyntax checks the domain only, when the local part is missing and otherwise checks the full address
+func checkSyntax(a *Artifact) error {
+	if a.email.Local == "" {
+		return checkDomainSyntax(a)
+	}
+
+	return checkEmailAddressSyntax(a)
+}
+
+// Context returns the context of the check, steps should abort when it's done
+func (a *Artifact) Context() context.Context {
+	return a.ctx
+}
+
+// Email returns the address being checked
+func (a *Artifact) Email() types.EmailParts {
+	return a.email
+}
+
+// Resolver returns the resolver used for DNS lookups
+func (a *Artifact) Resolver() Resolver {
+	return a.resolver
+}
+
+// Dialer returns the dialer used to connect to MX hosts
+func (a *Artifact) Dialer() DialContext {
+	return a.dialer
+}
+
+// MX returns a copy of the MX hosts found by StepMXLookup. Hosts that failed to resolve in StepMXHasIP are empty.
+func (a *Artifact) MX() []string {
+	return append([]string(nil), a.mx...)
+}
+
+// Conn returns the connection to the MX host made by StepConnect, or nil when there is none
+func (a *Artifact) Conn() net.Conn {
+	return a.conn
+}
@@ -0,0 +1,254 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+const (
+	fCustomA = validations.FUserDefined << iota
+	fCustomB
+)
+
+func stepNames(p Pipeline) []string {
+	var names []string
+	for _, s := range p.Steps() {
+		names = append(names, s.Name)
+	}
+
+	return names
+}
+
+func TestPipeline_builder(t *testing.T) {
+	custom := NewStep("custom", fCustomA, func(a *Artifact) error { return nil })
+	base := NewPipeline(StepSyntax, StepMXLookup, StepMXHasIP)
+
+	tests := []struct {
+		name string
+		p    Pipeline
+		want []string
+	}{
+		{name: "append", p: base.Append(custom), want: []string{"syntax", "mxLookup", "mxHasIP", "custom"}},
+		{name: "insert before", p: base.InsertBefore(validations.FMXLookup, custom), want: []string{"syntax", "custom", "mxLookup", "mxHasIP"}},
+		{name: "insert after", p: base.InsertAfter(validations.FMXLookup, custom), want: []string{"syntax", "mxLookup", "custom", "mxHasIP"}},
+		{name: "insert before unknown", p: base.InsertBefore(validations.FHostConnect, custom), want: []string{"syntax", "mxLookup", "mxHasIP", "custom"}},
+		{name: "insert after unknown", p: base.InsertAfter(validations.FHostConnect, custom), want: []string{"syntax", "mxLookup", "mxHasIP", "custom"}},
+		{name: "without", p: base.Without(validations.FMXLookup), want: []string{"syntax", "mxHasIP"}},
+		{name: "base is unchanged", p: base, want: []string{"syntax", "mxLookup", "mxHasIP"}},
+		{name: "rcpt", p: RCPTPipeline(), want: []string{"syntax", "roleAccount", "mxLookup", "disposable", "mxHasIP", "connect", "rcpt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stepNames(tt.p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Steps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStep_InvalidFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		flag validations.Flag
+	}{
+		{name: "zero"},
+		{name: "built-in", flag: validations.FSyntax},
+		{name: "reserved", flag: 1 << 12},
+		{name: "multiple", flag: fCustomA | fCustomB},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected NewStep to panic on flag %#x", uint64(tt.flag))
+				}
+			}()
+
+			NewStep("custom", tt.flag, func(a *Artifact) error { return nil })
+		})
+	}
+}
+
+func TestNewStep(t *testing.T) {
+	errCustom := errors.New("custom failure")
+
+	tests := []struct {
+		name        string
+		steps       validations.Steps
+		validations validations.Validations
+		fnErr       error
+		wantCalled  bool
+		wantErr     error
+		wantValid   bool
+	}{
+		{name: "passes", wantCalled: true, wantValid: true},
+		{name: "fails", fnErr: errCustom, wantCalled: true, wantErr: errCustom},
+		{name: "passed on a previous run", steps: validations.Steps(fCustomA), validations: validations.Validations(fCustomA), wantValid: true},
+		{name: "failed on a previous run", steps: validations.Steps(fCustomA), wantErr: ErrStepFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			step := NewStep("custom", fCustomA, func(a *Artifact) error {
+				called = true
+				return tt.fnErr
+			})
+
+			a := &Artifact{
+				Steps:       tt.steps,
+				Validations: tt.validations,
+			}
+
+			err := step.fn(a)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+
+			var vErr ValidationError
+			if err != nil && (!errors.As(err, &vErr) || vErr.Validator != "custom") {
+				t.Errorf("Expected a ValidationError from validator %q, got %#v", "custom", err)
+			}
+
+			if called != tt.wantCalled {
+				t.Errorf("Expected the step to be called: %t, got: %t", tt.wantCalled, called)
+			}
+
+			if !a.Steps.HasFlag(fCustomA) {
+				t.Errorf("Expected the step to be defined")
+			}
+
+			if got := a.Validations.HasFlag(fCustomA); got != tt.wantValid {
+				t.Errorf("Expected the validation to be defined: %t, got: %t", tt.wantValid, got)
+			}
+		})
+	}
+}
+
+func TestEmailValidator_CheckWithPipeline(t *testing.T) {
+	parts := types.NewEmailFromParts("john", "example.org")
+	resolver := buildResolver([]string{"mx.example.org"}, map[string][]net.IPAddr{
+		"mx.example.org": {{IP: net.ParseIP("192.0.2.1")}},
+	}, nil)
+
+	withResolver := func(artifact *Artifact) {
+		artifact.resolver = resolver
+	}
+
+	t.Run("custom steps see the artifact", func(t *testing.T) {
+		var seenMX []string
+		var seenEmail types.EmailParts
+		var seenResolver Resolver
+
+		p := LookupPipeline().InsertAfter(validations.FMXLookup, NewStep("inspect", fCustomA, func(a *Artifact) error {
+			seenMX = a.MX()
+			seenEmail = a.Email()
+			seenResolver = a.Resolver()
+
+			if a.Context() == nil || a.Dialer() == nil {
+				return errors.New("expected a context and dialer")
+			}
+
+			return nil
+		}))
+
+		v := NewEmailAddressValidator(nil, withDocumentationMX)
+		r := v.CheckWithPipeline(p)(context.Background(), parts, withResolver)
+
+		if !r.Validations.IsValid() {
+			t.Errorf("Expected the result to be valid, got validations: %s steps: %s", r.Validations, r.Steps)
+		}
+
+		if !r.Validations.HasFlag(fCustomA) || !r.Steps.HasFlag(fCustomA) {
+			t.Errorf("Expected the custom flag to be set")
+		}
+
+		if !reflect.DeepEqual(seenMX, []string{"mx.example.org"}) {
+			t.Errorf("Expected the MX hosts to be available, got %v", seenMX)
+		}
+
+		if seenEmail != parts {
+			t.Errorf("Expected the email %+v, got %+v", parts, seenEmail)
+		}
+
+		if !reflect.DeepEqual(seenResolver, resolver) {
+			t.Errorf("Expected the configured resolver")
+		}
+	})
+
+	t.Run("failing step halts the pipeline", func(t *testing.T) {
+		var calledAfter bool
+		p := SyntaxPipeline().Append(
+			NewStep("fail", fCustomA, func(a *Artifact) error {
+				return errors.New("nope")
+			}),
+			NewStep("after", fCustomB, func(a *Artifact) error {
+				calledAfter = true
+				return nil
+			}),
+		)
+
+		v := NewEmailAddressValidator(nil)
+		r := v.CheckWithPipeline(p)(context.Background(), parts)
+
+		if r.Validations.IsValid() {
+			t.Errorf("Expected the result to be invalid")
+		}
+
+		if calledAfter {
+			t.Errorf("Expected steps after a failure to be skipped")
+		}
+
+		if !r.Steps.HasFlag(fCustomA) || r.Validations.HasFlag(fCustomA) {
+			t.Errorf("Expected the failing step to be recorded as failed, got validations: %s steps: %s", r.Validations, r.Steps)
+		}
+	})
+
+	t.Run("custom steps are rendered", func(t *testing.T) {
+		p := SyntaxPipeline().Append(
+			NewStep("a", fCustomA, func(a *Artifact) error { return nil }),
+			NewStep("b", fCustomB, func(a *Artifact) error { return errors.New("nope") }),
+		)
+
+		v := NewEmailAddressValidator(nil)
+		r := v.CheckWithPipeline(p)(context.Background(), parts)
+
+		if got, want := validations.Flag(r.Steps).AsStringSlice(), []string{"syntax", "roleAccount", "user:0", "user:1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected the steps %v, got %v", want, got)
+		}
+
+		if got, want := validations.Flag(r.Validations).AsStringSlice(), []string{"syntax", "user:0"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected the validations %v, got %v", want, got)
+		}
+	})
+
+	t.Run("cached steps are skipped", func(t *testing.T) {
+		var called bool
+		p := SyntaxPipeline().Append(NewStep("cached", fCustomA, func(a *Artifact) error {
+			called = true
+			return nil
+		}))
+
+		v := NewEmailAddressValidator(nil)
+		r := v.CheckWithPipeline(p)(context.Background(), parts, func(artifact *Artifact) {
+			artifact.Steps.SetFlag(fCustomA)
+			artifact.Validations.SetFlag(fCustomA)
+		})
+
+		if called {
+			t.Errorf("Expected the cached step to be skipped")
+		}
+
+		if !r.Validations.IsValid() {
+			t.Errorf("Expected the result to be valid")
+		}
+	})
+}
@@ -0,0 +1,391 @@
+package validator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+// mtaSTSPolicyMaxSize limits the size of an MTA-STS policy, real policies are a few hundred bytes
+const mtaSTSPolicyMaxSize = 64 * 1024
+
+// ErrNoTLSALookup is reported for DANE, when the resolver can't look up TLSA records
+var ErrNoTLSALookup = errors.New("resolver does not support TLSA lookups")
+
+// PostureConfig defines how StepPosture looks up the mail-authentication posture of a domain
+type PostureConfig struct {
+	// Resolver looks up the TXT records and, when it implements LookupTLSA, the TLSA records of the MX hosts. Defaults
+	// to the resolver of the dialer, which can't look up TLSA records. See resolver.NewRecords
+	Resolver LookupTXT
+
+	// HTTPClient fetches MTA-STS policies, defaults to a client that connects with the dialer of the validator.
+	// Redirects are never followed (RFC 8461 §3.3).
+	HTTPClient *http.Client
+}
+
+func (c PostureConfig) withDefaults(dialer *net.Dialer) PostureConfig {
+	if c.Resolver == nil {
+		c.Resolver = dialer.Resolver
+	}
+
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true,
+		},
+	}
+
+	if c.HTTPClient != nil {
+		client = *c.HTTPClient
+	}
+
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	c.HTTPClient = &client
+	return c
+}
+
+// Posture describes how a domain authenticates its mail. Domains in active use tend to publish these records, parked
+// domains tend not to. A record that doesn't exist is empty, other failures are described by Error.
+type Posture struct {
+	SPF    SPFPosture    `json:"spf"`
+	DMARC  DMARCPosture  `json:"dmarc"`
+	MTASTS MTASTSPosture `json:"mta_sts"`
+	DANE   DANEPosture   `json:"dane"`
+}
+
+// SPFPosture is the SPF record of the domain (RFC 7208)
+type SPFPosture struct {
+	Record string `json:"record,omitempty"`
+
+	// All is the "all" mechanism with its qualifier, e.g. "-all" (fail) or "~all" (soft fail), empty when absent
+	All   string `json:"all,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// DMARCPosture is the DMARC record of the domain (RFC 7489). The organizational domain isn't consulted.
+type DMARCPosture struct {
+	Record string `json:"record,omitempty"`
+
+	// Policy is how receivers should treat mail that fails authentication: "none", "quarantine" or "reject"
+	Policy          string `json:"policy,omitempty"`
+	SubdomainPolicy string `json:"subdomain_policy,omitempty"`
+
+	// Percentage is the share of the failing mail the policy applies to
+	Percentage int    `json:"percentage,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MTASTSPosture is the MTA-STS record and policy of the domain (RFC 8461)
+type MTASTSPosture struct {
+	Record string `json:"record,omitempty"`
+	ID     string `json:"id,omitempty"`
+
+	// Mode is "enforce", "testing" or "none". Mode, MX and MaxAge are empty when the policy couldn't be fetched.
+	Mode   string   `json:"mode,omitempty"`
+	MX     []string `json:"mx,omitempty"`
+	MaxAge int64    `json:"max_age,omitempty"` // In seconds
+	Error  string   `json:"error,omitempty"`
+}
+
+// DANEPosture holds the TLSA records (RFC 7672) of the MX hosts, in presentation format. The records aren't validated
+// with DNSSEC, a validating resolver is expected to take care of that.
+type DANEPosture struct {
+	TLSA  map[string][]string `json:"tlsa,omitempty"`
+	Error string              `json:"error,omitempty"`
+}
+
+// complete returns true when none of the lookups failed
+func (p *Posture) complete() bool {
+	return p.SPF.Error == "" && p.DMARC.Error == "" && p.MTASTS.Error == "" && p.DANE.Error == ""
+}
+
+// checkPosture looks up the SPF, DMARC and MTA-STS records of the domain and the TLSA records of its MX hosts. It's
+// informational and never fails, the Validations are marked with FPosture when all lookups succeeded. The TLSA records
+// are only looked up for the MX hosts that resolved, so it's expected to run after checkIfMXHasIP(). When the MX hosts
+// weren't looked up, e.g. because the result of the domain came from a cache, they're looked up here.
+func checkPosture(a *Artifact) error {
+	if a.Steps.HasFlag(validations.FPosture) || a.isAddressLiteral() {
+		return nil
+	}
+
+	a.Steps.SetFlag(validations.FPosture)
+
+	start := time.Now()
+	defer func() {
+		a.Timings.Add("checkPosture", time.Since(start))
+	}()
+
+	conf := a.postureConfig
+	if conf.Resolver == nil || conf.HTTPClient == nil {
+		// Artifacts that weren't created by a validator
+		conf = conf.withDefaults(&net.Dialer{Resolver: net.DefaultResolver})
+	}
+
+	domain := a.email.CanonicalDomain()
+
+	var p Posture
+	var wg sync.WaitGroup
+	wg.Add(4)
+
+	go func() {
+		defer wg.Done()
+		p.SPF = lookupSPF(a.ctx, conf.Resolver, domain)
+	}()
+
+	go func() {
+		defer wg.Done()
+		p.DMARC = lookupDMARC(a.ctx, conf.Resolver, domain)
+	}()
+
+	go func() {
+		defer wg.Done()
+		p.MTASTS = lookupMTASTS(a.ctx, conf, domain)
+	}()
+
+	go func() {
+		defer wg.Done()
+		mx, err := postureMX(a)
+		if err != nil {
+			p.DANE = DANEPosture{Error: err.Error()}
+			return
+		}
+
+		p.DANE = lookupDANE(a.ctx, conf.Resolver, mx)
+	}()
+
+	wg.Wait()
+
+	a.posture = &p
+	if p.complete() {
+		a.Validations.SetFlag(validations.FPosture)
+	}
+
+	return nil
+}
+
+// postureMX returns the MX hosts of the artifact, or looks them up when the artifact has none
+func postureMX(a *Artifact) ([]string, error) {
+	if len(a.mx) > 0 {
+		return a.mx, nil
+	}
+
+	var resolver Resolver = net.DefaultResolver
+	if a.resolver != nil {
+		resolver = a.resolver
+	}
+
+	mx, err := fetchMXHosts(a.ctx, resolver, a.email.CanonicalDomain())
+	if errors.Is(err, ErrNullMX) {
+		return nil, nil
+	}
+
+	return mx, err
+}
+
+// lookupRecords returns the TXT records of name that start with the version tag, e.g. "v=spf1". A name that doesn't
+// exist has no records.
+func lookupRecords(ctx context.Context, r LookupTXT, name, version string) ([]string, error) {
+	txts, err := r.LookupTXT(ctx, name)
+	if isNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var records []string
+	for _, txt := range txts {
+		tag, _, _ := strings.Cut(txt, ";")
+		if tag, _, _ = strings.Cut(tag, " "); strings.EqualFold(strings.TrimSpace(tag), version) {
+			records = append(records, txt)
+		}
+	}
+
+	return records, nil
+}
+
+func lookupSPF(ctx context.Context, r LookupTXT, domain string) SPFPosture {
+	records, err := lookupRecords(ctx, r, domain, "v=spf1")
+	switch {
+	case err != nil:
+		return SPFPosture{Error: err.Error()}
+	case len(records) == 0:
+		return SPFPosture{}
+	case len(records) > 1:
+		return SPFPosture{Error: fmt.Sprintf("%d SPF records found, expected one", len(records))}
+	}
+
+	spf := SPFPosture{Record: records[0]}
+	for _, term := range strings.Fields(strings.ToLower(spf.Record)) {
+		switch term {
+		case "all", "+all":
+			spf.All = "+all"
+		case "-all", "~all", "?all":
+			spf.All = term
+		}
+	}
+
+	return spf
+}
+
+func lookupDMARC(ctx context.Context, r LookupTXT, domain string) DMARCPosture {
+	records, err := lookupRecords(ctx, r, "_dmarc."+domain, "v=DMARC1")
+	switch {
+	case err != nil:
+		return DMARCPosture{Error: err.Error()}
+	case len(records) == 0:
+		return DMARCPosture{}
+	case len(records) > 1:
+		return DMARCPosture{Error: fmt.Sprintf("%d DMARC records found, expected one", len(records))}
+	}
+
+	dmarc := DMARCPosture{Record: records[0], Percentage: 100}
+	tags := parseTags(dmarc.Record)
+
+	dmarc.Policy = strings.ToLower(tags["p"])
+	if dmarc.Policy == "" {
+		dmarc.Error = "DMARC record without a policy"
+	}
+
+	dmarc.SubdomainPolicy = strings.ToLower(tags["sp"])
+	if pct, err := strconv.Atoi(tags["pct"]); err == nil && 0 <= pct && pct <= 100 {
+		dmarc.Percentage = pct
+	}
+
+	return dmarc
+}
+
+func lookupMTASTS(ctx context.Context, conf PostureConfig, domain string) MTASTSPosture {
+	records, err := lookupRecords(ctx, conf.Resolver, "_mta-sts."+domain, "v=STSv1")
+	switch {
+	case err != nil:
+		return MTASTSPosture{Error: err.Error()}
+	case len(records) == 0:
+		return MTASTSPosture{}
+	case len(records) > 1:
+		return MTASTSPosture{Error: fmt.Sprintf("%d MTA-STS records found, expected one", len(records))}
+	}
+
+	sts := MTASTSPosture{Record: records[0], ID: parseTags(records[0])["id"]}
+	if err := fetchMTASTSPolicy(ctx, conf.HTTPClient, domain, &sts); err != nil {
+		sts.Error = err.Error()
+	}
+
+	return sts
+}
+
+// fetchMTASTSPolicy fetches the policy of domain from its well-known location (RFC 8461 §3.3) and parses it into sts
+func fetchMTASTSPolicy(ctx context.Context, client *http.Client, domain string, sts *MTASTSPosture) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://mta-sts."+domain+"/.well-known/mta-sts.txt", http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("MTA-STS policy request failed with status %d", resp.StatusCode)
+	}
+
+	policy := MTASTSPosture{}
+	var version string
+
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, mtaSTSPolicyMaxSize))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			version = value
+		case "mode":
+			policy.Mode = value
+		case "mx":
+			policy.MX = append(policy.MX, value)
+		case "max_age":
+			policy.MaxAge, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if version != "STSv1" || policy.Mode == "" || policy.MaxAge <= 0 {
+		return errors.New("invalid MTA-STS policy")
+	}
+
+	sts.Mode, sts.MX, sts.MaxAge = policy.Mode, policy.MX, policy.MaxAge
+	return nil
+}
+
+func lookupDANE(ctx context.Context, r LookupTXT, mx []string) DANEPosture {
+	tlsa, ok := r.(LookupTLSA)
+	if !ok {
+		return DANEPosture{Error: ErrNoTLSALookup.Error()}
+	}
+
+	var dane DANEPosture
+	for _, host := range mx {
+		if host == "" {
+			continue
+		}
+
+		records, err := tlsa.LookupTLSA(ctx, "_25._tcp."+strings.TrimSuffix(host, "."))
+		if isNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			dane.Error = err.Error()
+			continue
+		}
+
+		if dane.TLSA == nil {
+			dane.TLSA = make(map[string][]string, len(mx))
+		}
+
+		dane.TLSA[host] = records
+	}
+
+	return dane
+}
+
+// parseTags parses the "tag=value" pairs of a record, separated by semicolons (e.g. RFC 7489 §6.4). Tags are
+// lower-cased.
+func parseTags(record string) map[string]string {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(record, ";") {
+		tag, value, ok := strings.Cut(pair, "=")
+		if ok {
+			tags[strings.ToLower(strings.TrimSpace(tag))] = strings.TrimSpace(value)
+		}
+	}
+
+	return tags
+}
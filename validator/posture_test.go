@@ -0,0 +1,379 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+// postureResolver answers TXT and TLSA lookups, names without an entry are reported as not found
+type postureResolver struct {
+	txt  map[string][]string
+	tlsa map[string][]string
+	err  error // A single error for every lookup
+}
+
+func (r postureResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	return r.lookup(r.txt, name)
+}
+
+func (r postureResolver) LookupTLSA(_ context.Context, name string) ([]string, error) {
+	return r.lookup(r.tlsa, name)
+}
+
+func (r postureResolver) lookup(records map[string][]string, name string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if v, ok := records[name]; ok {
+		return v, nil
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// txtResolver hides the TLSA lookups of a resolver, like *net.Resolver
+type txtResolver struct {
+	r postureResolver
+}
+
+func (r txtResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r.r.LookupTXT(ctx, name)
+}
+
+// newMTASTSServer serves policy for every MTA-STS policy request. The returned client connects to the server, for
+// hosts under example.com.
+func newMTASTSServer(t *testing.T, handler http.HandlerFunc) *http.Client {
+	t.Helper()
+
+	s := httptest.NewTLSServer(handler)
+	t.Cleanup(s.Close)
+
+	client := s.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, s.Listener.Addr().String())
+	}
+
+	client.Transport = transport
+	return client
+}
+
+func servePolicy(policy string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "mta-sts.example.com" || r.URL.Path != "/.well-known/mta-sts.txt" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = fmt.Fprint(w, policy)
+	}
+}
+
+func Test_lookupSPF(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolver postureResolver
+		want     SPFPosture
+	}{
+		{
+			name:     "hard fail",
+			resolver: postureResolver{txt: map[string][]string{"example.org": {"google-site-verification=b0rk", "v=spf1 mx include:_spf.example.org -all"}}},
+			want:     SPFPosture{Record: "v=spf1 mx include:_spf.example.org -all", All: "-all"},
+		},
+		{
+			name:     "implicit pass",
+			resolver: postureResolver{txt: map[string][]string{"example.org": {"V=SPF1 a ALL"}}},
+			want:     SPFPosture{Record: "V=SPF1 a ALL", All: "+all"},
+		},
+		{
+			name:     "without all",
+			resolver: postureResolver{txt: map[string][]string{"example.org": {"v=spf1 redirect=_spf.example.org"}}},
+			want:     SPFPosture{Record: "v=spf1 redirect=_spf.example.org"},
+		},
+		{
+			name:     "no record",
+			resolver: postureResolver{txt: map[string][]string{"example.org": {"v=spf10 -all"}}},
+		},
+		{
+			name:     "no such domain",
+			resolver: postureResolver{},
+		},
+		{
+			name:     "multiple records",
+			resolver: postureResolver{txt: map[string][]string{"example.org": {"v=spf1 -all", "v=spf1 ~all"}}},
+			want:     SPFPosture{Error: "2 SPF records found, expected one"},
+		},
+		{
+			name:     "lookup error",
+			resolver: postureResolver{err: errors.New("server misbehaving")},
+			want:     SPFPosture{Error: "server misbehaving"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lookupSPF(context.Background(), tt.resolver, "example.org"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_lookupDMARC(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  []string
+		want DMARCPosture
+	}{
+		{
+			name: "reject",
+			txt:  []string{"v=DMARC1; p=reject; sp=Quarantine; pct=50; rua=mailto:dmarc@example.org"},
+			want: DMARCPosture{Record: "v=DMARC1; p=reject; sp=Quarantine; pct=50; rua=mailto:dmarc@example.org", Policy: "reject", SubdomainPolicy: "quarantine", Percentage: 50},
+		},
+		{
+			name: "defaults",
+			txt:  []string{"v=DMARC1;p=none"},
+			want: DMARCPosture{Record: "v=DMARC1;p=none", Policy: "none", Percentage: 100},
+		},
+		{
+			name: "without policy",
+			txt:  []string{"v=DMARC1; rua=mailto:dmarc@example.org"},
+			want: DMARCPosture{Record: "v=DMARC1; rua=mailto:dmarc@example.org", Percentage: 100, Error: "DMARC record without a policy"},
+		},
+		{
+			name: "no record",
+			txt:  []string{"v=spf1 -all"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := postureResolver{txt: map[string][]string{"_dmarc.example.org": tt.txt}}
+			if got := lookupDMARC(context.Background(), r, "example.org"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_lookupMTASTS(t *testing.T) {
+	const record = "v=STSv1; id=20240101T000000;"
+
+	tests := []struct {
+		name    string
+		txt     []string
+		handler http.HandlerFunc
+		want    MTASTSPosture
+	}{
+		{
+			name:    "enforce",
+			txt:     []string{record},
+			handler: servePolicy("version: STSv1\r\nmode: enforce\r\nmx: mx1.example.com\r\nmx: *.example.com\r\nmax_age: 604800\r\n"),
+			want:    MTASTSPosture{Record: record, ID: "20240101T000000", Mode: "enforce", MX: []string{"mx1.example.com", "*.example.com"}, MaxAge: 604800},
+		},
+		{
+			name: "no record",
+			txt:  []string{"v=spf1 -all"},
+		},
+		{
+			name:    "policy not found",
+			txt:     []string{record},
+			handler: http.NotFound,
+			want:    MTASTSPosture{Record: record, ID: "20240101T000000", Error: "MTA-STS policy request failed with status 404"},
+		},
+		{
+			name:    "invalid policy",
+			txt:     []string{record},
+			handler: servePolicy("version: STSv1\nmode: enforce\n"),
+			want:    MTASTSPosture{Record: record, ID: "20240101T000000", Error: "invalid MTA-STS policy"},
+		},
+		{
+			name: "redirects aren't followed",
+			txt:  []string{record},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "https://mta-sts.example.com/policy.txt", http.StatusFound)
+			},
+			want: MTASTSPosture{Record: record, ID: "20240101T000000", Error: "MTA-STS policy request failed with status 302"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := PostureConfig{
+				Resolver:   postureResolver{txt: map[string][]string{"_mta-sts.example.com": tt.txt}},
+				HTTPClient: newMTASTSServer(t, tt.handler),
+			}
+
+			got := lookupMTASTS(context.Background(), conf.withDefaults(&net.Dialer{}), "example.com")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_lookupDANE(t *testing.T) {
+	r := postureResolver{tlsa: map[string][]string{"_25._tcp.mx1.example.org": {"3 1 1 deadbeef"}}}
+
+	tests := []struct {
+		name     string
+		resolver LookupTXT
+		mx       []string
+		want     DANEPosture
+	}{
+		{
+			name:     "records",
+			resolver: r,
+			mx:       []string{"mx1.example.org.", "", "mx2.example.org."},
+			want:     DANEPosture{TLSA: map[string][]string{"mx1.example.org.": {"3 1 1 deadbeef"}}},
+		},
+		{
+			name:     "no records",
+			resolver: r,
+			mx:       []string{"mx2.example.org."},
+		},
+		{
+			name:     "lookup error",
+			resolver: postureResolver{err: errors.New("server misbehaving")},
+			mx:       []string{"mx1.example.org."},
+			want:     DANEPosture{Error: "server misbehaving"},
+		},
+		{
+			name:     "TLSA isn't supported",
+			resolver: txtResolver{r},
+			mx:       []string{"mx1.example.org."},
+			want:     DANEPosture{Error: ErrNoTLSALookup.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lookupDANE(context.Background(), tt.resolver, tt.mx); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_checkPosture(t *testing.T) {
+	r := postureResolver{
+		txt: map[string][]string{
+			"example.com":        {"v=spf1 mx -all"},
+			"_dmarc.example.com": {"v=DMARC1; p=reject"},
+		},
+		tlsa: map[string][]string{"_25._tcp.mx.example.com": {"3 1 1 deadbeef"}},
+	}
+
+	tests := []struct {
+		name            string
+		resolver        LookupTXT
+		steps           validations.Steps
+		domain          string
+		wantPosture     bool
+		wantValidations bool
+	}{
+		{name: "complete", resolver: r, domain: "example.com", wantPosture: true, wantValidations: true},
+		{name: "incomplete", resolver: txtResolver{r}, domain: "example.com", wantPosture: true},
+		{name: "ran before", resolver: r, domain: "example.com", steps: validations.Steps(validations.FPosture)},
+		{name: "address literal", resolver: r, domain: "[192.0.2.1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Artifact{
+				ctx:           context.Background(),
+				email:         types.NewEmailFromParts("john", tt.domain),
+				mx:            []string{"mx.example.com."},
+				Steps:         tt.steps,
+				syntaxMode:    SyntaxRFC5322,
+				postureConfig: PostureConfig{Resolver: tt.resolver, HTTPClient: newMTASTSServer(t, http.NotFound)},
+			}
+
+			if err := checkPosture(a); err != nil {
+				t.Fatalf("Expected checkPosture to never fail, got %s", err)
+			}
+
+			if (a.posture != nil) != tt.wantPosture {
+				t.Fatalf("Expected a posture: %t, got %+v", tt.wantPosture, a.posture)
+			}
+
+			if a.Validations.HasFlag(validations.FPosture) != tt.wantValidations {
+				t.Errorf("Expected the FPosture validation: %t, got %s", tt.wantValidations, a.Validations)
+			}
+
+			if !tt.wantPosture {
+				return
+			}
+
+			if a.posture.SPF.All != "-all" || a.posture.DMARC.Policy != "reject" {
+				t.Errorf("Expected the SPF and DMARC records, got %+v", a.posture)
+			}
+		})
+	}
+}
+
+func Test_checkPosture_withoutMX(t *testing.T) {
+	a := &Artifact{
+		ctx:        context.Background(),
+		email:      types.NewEmailFromParts("john", "example.com"),
+		resolver:   buildResolver([]string{"mx.example.com"}, nil, nil),
+		syntaxMode: SyntaxRFC5322,
+		postureConfig: PostureConfig{
+			Resolver:   postureResolver{tlsa: map[string][]string{"_25._tcp.mx.example.com": {"3 1 1 deadbeef"}}},
+			HTTPClient: newMTASTSServer(t, http.NotFound),
+		},
+	}
+
+	// E.g. when the MX lookup was skipped, because the result of the domain came from a cache
+	_ = checkPosture(a)
+
+	want := map[string][]string{"mx.example.com": {"3 1 1 deadbeef"}}
+	if !reflect.DeepEqual(a.posture.DANE.TLSA, want) {
+		t.Errorf("Expected the TLSA records of the looked up MX hosts, got %+v", a.posture.DANE)
+	}
+
+	a.posture, a.Steps, a.Validations = nil, 0, 0
+	a.resolver = buildResolver(nil, nil, errors.New("server misbehaving"))
+
+	if _ = checkPosture(a); a.posture.DANE.Error == "" || a.Validations.HasFlag(validations.FPosture) {
+		t.Errorf("Expected DANE to be unknown when the MX hosts can't be looked up, got %+v", a.posture.DANE)
+	}
+}
+
+func TestEmailValidator_WithPostureConfig(t *testing.T) {
+	resolver := buildResolver([]string{"mx.example.com"}, map[string][]net.IPAddr{
+		"mx.example.com": {{IP: net.ParseIP("192.0.2.1")}},
+	}, nil)
+
+	records := postureResolver{
+		txt:  map[string][]string{"example.com": {"v=spf1 -all"}},
+		tlsa: map[string][]string{"_25._tcp.mx.example.com": {"3 1 1 deadbeef"}},
+	}
+
+	v := NewEmailAddressValidator(nil, WithResolver(resolver), withDocumentationMX, WithPostureConfig(PostureConfig{Resolver: records}))
+
+	parts := types.NewEmailFromParts("john", "example.com")
+	if r := v.CheckWithLookup(context.Background(), parts); r.Posture != nil {
+		t.Errorf("Expected no posture without StepPosture, got %+v", r.Posture)
+	}
+
+	r := v.CheckWithPipeline(LookupPipeline().Append(StepPosture))(context.Background(), parts)
+	if !r.Validations.IsValid() || r.Posture == nil {
+		t.Fatalf("Expected a valid result with a posture, got %+v", r)
+	}
+
+	want := map[string][]string{"mx.example.com": {"3 1 1 deadbeef"}}
+	if r.Posture.SPF.All != "-all" || !reflect.DeepEqual(r.Posture.DANE.TLSA, want) {
+		t.Errorf("Expected the records of the resolver, got %+v", r.Posture)
+	}
+}
@@ -0,0 +1,129 @@
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed" // The embedded copy of the list
+	"io"
+	"strings"
+	"sync"
+)
+
+//go:embed data/public_suffix_list.dat
+var embeddedPublicSuffixes []byte
+
+// suffixRule holds the kinds of rules (https://github.com/publicsuffix/list/wiki/Format) defined for a name
+type suffixRule uint8
+
+const (
+	suffixRuleNormal    suffixRule = 1 << iota // "name"
+	suffixRuleWildcard                         // "*.name"
+	suffixRuleException                        // "!name"
+)
+
+// NewSuffixList creates a Public Suffix List, loaded with the embedded copy. It can be refreshed with Load, using
+// https://publicsuffix.org/list/public_suffix_list.dat
+func NewSuffixList() *SuffixList {
+	l := &SuffixList{}
+	if err := l.Load(bytes.NewReader(embeddedPublicSuffixes)); err != nil {
+		panic("embedded Public Suffix List is invalid: " + err.Error())
+	}
+
+	return l
+}
+
+// SuffixList holds the rules of the Public Suffix List, the domains under which names can be registered (e.g.: "org"
+// or "co.uk"). Both the ICANN and the private sections are used. It's safe for concurrent use and can be updated at
+// runtime.
+type SuffixList struct {
+	lock  sync.RWMutex
+	rules map[string]suffixRule
+}
+
+// Load replaces the content of the list with what is read from r, in the format of the Public Suffix List
+func (l *SuffixList) Load(r io.Reader) error {
+	rules := make(map[string]suffixRule)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Only the first word of a line is considered, the remainder is a comment
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+			continue
+		}
+
+		rule, kind := fields[0], suffixRuleNormal
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			rule, kind = rule[1:], suffixRuleException
+		case strings.HasPrefix(rule, "*."):
+			rule, kind = rule[2:], suffixRuleWildcard
+		}
+
+		if rule = normalizeDomainEntry(rule); rule != "" {
+			rules[rule] |= kind
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		return ErrEmptyList
+	}
+
+	l.lock.Lock()
+	l.rules = rules
+	l.lock.Unlock()
+
+	return nil
+}
+
+// Len returns the number of names with rules on the list
+func (l *SuffixList) Len() int {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return len(l.rules)
+}
+
+// PublicSuffix returns the public suffix of domain in its ASCII form, e.g.: "co.uk" for "mail.example.co.uk". Domains
+// without a matching rule have their top-level domain as public suffix.
+func (l *SuffixList) PublicSuffix(domain string) string {
+	domain = normalizeDomainEntry(domain)
+	labels := strings.Split(domain, ".")
+
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	// The candidates are tried from long to short, so the first match is the prevailing rule
+	for i := range labels {
+		candidate := strings.Join(labels[i:], ".")
+		rule := l.rules[candidate]
+
+		switch {
+		case rule&suffixRuleException != 0:
+			return strings.Join(labels[i+1:], ".")
+		case rule&suffixRuleNormal != 0:
+			return candidate
+		case i+1 < len(labels) && l.rules[strings.Join(labels[i+1:], ".")]&suffixRuleWildcard != 0:
+			return candidate
+		}
+	}
+
+	return topLevelDomain(domain)
+}
+
+// RegistrableDomain returns the public suffix of domain plus one label in its ASCII form, e.g.: "example.co.uk" for
+// "mail.corp.example.co.uk". It returns false when domain is a public suffix itself.
+func (l *SuffixList) RegistrableDomain(domain string) (string, bool) {
+	domain = normalizeDomainEntry(domain)
+	suffix := l.PublicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return "", false
+	}
+
+	registrable := domain[:len(domain)-len(suffix)-1]
+	return registrable[strings.LastIndexByte(registrable, '.')+1:] + "." + suffix, true
+}
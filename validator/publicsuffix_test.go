@@ -0,0 +1,86 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuffixList_RegistrableDomain(t *testing.T) {
+	l := NewSuffixList()
+
+	tests := []struct {
+		domain     string
+		wantSuffix string
+		want       string
+	}{
+		{domain: "example.org", wantSuffix: "org", want: "example.org"},
+		{domain: "Mail.Example.ORG.", wantSuffix: "org", want: "example.org"},
+		{domain: "mail.corp.example.co.uk", wantSuffix: "co.uk", want: "example.co.uk"},
+		{domain: "example.blogspot.com", wantSuffix: "blogspot.com", want: "example.blogspot.com"},
+		{domain: "пример.рф", wantSuffix: "xn--p1ai", want: "xn--e1afmkfd.xn--p1ai"},
+
+		// Wildcards and their exceptions: "*.ck" and "!www.ck"
+		{domain: "mail.example.ck", wantSuffix: "example.ck", want: "mail.example.ck"},
+		{domain: "mail.www.ck", wantSuffix: "ck", want: "www.ck"},
+
+		// Without a matching rule, the top-level domain is the public suffix
+		{domain: "mail.example.unknowntld", wantSuffix: "unknowntld", want: "example.unknowntld"},
+
+		// Public suffixes aren't registrable
+		{domain: "co.uk", wantSuffix: "co.uk"},
+		{domain: "example.ck", wantSuffix: "example.ck"},
+		{domain: "org", wantSuffix: "org"},
+		{domain: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := l.PublicSuffix(tt.domain); got != tt.wantSuffix {
+				t.Errorf("PublicSuffix() = %q, want %q", got, tt.wantSuffix)
+			}
+
+			got, ok := l.RegistrableDomain(tt.domain)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("RegistrableDomain() = %q, %t, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuffixList_Load(t *testing.T) {
+	input := `
+// ===BEGIN ICANN DOMAINS===
+uk
+co.uk
+
+// Only the first word counts
+*.example  wildcard
+!www.example
+`
+
+	l := NewSuffixList()
+	if err := l.Load(strings.NewReader(input)); err != nil {
+		t.Fatalf("Load() unexpected error %s", err)
+	}
+
+	if l.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", l.Len())
+	}
+
+	tests := map[string]string{
+		"mail.example.co.uk":        "example.co.uk",
+		"mail.example.blogspot.com": "blogspot.com",
+		"mail.corp.example":         "mail.corp.example",
+		"mail.www.example":          "www.example",
+	}
+
+	for domain, want := range tests {
+		if got, _ := l.RegistrableDomain(domain); got != want {
+			t.Errorf("RegistrableDomain(%q) = %q, want %q", domain, got, want)
+		}
+	}
+
+	if err := l.Load(strings.NewReader("// Nothing but comments\n")); err == nil {
+		t.Errorf("Expected an empty list to be refused")
+	}
+}
@@ -0,0 +1,230 @@
+package resolver
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxEntries  = 10000
+	DefaultMaxTTL      = time.Hour
+	DefaultNegativeTTL = time.Minute
+)
+
+// CacheOption configures a Cache
+type CacheOption func(c *Cache)
+
+// WithMaxEntries bounds the number of cached answers, the least recently used answers are evicted first
+func WithMaxEntries(n int) CacheOption {
+	return func(c *Cache) {
+		c.maxEntries = n
+	}
+}
+
+// WithMaxTTL caps the TTL of answers, it limits how long a changed record can go unnoticed
+func WithMaxTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.maxTTL = ttl
+	}
+}
+
+// WithNegativeTTL caps the TTL of "not found" answers (NXDOMAIN and NODATA). It's also used when the upstream doesn't
+// report a negative caching TTL. A TTL of 0 disables negative caching.
+func WithNegativeTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.negativeTTL = ttl
+	}
+}
+
+// NewCache creates a Cache in front of upstream
+func NewCache(upstream Upstream, options ...CacheOption) *Cache {
+	c := Cache{
+		upstream:    upstream,
+		entries:     make(map[cacheKey]*list.Element),
+		lru:         list.New(),
+		maxEntries:  DefaultMaxEntries,
+		maxTTL:      DefaultMaxTTL,
+		negativeTTL: DefaultNegativeTTL,
+		now:         time.Now,
+	}
+
+	for _, o := range options {
+		o(&c)
+	}
+
+	return &c
+}
+
+// Cache is a Resolver that caches answers for as long as their TTL allows. "Not found" answers are cached as well,
+// other errors (e.g. timeouts) aren't. It's safe for concurrent use.
+type Cache struct {
+	upstream Upstream
+
+	lock    sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+
+	maxEntries  int
+	maxTTL      time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+}
+
+type cacheKey struct {
+	kind uint8
+	name string
+}
+
+const (
+	kindMX uint8 = iota
+	kindIP
+)
+
+type cacheEntry struct {
+	key     cacheKey
+	mxs     []*net.MX
+	ips     []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// LookupMX returns the MX records of name, from cache when possible
+func (c *Cache) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	key := newCacheKey(kindMX, name)
+	if e, ok := c.get(key); ok {
+		return copyMX(e.mxs), e.err
+	}
+
+	mxs, ttl, err := c.upstream.LookupMX(ctx, name)
+	c.set(cacheEntry{key: key, mxs: copyMX(mxs), err: err}, ttl)
+
+	return mxs, err
+}
+
+// LookupIPAddr returns the A and AAAA records of host, from cache when possible
+func (c *Cache) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	key := newCacheKey(kindIP, host)
+	if e, ok := c.get(key); ok {
+		return copyIPs(e.ips), e.err
+	}
+
+	ips, ttl, err := c.upstream.LookupIPAddr(ctx, host)
+	c.set(cacheEntry{key: key, ips: copyIPs(ips), err: err}, ttl)
+
+	return ips, err
+}
+
+// MXTTL returns the remaining TTL of the cached MX answer of name, which is also the TTL of a cached "not found"
+// answer. It returns false when no answer is cached.
+func (c *Cache) MXTTL(name string) (time.Duration, bool) {
+	e, ok := c.get(newCacheKey(kindMX, name))
+	if !ok {
+		return 0, false
+	}
+
+	return e.expires.Sub(c.now()), true
+}
+
+// Len returns the number of cached answers, including those that expired but haven't been evicted yet
+func (c *Cache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *Cache) get(key cacheKey) (cacheEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	e := el.Value.(cacheEntry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return cacheEntry{}, false
+	}
+
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+func (c *Cache) set(e cacheEntry, ttl time.Duration) {
+	if e.err != nil {
+		if !isNotFound(e.err) {
+			return
+		}
+
+		if ttl == 0 || ttl > c.negativeTTL {
+			ttl = c.negativeTTL
+		}
+	}
+
+	if ttl > c.maxTTL {
+		ttl = c.maxTTL
+	}
+
+	if ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	e.expires = c.now().Add(ttl)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, ok := c.entries[e.key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(cacheEntry).key)
+}
+
+// newCacheKey creates a key that ignores case and a trailing dot, as DNS names do
+func newCacheKey(kind uint8, name string) cacheKey {
+	return cacheKey{
+		kind: kind,
+		name: strings.TrimSuffix(strings.ToLower(name), "."),
+	}
+}
+
+func copyMX(mxs []*net.MX) []*net.MX {
+	if mxs == nil {
+		return nil
+	}
+
+	result := make([]*net.MX, len(mxs))
+	for i, mx := range mxs {
+		if mx != nil {
+			mxCopy := *mx
+			result[i] = &mxCopy
+		}
+	}
+
+	return result
+}
+
+func copyIPs(ips []net.IPAddr) []net.IPAddr {
+	if ips == nil {
+		return nil
+	}
+
+	return append([]net.IPAddr(nil), ips...)
+}
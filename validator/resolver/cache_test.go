@@ -0,0 +1,194 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type stubUpstream struct {
+	mxs   []*net.MX
+	ips   []net.IPAddr
+	ttl   time.Duration
+	err   error
+	calls int
+}
+
+func (s *stubUpstream) LookupMX(_ context.Context, _ string) ([]*net.MX, time.Duration, error) {
+	s.calls++
+	return copyMX(s.mxs), s.ttl, s.err
+}
+
+func (s *stubUpstream) LookupIPAddr(_ context.Context, _ string) ([]net.IPAddr, time.Duration, error) {
+	s.calls++
+	return copyIPs(s.ips), s.ttl, s.err
+}
+
+type stubClock struct {
+	now time.Time
+}
+
+func (c *stubClock) Now() time.Time {
+	return c.now
+}
+
+func newTestCache(upstream Upstream, options ...CacheOption) (*Cache, *stubClock) {
+	clock := &stubClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := NewCache(upstream, options...)
+	c.now = clock.Now
+
+	return c, clock
+}
+
+func TestCache_ttl(t *testing.T) {
+	notFound := newNotFoundError("example.org")
+
+	tests := []struct {
+		name      string
+		options   []CacheOption
+		ttl       time.Duration
+		err       error
+		wantCalls []int // The number of upstream calls after each lookup, with the clock moving 1 minute per lookup
+	}{
+		{name: "honours the TTL", ttl: 150 * time.Second, wantCalls: []int{1, 1, 1, 2}},
+		{name: "zero TTL isn't cached", ttl: 0, wantCalls: []int{1, 2, 3}},
+		{name: "TTL is capped", ttl: time.Hour, options: []CacheOption{WithMaxTTL(time.Minute)}, wantCalls: []int{1, 2, 3}},
+		{name: "negative answers use the negative TTL", err: notFound, ttl: 150 * time.Second, options: []CacheOption{WithNegativeTTL(5 * time.Minute)}, wantCalls: []int{1, 1, 1, 2}},
+		{name: "negative answers are capped", err: notFound, ttl: time.Hour, options: []CacheOption{WithNegativeTTL(90 * time.Second)}, wantCalls: []int{1, 1, 2}},
+		{name: "negative answers without TTL", err: notFound, options: []CacheOption{WithNegativeTTL(90 * time.Second)}, wantCalls: []int{1, 1, 2}},
+		{name: "negative caching disabled", err: notFound, ttl: time.Hour, options: []CacheOption{WithNegativeTTL(0)}, wantCalls: []int{1, 2}},
+		{name: "other errors aren't cached", err: errors.New("b0rk"), ttl: time.Hour, wantCalls: []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &stubUpstream{mxs: []*net.MX{{Host: "mx.example.org.", Pref: 10}}, ttl: tt.ttl, err: tt.err}
+			c, clock := newTestCache(upstream, tt.options...)
+
+			for i, want := range tt.wantCalls {
+				_, err := c.LookupMX(context.Background(), "example.org")
+				if !errors.Is(err, tt.err) {
+					t.Errorf("Expected error %v, got %v", tt.err, err)
+				}
+
+				if upstream.calls != want {
+					t.Errorf("Lookup %d: expected %d upstream calls, got %d", i, want, upstream.calls)
+				}
+
+				clock.now = clock.now.Add(time.Minute)
+			}
+		})
+	}
+}
+
+func TestCache_keys(t *testing.T) {
+	upstream := &stubUpstream{
+		mxs: []*net.MX{{Host: "mx.example.org.", Pref: 10}},
+		ips: []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}},
+		ttl: time.Hour,
+	}
+
+	c, _ := newTestCache(upstream)
+
+	_, _ = c.LookupMX(context.Background(), "example.org")
+	_, _ = c.LookupMX(context.Background(), "Example.ORG.")
+	if upstream.calls != 1 {
+		t.Errorf("Expected names to be case and trailing dot insensitive, got %d calls", upstream.calls)
+	}
+
+	ips, _ := c.LookupIPAddr(context.Background(), "example.org")
+	if upstream.calls != 2 || !reflect.DeepEqual(ips, upstream.ips) {
+		t.Errorf("Expected host lookups to be cached separately, got %d calls and %v", upstream.calls, ips)
+	}
+}
+
+func TestCache_MXTTL(t *testing.T) {
+	upstream := &stubUpstream{mxs: []*net.MX{{Host: "mx.example.org.", Pref: 10}}, ttl: 10 * time.Minute}
+	c, clock := newTestCache(upstream)
+
+	if _, ok := c.MXTTL("example.org"); ok {
+		t.Errorf("Expected no TTL before the lookup")
+	}
+
+	_, _ = c.LookupMX(context.Background(), "example.org")
+	clock.now = clock.now.Add(4 * time.Minute)
+
+	if ttl, ok := c.MXTTL("Example.org."); !ok || ttl != 6*time.Minute {
+		t.Errorf("Expected the remaining TTL of 6m, got %s %t", ttl, ok)
+	}
+
+	clock.now = clock.now.Add(6 * time.Minute)
+	if _, ok := c.MXTTL("example.org"); ok {
+		t.Errorf("Expected no TTL once the answer expired")
+	}
+}
+
+func TestCache_copies(t *testing.T) {
+	upstream := &stubUpstream{mxs: []*net.MX{{Host: "mx.example.org.", Pref: 10}}, ttl: time.Hour}
+	c, _ := newTestCache(upstream)
+
+	mxs, _ := c.LookupMX(context.Background(), "example.org")
+	mxs[0].Host = "altered"
+
+	mxs, _ = c.LookupMX(context.Background(), "example.org")
+	if mxs[0].Host != "mx.example.org." {
+		t.Errorf("Expected the cached answer to be unaffected by callers, got %q", mxs[0].Host)
+	}
+}
+
+func TestCache_maxEntries(t *testing.T) {
+	upstream := &stubUpstream{mxs: []*net.MX{{Host: "mx.example.org.", Pref: 10}}, ttl: time.Hour}
+	c, _ := newTestCache(upstream, WithMaxEntries(2))
+
+	lookup := func(name string) {
+		_, _ = c.LookupMX(context.Background(), name)
+	}
+
+	lookup("a.example")
+	lookup("b.example")
+	lookup("a.example") // Makes b the least recently used
+	lookup("c.example") // Evicts b
+
+	if c.Len() != 2 {
+		t.Errorf("Expected the cache to be bound to 2 entries, got %d", c.Len())
+	}
+
+	calls := upstream.calls
+	lookup("a.example")
+	lookup("c.example")
+	if upstream.calls != calls {
+		t.Errorf("Expected a and c to be cached")
+	}
+
+	lookup("b.example")
+	if upstream.calls != calls+1 {
+		t.Errorf("Expected b to be evicted")
+	}
+}
+
+func TestCache_withClient(t *testing.T) {
+	s := newDNSStandIn(t, testZone())
+	c, clock := newTestCache(NewClient(NewUDPTransport(s.Addr())))
+
+	mxs, err := c.LookupMX(context.Background(), "example.org")
+	if err != nil || len(mxs) != 2 {
+		t.Fatalf("Expected 2 MX records, got %v (%v)", mxs, err)
+	}
+
+	// The records are gone, but their TTL (300s) hasn't passed yet
+	s.update(func(s *dnsStandIn) {
+		s.zone = zone{}
+	})
+	clock.now = clock.now.Add(299 * time.Second)
+	if mxs, err := c.LookupMX(context.Background(), "example.org"); err != nil || len(mxs) != 2 {
+		t.Errorf("Expected the cached records, got %v (%v)", mxs, err)
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if _, err := c.LookupMX(context.Background(), "example.org"); !isNotFound(err) {
+		t.Errorf("Expected the records to have expired, got %v", err)
+	}
+}
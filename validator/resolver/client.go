@@ -0,0 +1,288 @@
+package resolver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var (
+	ErrInvalidResponse = errors.New("invalid DNS response")
+	ErrServerFailure   = errors.New("server misbehaving")
+)
+
+// typeTLSA is the type of TLSA records (RFC 6698), dnsmessage doesn't define it
+const typeTLSA dnsmessage.Type = 52
+
+// udpPayloadSize is the EDNS(0) buffer size we advertise, it avoids IP fragmentation (see: https://dnsflagday.net/2020/)
+const udpPayloadSize = 1232
+
+// NewClient creates a client that sends its queries over t. The server behind the transport is expected to be a
+// recursive resolver.
+func NewClient(t Transport) *Client {
+	return &Client{
+		transport: t,
+	}
+}
+
+// Client is an Upstream that resolves names by exchanging DNS messages, it reports the TTL of the answers
+type Client struct {
+	transport Transport
+}
+
+// LookupMX returns the MX records of name, sorted by preference. An empty name (the null MX) is returned as ".".
+func (c *Client) LookupMX(ctx context.Context, name string) ([]*net.MX, time.Duration, error) {
+	answers, ttl, err := c.query(ctx, name, dnsmessage.TypeMX)
+	if err != nil {
+		return nil, ttl, err
+	}
+
+	mxs := make([]*net.MX, 0, len(answers))
+	for _, rr := range answers {
+		if body, ok := rr.Body.(*dnsmessage.MXResource); ok {
+			mxs = append(mxs, &net.MX{Host: body.MX.String(), Pref: body.Pref})
+		}
+	}
+
+	sort.SliceStable(mxs, func(i, j int) bool {
+		return mxs[i].Pref < mxs[j].Pref
+	})
+
+	return mxs, ttl, nil
+}
+
+// LookupIPAddr returns the A and AAAA records of host. It only fails when neither lookup produced an address.
+func (c *Client) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, 0, nil
+	}
+
+	type result struct {
+		answers []dnsmessage.Resource
+		ttl     time.Duration
+		err     error
+	}
+
+	aaaa := make(chan result, 1)
+	go func() {
+		answers, ttl, err := c.query(ctx, host, dnsmessage.TypeAAAA)
+		aaaa <- result{answers: answers, ttl: ttl, err: err}
+	}()
+
+	answers, ttl, err := c.query(ctx, host, dnsmessage.TypeA)
+	results := []result{{answers: answers, ttl: ttl, err: err}, <-aaaa}
+
+	var ips []net.IPAddr
+	var minTTL time.Duration
+	for i, r := range results {
+		if i == 0 || r.ttl < minTTL {
+			minTTL = r.ttl
+		}
+
+		for _, rr := range r.answers {
+			switch body := rr.Body.(type) {
+			case *dnsmessage.AResource:
+				ips = append(ips, net.IPAddr{IP: net.IP(append([]byte(nil), body.A[:]...))})
+			case *dnsmessage.AAAAResource:
+				ips = append(ips, net.IPAddr{IP: net.IP(append([]byte(nil), body.AAAA[:]...))})
+			}
+		}
+	}
+
+	if len(ips) > 0 {
+		return ips, minTTL, nil
+	}
+
+	// Prefer the error that isn't "not found", it tells more
+	for _, r := range results {
+		if !isNotFound(r.err) {
+			return nil, 0, r.err
+		}
+	}
+
+	return nil, minTTL, results[0].err
+}
+
+// LookupTXT returns the TXT records of name. The character strings of a record are concatenated (RFC 7208 §3.3).
+func (c *Client) LookupTXT(ctx context.Context, name string) ([]string, time.Duration, error) {
+	answers, ttl, err := c.query(ctx, name, dnsmessage.TypeTXT)
+	if err != nil {
+		return nil, ttl, err
+	}
+
+	txts := make([]string, 0, len(answers))
+	for _, rr := range answers {
+		if body, ok := rr.Body.(*dnsmessage.TXTResource); ok {
+			txts = append(txts, strings.Join(body.TXT, ""))
+		}
+	}
+
+	return txts, ttl, nil
+}
+
+// LookupTLSA returns the TLSA records of name (e.g.: "_25._tcp.mx.example.org") in presentation format, e.g.:
+// "3 1 1 0c72ac70..." (RFC 6698 §2.2). The answers aren't validated with DNSSEC, that's left to the resolver.
+func (c *Client) LookupTLSA(ctx context.Context, name string) ([]string, time.Duration, error) {
+	answers, ttl, err := c.query(ctx, name, typeTLSA)
+	if err != nil {
+		return nil, ttl, err
+	}
+
+	records := make([]string, 0, len(answers))
+	for _, rr := range answers {
+		body, ok := rr.Body.(*dnsmessage.UnknownResource)
+		if !ok || len(body.Data) < 4 {
+			continue
+		}
+
+		d := body.Data
+		records = append(records, fmt.Sprintf("%d %d %d %s", d[0], d[1], d[2], hex.EncodeToString(d[3:])))
+	}
+
+	if len(records) == 0 {
+		return nil, 0, &net.DNSError{Err: ErrInvalidResponse.Error(), Name: name}
+	}
+
+	return records, ttl, nil
+}
+
+// query sends a single question and returns the answers of type qtype and the lowest TTL of the answer section. For
+// not found errors (NXDOMAIN or NODATA), the negative caching TTL is returned (RFC 2308).
+func (c *Client) query(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, time.Duration, error) {
+	fqdn := name
+	if !strings.HasSuffix(fqdn, ".") {
+		fqdn += "."
+	}
+
+	qname, err := dnsmessage.NewName(fqdn)
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: err.Error(), Name: name}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: err.Error(), Name: name, IsTemporary: true}
+	}
+
+	query, err := newQuery(id, qname, qtype)
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: err.Error(), Name: name}
+	}
+
+	response, err := c.transport.Exchange(ctx, query)
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: err.Error(), Name: name, IsTimeout: isTimeout(ctx, err), IsTemporary: true}
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(response); err != nil || !isResponseTo(msg, id, qname, qtype) {
+		return nil, 0, &net.DNSError{Err: ErrInvalidResponse.Error(), Name: name, IsTemporary: true}
+	}
+
+	switch msg.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, negativeTTL(msg), newNotFoundError(name)
+	default:
+		return nil, 0, &net.DNSError{Err: ErrServerFailure.Error(), Name: name, IsTemporary: true}
+	}
+
+	var answers []dnsmessage.Resource
+	var ttl uint32
+	for i, rr := range msg.Answers {
+		if i == 0 || rr.Header.TTL < ttl {
+			ttl = rr.Header.TTL
+		}
+
+		if rr.Header.Type == qtype {
+			answers = append(answers, rr)
+		}
+	}
+
+	if len(answers) == 0 {
+		return nil, negativeTTL(msg), newNotFoundError(name)
+	}
+
+	return answers, time.Duration(ttl) * time.Second, nil
+}
+
+func newQuery(id uint16, name dnsmessage.Name, qtype dnsmessage.Type) ([]byte, error) {
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(udpPayloadSize, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               id,
+			RecursionDesired: true,
+		},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+		Additionals: []dnsmessage.Resource{
+			{Header: opt, Body: &dnsmessage.OPTResource{}},
+		},
+	}
+
+	return msg.Pack()
+}
+
+func newID() (uint16, error) {
+	var b [2]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint16(b[:]), nil
+}
+
+// isResponseTo returns true when msg is the response to the question with id
+func isResponseTo(msg dnsmessage.Message, id uint16, name dnsmessage.Name, qtype dnsmessage.Type) bool {
+	if !msg.Response || msg.ID != id || len(msg.Questions) != 1 {
+		return false
+	}
+
+	q := msg.Questions[0]
+	return q.Type == qtype && strings.EqualFold(q.Name.String(), name.String())
+}
+
+// negativeTTL returns the TTL for negative answers, the lowest of the SOA's TTL and its minimum field (RFC 2308 §5)
+func negativeTTL(msg dnsmessage.Message) time.Duration {
+	for _, rr := range msg.Authorities {
+		if soa, ok := rr.Body.(*dnsmessage.SOAResource); ok {
+			ttl := rr.Header.TTL
+			if soa.MinTTL < ttl {
+				ttl = soa.MinTTL
+			}
+
+			return time.Duration(ttl) * time.Second
+		}
+	}
+
+	return 0
+}
+
+func newNotFoundError(name string) *net.DNSError {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// isNotFound returns true when the error is a DNS error stating that the name (or the requested record) doesn't exist
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+func isTimeout(ctx context.Context, err error) bool {
+	var netErr net.Error
+	return errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
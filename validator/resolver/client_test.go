@@ -0,0 +1,460 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// zone is the content of a dnsStandIn, records are keyed on the lower-cased FQDN and type
+type zone map[dnsmessage.Question][]dnsmessage.Resource
+
+type dnsStandIn struct {
+	lock     sync.Mutex
+	zone     zone
+	rcode    dnsmessage.RCode
+	truncate bool // Truncate UDP responses, forcing clients to use TCP
+	soaTTL   uint32
+	udp      net.PacketConn
+	tcp      net.Listener
+}
+
+// newDNSStandIn starts a DNS server on the loopback interface, serving both UDP and TCP on the same port
+func newDNSStandIn(t *testing.T, z zone) *dnsStandIn {
+	t.Helper()
+
+	s := &dnsStandIn{zone: z, soaTTL: 60}
+
+	var err error
+	for i := 0; i < 10; i++ {
+		s.udp, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unable to listen: %s", err)
+		}
+
+		s.tcp, err = net.Listen("tcp", s.udp.LocalAddr().String())
+		if err == nil {
+			break
+		}
+
+		_ = s.udp.Close()
+	}
+
+	if err != nil {
+		t.Fatalf("Unable to listen: %s", err)
+	}
+
+	t.Cleanup(func() {
+		_ = s.udp.Close()
+		_ = s.tcp.Close()
+	})
+
+	go s.serveUDP()
+	go s.serveTCP()
+
+	return s
+}
+
+// update changes the behaviour of the running server
+func (s *dnsStandIn) update(fn func(s *dnsStandIn)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	fn(s)
+}
+
+func (s *dnsStandIn) Addr() string {
+	return s.udp.LocalAddr().String()
+}
+
+func (s *dnsStandIn) serveUDP() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		truncate := s.truncate
+		s.lock.Unlock()
+
+		response, err := s.respond(buf[:n], truncate)
+		if err == nil {
+			_, _ = s.udp.WriteTo(response, addr)
+		}
+	}
+}
+
+func (s *dnsStandIn) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			_, _ = serveStream(conn, func(query []byte) ([]byte, error) {
+				return s.respond(query, false)
+			})
+		}()
+	}
+}
+
+func (s *dnsStandIn) respond(query []byte, truncate bool) ([]byte, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil || len(msg.Questions) != 1 {
+		return nil, errors.New("bad query")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	q := msg.Questions[0]
+	name := dnsmessage.MustNewName(lowerName(q.Name.String()))
+
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 msg.ID,
+			Response:           true,
+			RecursionAvailable: true,
+			RCode:              s.rcode,
+			Truncated:          truncate,
+		},
+		Questions: msg.Questions,
+	}
+
+	if s.rcode == dnsmessage.RCodeSuccess && !truncate {
+		answers, ok := s.zone[dnsmessage.Question{Name: name, Type: q.Type, Class: dnsmessage.ClassINET}]
+		response.Answers = answers
+
+		if !ok && !s.hasName(name) {
+			response.RCode = dnsmessage.RCodeNameError
+		}
+
+		if len(answers) == 0 {
+			response.Authorities = []dnsmessage.Resource{soa(s.soaTTL)}
+		}
+	}
+
+	return response.Pack()
+}
+
+func (s *dnsStandIn) hasName(name dnsmessage.Name) bool {
+	for q := range s.zone {
+		if q.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serveStream reads a single length-prefixed query and writes the response
+func serveStream(conn net.Conn, handler func([]byte) ([]byte, error)) (int, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return 0, err
+	}
+
+	query := make([]byte, int(length[0])<<8|int(length[1]))
+	if _, err := io.ReadFull(conn, query); err != nil {
+		return 0, err
+	}
+
+	response, err := handler(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return conn.Write(append([]byte{byte(len(response) >> 8), byte(len(response))}, response...))
+}
+
+func lowerName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+
+	return string(b)
+}
+
+func question(name string, qtype dnsmessage.Type) dnsmessage.Question {
+	return dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}
+}
+
+func rrHeader(name string, qtype dnsmessage.Type, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET, TTL: ttl}
+}
+
+func mxRR(name, host string, pref uint16, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: rrHeader(name, dnsmessage.TypeMX, ttl),
+		Body:   &dnsmessage.MXResource{Pref: pref, MX: dnsmessage.MustNewName(host)},
+	}
+}
+
+func aRR(name string, ip string, ttl uint32) dnsmessage.Resource {
+	var a [4]byte
+	copy(a[:], net.ParseIP(ip).To4())
+
+	return dnsmessage.Resource{
+		Header: rrHeader(name, dnsmessage.TypeA, ttl),
+		Body:   &dnsmessage.AResource{A: a},
+	}
+}
+
+func aaaaRR(name string, ip string, ttl uint32) dnsmessage.Resource {
+	var aaaa [16]byte
+	copy(aaaa[:], net.ParseIP(ip).To16())
+
+	return dnsmessage.Resource{
+		Header: rrHeader(name, dnsmessage.TypeAAAA, ttl),
+		Body:   &dnsmessage.AAAAResource{AAAA: aaaa},
+	}
+}
+
+func txtRR(name string, ttl uint32, txt ...string) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: rrHeader(name, dnsmessage.TypeTXT, ttl),
+		Body:   &dnsmessage.TXTResource{TXT: txt},
+	}
+}
+
+func tlsaRR(name string, usage, selector, matchingType byte, data []byte, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: rrHeader(name, typeTLSA, ttl),
+		Body:   &dnsmessage.UnknownResource{Type: typeTLSA, Data: append([]byte{usage, selector, matchingType}, data...)},
+	}
+}
+
+func soa(ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: rrHeader("example.org.", dnsmessage.TypeSOA, 3600),
+		Body: &dnsmessage.SOAResource{
+			NS:     dnsmessage.MustNewName("ns.example.org."),
+			MBox:   dnsmessage.MustNewName("hostmaster.example.org."),
+			MinTTL: ttl,
+		},
+	}
+}
+
+func testZone() zone {
+	return zone{
+		question("example.org.", dnsmessage.TypeMX): {
+			mxRR("example.org.", "mx2.example.org.", 20, 300),
+			mxRR("example.org.", "mx1.example.org.", 10, 600),
+		},
+		question("mx1.example.org.", dnsmessage.TypeA):    {aRR("mx1.example.org.", "192.0.2.1", 120)},
+		question("mx1.example.org.", dnsmessage.TypeAAAA): {aaaaRR("mx1.example.org.", "2001:db8::1", 60)},
+		question("mx2.example.org.", dnsmessage.TypeA):    {aRR("mx2.example.org.", "192.0.2.2", 120)},
+	}
+}
+
+func TestClient_LookupMX(t *testing.T) {
+	s := newDNSStandIn(t, testZone())
+	c := NewClient(NewUDPTransport(s.Addr()))
+
+	t.Run("found", func(t *testing.T) {
+		mxs, ttl, err := c.LookupMX(context.Background(), "Example.org")
+		if err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		want := []*net.MX{{Host: "mx1.example.org.", Pref: 10}, {Host: "mx2.example.org.", Pref: 20}}
+		if !reflect.DeepEqual(mxs, want) {
+			t.Errorf("Expected %v, got %v", want, mxs)
+		}
+
+		if ttl != 300*time.Second {
+			t.Errorf("Expected the lowest TTL of 300s, got %s", ttl)
+		}
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		_, ttl, err := c.LookupMX(context.Background(), "mx1.example.org")
+		if !isNotFound(err) {
+			t.Errorf("Expected a not found error, got %v", err)
+		}
+
+		if ttl != 60*time.Second {
+			t.Errorf("Expected the negative TTL of the SOA, got %s", ttl)
+		}
+	})
+
+	t.Run("no such domain", func(t *testing.T) {
+		_, _, err := c.LookupMX(context.Background(), "example.com")
+		if !isNotFound(err) {
+			t.Errorf("Expected a not found error, got %v", err)
+		}
+	})
+}
+
+func TestClient_LookupIPAddr(t *testing.T) {
+	s := newDNSStandIn(t, testZone())
+	c := NewClient(NewUDPTransport(s.Addr()))
+
+	tests := []struct {
+		name         string
+		host         string
+		want         []net.IPAddr
+		wantTTL      time.Duration
+		wantNotFound bool
+	}{
+		{name: "A and AAAA", host: "mx1.example.org", want: []net.IPAddr{{IP: net.ParseIP("192.0.2.1").To4()}, {IP: net.ParseIP("2001:db8::1")}}, wantTTL: 60 * time.Second},
+		{name: "A only", host: "mx2.example.org", want: []net.IPAddr{{IP: net.ParseIP("192.0.2.2").To4()}}, wantTTL: 60 * time.Second},
+		{name: "IP", host: "192.0.2.3", want: []net.IPAddr{{IP: net.ParseIP("192.0.2.3")}}},
+		{name: "not found", host: "mx3.example.org", wantNotFound: true, wantTTL: 60 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ips, ttl, err := c.LookupIPAddr(context.Background(), tt.host)
+			if isNotFound(err) != tt.wantNotFound {
+				t.Errorf("Expected not found: %t, got %v", tt.wantNotFound, err)
+			}
+
+			if !reflect.DeepEqual(ips, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, ips)
+			}
+
+			if ttl != tt.wantTTL {
+				t.Errorf("Expected TTL %s, got %s", tt.wantTTL, ttl)
+			}
+		})
+	}
+}
+
+func TestClient_LookupTXT(t *testing.T) {
+	s := newDNSStandIn(t, zone{
+		question("example.org.", dnsmessage.TypeTXT): {
+			txtRR("example.org.", 300, "v=spf1 ", "-all"),
+			txtRR("example.org.", 120, "google-site-verification=b0rk"),
+		},
+	})
+
+	c := NewClient(NewUDPTransport(s.Addr()))
+
+	txts, ttl, err := c.LookupTXT(context.Background(), "example.org")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	want := []string{"v=spf1 -all", "google-site-verification=b0rk"}
+	if !reflect.DeepEqual(txts, want) {
+		t.Errorf("Expected %q, got %q", want, txts)
+	}
+
+	if ttl != 120*time.Second {
+		t.Errorf("Expected the lowest TTL of 120s, got %s", ttl)
+	}
+
+	if _, _, err := c.LookupTXT(context.Background(), "_dmarc.example.org"); !isNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestClient_LookupTLSA(t *testing.T) {
+	s := newDNSStandIn(t, zone{
+		question("_25._tcp.mx1.example.org.", typeTLSA): {
+			tlsaRR("_25._tcp.mx1.example.org.", 3, 1, 1, []byte{0xde, 0xad, 0xbe, 0xef}, 300),
+			tlsaRR("_25._tcp.mx1.example.org.", 2, 0, 1, []byte{0x0c, 0x72}, 300),
+		},
+	})
+
+	c := NewClient(NewUDPTransport(s.Addr()))
+
+	records, _, err := c.LookupTLSA(context.Background(), "_25._tcp.mx1.example.org")
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	want := []string{"3 1 1 deadbeef", "2 0 1 0c72"}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Expected %q, got %q", want, records)
+	}
+
+	if _, _, err := c.LookupTLSA(context.Background(), "_25._tcp.mx2.example.org"); !isNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestClient_errors(t *testing.T) {
+	t.Run("server failure", func(t *testing.T) {
+		s := newDNSStandIn(t, testZone())
+		s.update(func(s *dnsStandIn) {
+			s.rcode = dnsmessage.RCodeServerFailure
+		})
+
+		_, _, err := NewClient(NewUDPTransport(s.Addr())).LookupMX(context.Background(), "example.org")
+
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsTemporary || dnsErr.IsNotFound {
+			t.Errorf("Expected a temporary DNS error, got %#v", err)
+		}
+	})
+
+	t.Run("truncated responses are retried over TCP", func(t *testing.T) {
+		s := newDNSStandIn(t, testZone())
+		s.update(func(s *dnsStandIn) {
+			s.truncate = true
+		})
+
+		mxs, _, err := NewClient(NewUDPTransport(s.Addr())).LookupMX(context.Background(), "example.org")
+		if err != nil || len(mxs) != 2 {
+			t.Errorf("Expected 2 MX records, got %v (%v)", mxs, err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		// Nobody answers on a bound, but unserved, UDP socket
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unable to listen: %s", err)
+		}
+
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, _, err = NewClient(NewUDPTransport(conn.LocalAddr().String())).LookupMX(ctx, "example.org")
+
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsTimeout {
+			t.Errorf("Expected a timeout, got %#v", err)
+		}
+	})
+}
+
+func Test_withDefaultPort(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{address: "192.0.2.1", want: "192.0.2.1:53"},
+		{address: "192.0.2.1:5353", want: "192.0.2.1:5353"},
+		{address: "2001:db8::1", want: "[2001:db8::1]:53"},
+		{address: "[2001:db8::1]", want: "[2001:db8::1]:53"},
+		{address: "[2001:db8::1]:5353", want: "[2001:db8::1]:5353"},
+		{address: "dns.example.org", want: "dns.example.org:53"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if got := withDefaultPort(tt.address, "53"); got != tt.want {
+				t.Errorf("withDefaultPort(%q) = %q, want %q", tt.address, got, tt.want)
+			}
+		})
+	}
+}
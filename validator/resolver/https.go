@@ -0,0 +1,73 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const dnsMessageContentType = "application/dns-message"
+
+// NewHTTPSTransport creates a DNS-over-HTTPS (RFC 8484) transport for the URL of a server, e.g.:
+// "https://dns.example.org/dns-query". Queries are sent with POST. A nil client uses a client with the default HTTP
+// transport, the host of the URL is resolved with the system's resolver.
+func NewHTTPSTransport(url string, client *http.Client) Transport {
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &httpsTransport{
+		url:    url,
+		client: client,
+	}
+}
+
+type httpsTransport struct {
+	url    string
+	client *http.Client
+}
+
+func (t *httpsTransport) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", dnsMessageContentType)
+	req.Header.Set("Accept", dnsMessageContentType)
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status %q from %s", res.Status, t.url)
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type")); err != nil || mediaType != dnsMessageContentType {
+		return nil, fmt.Errorf("unexpected content type %q from %s", res.Header.Get("Content-Type"), t.url)
+	}
+
+	// A DNS message is at most 65535 octets, anything beyond that isn't a DNS message
+	response, err := io.ReadAll(io.LimitReader(res.Body, 65536))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) > 65535 {
+		return nil, ErrInvalidResponse
+	}
+
+	return response, nil
+}
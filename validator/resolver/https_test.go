@@ -0,0 +1,96 @@
+package resolver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newDoHStandIn serves the zone of s over HTTPS, handler can override the responses
+func newDoHStandIn(t *testing.T, s *dnsStandIn, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handler != nil {
+			handler(w, r)
+			return
+		}
+
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessageContentType {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		query, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response, err := s.respond(query, false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", dnsMessageContentType)
+		_, _ = w.Write(response)
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestHTTPSTransport(t *testing.T) {
+	s := newDNSStandIn(t, testZone())
+
+	t.Run("answers", func(t *testing.T) {
+		srv := newDoHStandIn(t, s, nil)
+		c := NewClient(NewHTTPSTransport(srv.URL+"/dns-query", srv.Client()))
+
+		mxs, _, err := c.LookupMX(context.Background(), "example.org")
+		if err != nil || len(mxs) != 2 {
+			t.Errorf("Expected 2 MX records, got %v (%v)", mxs, err)
+		}
+
+		if _, _, err := c.LookupMX(context.Background(), "example.com"); !isNotFound(err) {
+			t.Errorf("Expected a not found error, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "HTTP error", handler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "b0rk", http.StatusBadGateway)
+		}},
+		{name: "not a DNS message", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html></html>"))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDoHStandIn(t, s, tt.handler)
+
+			_, err := NewHTTPSTransport(srv.URL, srv.Client()).Exchange(context.Background(), testQuery(t))
+			if err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		srv := newDoHStandIn(t, s, nil)
+
+		_, err := NewHTTPSTransport(srv.URL, nil).Exchange(context.Background(), testQuery(t))
+		if err == nil {
+			t.Errorf("Expected the certificate to be rejected")
+		}
+	})
+}
@@ -0,0 +1,278 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoUpstreams = errors.New("no upstream resolvers")
+
+const (
+	DefaultAttemptTimeout = 2 * time.Second
+	DefaultMaxFailures    = 3
+	DefaultDemotion       = 30 * time.Second
+)
+
+// Strategy defines how a Pool spreads a query over its upstreams
+type Strategy uint8
+
+const (
+	// StrategyFailover sends a query to one upstream at a time, the next one is tried when it fails
+	StrategyFailover Strategy = iota
+
+	// StrategyRace sends a query to the first two upstreams at once and uses the first usable response. The other
+	// upstreams are tried one at a time, when both fail.
+	StrategyRace
+)
+
+const (
+	strategyFailover = "failover"
+	strategyRace     = "race"
+)
+
+// ParseStrategy parses the name of a strategy: "failover" or "race"
+func ParseStrategy(name string) (Strategy, error) {
+	switch strings.ToLower(name) {
+	case strategyFailover:
+		return StrategyFailover, nil
+	case strategyRace:
+		return StrategyRace, nil
+	}
+
+	return StrategyFailover, fmt.Errorf("unsupported resolver strategy %q, expecting %q or %q", name, strategyFailover, strategyRace)
+}
+
+func (s Strategy) String() string {
+	if s == StrategyRace {
+		return strategyRace
+	}
+
+	return strategyFailover
+}
+
+// PoolOption configures a Pool
+type PoolOption func(p *Pool)
+
+// WithStrategy defines how queries are spread over the upstreams, StrategyFailover is used by default
+func WithStrategy(s Strategy) PoolOption {
+	return func(p *Pool) {
+		p.strategy = s
+	}
+}
+
+// WithAttemptTimeout limits the time a single upstream gets to respond, before the next one is tried. A timeout of 0
+// leaves it to the context of the query.
+func WithAttemptTimeout(d time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.attemptTimeout = d
+	}
+}
+
+// WithDemotion demotes an upstream for duration, after maxFailures consecutive failures. A demoted upstream is only
+// tried after the healthy ones. When it fails again after the duration passed, it's demoted again straight away.
+func WithDemotion(maxFailures int, duration time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.maxFailures = maxFailures
+		p.demotion = duration
+	}
+}
+
+// NewPool creates a Transport that spreads queries over multiple upstreams, in the order given
+func NewPool(upstreams []Transport, options ...PoolOption) *Pool {
+	p := Pool{
+		upstreams:      make([]*poolUpstream, len(upstreams)),
+		attemptTimeout: DefaultAttemptTimeout,
+		maxFailures:    DefaultMaxFailures,
+		demotion:       DefaultDemotion,
+		now:            time.Now,
+	}
+
+	for i, t := range upstreams {
+		p.upstreams[i] = &poolUpstream{transport: t}
+	}
+
+	for _, o := range options {
+		o(&p)
+	}
+
+	return &p
+}
+
+// NewPoolFromAddresses creates a Pool with a transport for each address, see NewTransport
+func NewPoolFromAddresses(addresses []string, options ...PoolOption) (*Pool, error) {
+	upstreams := make([]Transport, 0, len(addresses))
+	for _, address := range addresses {
+		t, err := NewTransport(address)
+		if err != nil {
+			return nil, err
+		}
+
+		upstreams = append(upstreams, t)
+	}
+
+	if len(upstreams) == 0 {
+		return nil, ErrNoUpstreams
+	}
+
+	return NewPool(upstreams, options...), nil
+}
+
+// Pool is a Transport for multiple upstreams. It tracks the health of each upstream, an upstream that keeps failing
+// is demoted for a while. Timeouts, network errors and SERVFAIL or REFUSED responses count as failures, "not found"
+// answers don't. It's safe for concurrent use.
+type Pool struct {
+	upstreams      []*poolUpstream
+	strategy       Strategy
+	attemptTimeout time.Duration
+	maxFailures    int
+	demotion       time.Duration
+	now            func() time.Time
+
+	lock sync.Mutex
+}
+
+type poolUpstream struct {
+	transport    Transport
+	failures     int
+	demotedUntil time.Time
+}
+
+// Exchange sends the query to the upstreams, according to the strategy. When no upstream produced a usable response,
+// the last SERVFAIL or REFUSED response is returned, so that callers learn about it. Otherwise the last error is.
+func (p *Pool) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	upstreams := p.ordered()
+	if len(upstreams) == 0 {
+		return nil, ErrNoUpstreams
+	}
+
+	var response []byte
+	var err error
+	if p.strategy == StrategyRace && len(upstreams) > 1 {
+		response, err = p.race(ctx, query, upstreams[0], upstreams[1])
+		upstreams = upstreams[2:]
+	} else {
+		response, err = p.attempt(ctx, query, upstreams[0])
+		upstreams = upstreams[1:]
+	}
+
+	for _, u := range upstreams {
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+
+		response, err = p.attempt(ctx, query, u)
+	}
+
+	if err != nil && response != nil {
+		return response, nil
+	}
+
+	return response, err
+}
+
+// race sends the query to a and b at once, the first usable response wins and the other attempt is cancelled
+func (p *Pool) race(ctx context.Context, query []byte, a, b *poolUpstream) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		response []byte
+		err      error
+	}
+
+	results := make(chan result, 2)
+	for _, u := range []*poolUpstream{a, b} {
+		go func(u *poolUpstream) {
+			response, err := p.attempt(ctx, query, u)
+			results <- result{response: response, err: err}
+		}(u)
+	}
+
+	var last result
+	for i := 0; i < 2; i++ {
+		last = <-results
+		if last.err == nil {
+			return last.response, nil
+		}
+	}
+
+	return last.response, last.err
+}
+
+// attempt sends the query to a single upstream and keeps track of its health. A SERVFAIL or REFUSED response is
+// returned along with an error.
+func (p *Pool) attempt(ctx context.Context, query []byte, u *poolUpstream) ([]byte, error) {
+	attemptCtx := ctx
+	if p.attemptTimeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, p.attemptTimeout)
+		defer cancel()
+	}
+
+	response, err := u.transport.Exchange(attemptCtx, query)
+	if err == nil && isServerFailure(response) {
+		err = ErrServerFailure
+	}
+
+	// When the query itself was cancelled or timed out (e.g. a lost race), the upstream isn't to blame
+	if ctx.Err() == nil {
+		p.report(u, err == nil)
+	}
+
+	return response, err
+}
+
+func (p *Pool) report(u *poolUpstream, ok bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if ok {
+		u.failures = 0
+		u.demotedUntil = time.Time{}
+		return
+	}
+
+	u.failures++
+	if p.maxFailures > 0 && u.failures >= p.maxFailures {
+		u.demotedUntil = p.now().Add(p.demotion)
+	}
+}
+
+// ordered returns the upstreams in the order to try them: the healthy ones as configured, followed by the demoted ones
+// that will be promoted first
+func (p *Pool) ordered() []*poolUpstream {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	healthy := make([]*poolUpstream, 0, len(p.upstreams))
+	var demoted []*poolUpstream
+	for _, u := range p.upstreams {
+		if now.Before(u.demotedUntil) {
+			demoted = append(demoted, u)
+		} else {
+			healthy = append(healthy, u)
+		}
+	}
+
+	sort.SliceStable(demoted, func(i, j int) bool {
+		return demoted[i].demotedUntil.Before(demoted[j].demotedUntil)
+	})
+
+	return append(healthy, demoted...)
+}
+
+// isServerFailure returns true when the RCODE of a DNS message is SERVFAIL (2) or REFUSED (5)
+func isServerFailure(msg []byte) bool {
+	if len(msg) < 4 {
+		return false
+	}
+
+	rcode := msg[3] & 0x0F
+	return rcode == 2 || rcode == 5
+}
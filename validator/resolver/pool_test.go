@@ -0,0 +1,290 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type stubTransport struct {
+	rcode dnsmessage.RCode
+	err   error
+	delay time.Duration
+
+	lock  sync.Mutex
+	calls int
+}
+
+func (s *stubTransport) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	s.lock.Lock()
+	s.calls++
+	s.lock.Unlock()
+
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		return nil, err
+	}
+
+	msg.Response = true
+	msg.RCode = s.rcode
+	msg.Additionals = nil
+	return msg.Pack()
+}
+
+func (s *stubTransport) Calls() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.calls
+}
+
+func testQuery(t *testing.T) []byte {
+	t.Helper()
+
+	query, err := newQuery(1, dnsmessage.MustNewName("example.org."), dnsmessage.TypeMX)
+	if err != nil {
+		t.Fatalf("Unable to create a query: %s", err)
+	}
+
+	return query
+}
+
+func rcodeOf(t *testing.T, response []byte) dnsmessage.RCode {
+	t.Helper()
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(response); err != nil {
+		t.Fatalf("Unable to unpack the response: %s", err)
+	}
+
+	return msg.RCode
+}
+
+func TestPool_Exchange(t *testing.T) {
+	b0rk := errors.New("b0rk")
+
+	tests := []struct {
+		name      string
+		upstreams []*stubTransport
+		strategy  Strategy
+		wantRCode dnsmessage.RCode
+		wantErr   error
+		wantCalls []int
+	}{
+		{
+			name:      "first upstream answers",
+			upstreams: []*stubTransport{{}, {}},
+			wantCalls: []int{1, 0},
+		},
+		{
+			name:      "not found is an answer",
+			upstreams: []*stubTransport{{rcode: dnsmessage.RCodeNameError}, {}},
+			wantRCode: dnsmessage.RCodeNameError,
+			wantCalls: []int{1, 0},
+		},
+		{
+			name:      "fails over on errors",
+			upstreams: []*stubTransport{{err: b0rk}, {}},
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "fails over on SERVFAIL and REFUSED",
+			upstreams: []*stubTransport{{rcode: dnsmessage.RCodeServerFailure}, {rcode: dnsmessage.RCodeRefused}, {}},
+			wantCalls: []int{1, 1, 1},
+		},
+		{
+			name:      "fails over on timeouts",
+			upstreams: []*stubTransport{{delay: time.Second}, {}},
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "last SERVFAIL is returned",
+			upstreams: []*stubTransport{{err: b0rk}, {rcode: dnsmessage.RCodeServerFailure}},
+			wantRCode: dnsmessage.RCodeServerFailure,
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "last error is returned",
+			upstreams: []*stubTransport{{rcode: dnsmessage.RCodeServerFailure}, {err: b0rk}},
+			wantErr:   b0rk,
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "race the first two",
+			strategy:  StrategyRace,
+			upstreams: []*stubTransport{{delay: 500 * time.Millisecond}, {}, {}},
+			wantCalls: []int{1, 1, 0},
+		},
+		{
+			name:      "race falls back on the others",
+			strategy:  StrategyRace,
+			upstreams: []*stubTransport{{err: b0rk}, {rcode: dnsmessage.RCodeServerFailure}, {}},
+			wantCalls: []int{1, 1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstreams := make([]Transport, len(tt.upstreams))
+			for i, u := range tt.upstreams {
+				upstreams[i] = u
+			}
+
+			p := NewPool(upstreams, WithStrategy(tt.strategy), WithAttemptTimeout(50*time.Millisecond))
+
+			start := time.Now()
+			response, err := p.Exchange(context.Background(), testQuery(t))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if err == nil && rcodeOf(t, response) != tt.wantRCode {
+				t.Errorf("Expected RCODE %s, got %s", tt.wantRCode, rcodeOf(t, response))
+			}
+
+			if time.Since(start) > 400*time.Millisecond {
+				t.Errorf("Expected slow upstreams to be given up on, it took %s", time.Since(start))
+			}
+
+			for i, u := range tt.upstreams {
+				// The loser of a race isn't waited for
+				deadline := time.Now().Add(time.Second)
+				for u.Calls() < tt.wantCalls[i] && time.Now().Before(deadline) {
+					time.Sleep(time.Millisecond)
+				}
+
+				if u.Calls() != tt.wantCalls[i] {
+					t.Errorf("Expected upstream %d to be called %d time(s), got %d", i, tt.wantCalls[i], u.Calls())
+				}
+			}
+		})
+	}
+}
+
+func TestPool_demotion(t *testing.T) {
+	flaky := &stubTransport{err: errors.New("b0rk")}
+	healthy := &stubTransport{}
+
+	clock := &stubClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	p := NewPool([]Transport{flaky, healthy}, WithDemotion(2, time.Minute))
+	p.now = clock.Now
+
+	exchange := func() {
+		if _, err := p.Exchange(context.Background(), testQuery(t)); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+	}
+
+	exchange()
+	exchange()
+	if flaky.Calls() != 2 {
+		t.Fatalf("Expected the flaky upstream to be tried first, until demoted, got %d calls", flaky.Calls())
+	}
+
+	exchange()
+	if flaky.Calls() != 2 || healthy.Calls() != 3 {
+		t.Errorf("Expected the flaky upstream to be demoted, got %d and %d calls", flaky.Calls(), healthy.Calls())
+	}
+
+	// After the demotion, it's tried again and demoted straight away when it still fails
+	clock.now = clock.now.Add(time.Minute)
+	exchange()
+	exchange()
+	if flaky.Calls() != 3 {
+		t.Errorf("Expected the flaky upstream to be tried once after its demotion, got %d calls", flaky.Calls())
+	}
+
+	// Once it recovers, it's back in its place
+	clock.now = clock.now.Add(time.Minute)
+	flaky.lock.Lock()
+	flaky.err = nil
+	flaky.lock.Unlock()
+
+	exchange()
+	exchange()
+	if flaky.Calls() != 5 || healthy.Calls() != 5 {
+		t.Errorf("Expected the recovered upstream to be used first, got %d and %d calls", flaky.Calls(), healthy.Calls())
+	}
+}
+
+func TestPool_lostRacesArentFailures(t *testing.T) {
+	slow := &stubTransport{delay: 100 * time.Millisecond}
+	p := NewPool([]Transport{slow, &stubTransport{}}, WithStrategy(StrategyRace), WithDemotion(1, time.Hour))
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Exchange(context.Background(), testQuery(t)); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+	}
+
+	if u := p.ordered(); u[0].transport != slow {
+		t.Errorf("Expected the slower upstream to keep its place")
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Strategy
+		wantErr bool
+	}{
+		{name: "failover", want: StrategyFailover},
+		{name: "Race", want: StrategyRace},
+		{name: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStrategy(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStrategy() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseStrategy() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPoolFromAddresses(t *testing.T) {
+	primary := newDNSStandIn(t, testZone())
+	secondary := newDNSStandIn(t, testZone())
+	primary.update(func(s *dnsStandIn) {
+		s.rcode = dnsmessage.RCodeServerFailure
+	})
+
+	p, err := NewPoolFromAddresses([]string{primary.Addr(), secondary.Addr()})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	mxs, _, err := NewClient(p).LookupMX(context.Background(), "example.org")
+	if err != nil || len(mxs) != 2 {
+		t.Errorf("Expected the secondary to answer, got %v (%v)", mxs, err)
+	}
+
+	if _, err := NewPoolFromAddresses(nil); !errors.Is(err, ErrNoUpstreams) {
+		t.Errorf("Expected an error without addresses, got %v", err)
+	}
+
+	if _, err := NewPoolFromAddresses([]string{"192.0.2.1:dns"}); err == nil {
+		t.Errorf("Expected an error for an invalid address")
+	}
+}
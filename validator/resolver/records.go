@@ -0,0 +1,30 @@
+package resolver
+
+import (
+	"context"
+)
+
+// NewRecords creates a resolver for the TXT and TLSA records of a domain, as used by the posture checks of the
+// validator. Unlike Cache, the answers aren't cached.
+func NewRecords(t Transport) *Records {
+	return &Records{
+		client: NewClient(t),
+	}
+}
+
+// Records resolves TXT and TLSA records. Unlike *net.Resolver, which only resolves the former, it supports DANE.
+type Records struct {
+	client *Client
+}
+
+// LookupTXT returns the TXT records of name
+func (r *Records) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	txts, _, err := r.client.LookupTXT(ctx, name)
+	return txts, err
+}
+
+// LookupTLSA returns the TLSA records of name in presentation format, see Client.LookupTLSA
+func (r *Records) LookupTLSA(ctx context.Context, name string) ([]string, error) {
+	records, _, err := r.client.LookupTLSA(ctx, name)
+	return records, err
+}
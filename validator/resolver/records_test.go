@@ -0,0 +1,33 @@
+package resolver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestRecords(t *testing.T) {
+	s := newDNSStandIn(t, zone{
+		question("example.org.", dnsmessage.TypeTXT):        {txtRR("example.org.", 300, "v=spf1 mx -all")},
+		question("_dmarc.example.org.", dnsmessage.TypeTXT): {txtRR("_dmarc.example.org.", 300, "v=DMARC1; p=reject")},
+		question("_25._tcp.mx1.example.org.", typeTLSA):     {tlsaRR("_25._tcp.mx1.example.org.", 3, 1, 1, []byte{0xde, 0xad}, 300)},
+	})
+
+	r := NewRecords(NewUDPTransport(s.Addr()))
+
+	txts, err := r.LookupTXT(context.Background(), "_dmarc.example.org")
+	if err != nil || !reflect.DeepEqual(txts, []string{"v=DMARC1; p=reject"}) {
+		t.Errorf("Expected the DMARC record, got %q (%v)", txts, err)
+	}
+
+	records, err := r.LookupTLSA(context.Background(), "_25._tcp.mx1.example.org")
+	if err != nil || !reflect.DeepEqual(records, []string{"3 1 1 dead"}) {
+		t.Errorf("Expected the TLSA record, got %q (%v)", records, err)
+	}
+
+	if _, err := r.LookupTXT(context.Background(), "_mta-sts.example.org"); !isNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
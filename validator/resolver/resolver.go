@@ -0,0 +1,57 @@
+// Package resolver offers DNS resolvers for the validator. Unlike net.Resolver they know the TTL of an answer, which
+// allows answers to be cached for as long as they're valid. See Cache.
+package resolver
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// DefaultTTL is used for answers of resolvers that don't report a TTL, see FixedTTL
+const DefaultTTL = 5 * time.Minute
+
+// Upstream resolves names and reports for how long the answer may be cached. For "not found" errors, the duration is
+// the negative caching TTL of the zone, or 0 when unknown.
+type Upstream interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, time.Duration, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error)
+}
+
+// Resolver is satisfied by *net.Resolver and matches validator.Resolver
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// FixedTTL turns a resolver that hides TTLs, such as net.DefaultResolver, into an Upstream. Every answer is reported
+// with ttl and negative answers with 0.
+func FixedTTL(r Resolver, ttl time.Duration) Upstream {
+	return fixedTTL{
+		r:   r,
+		ttl: ttl,
+	}
+}
+
+type fixedTTL struct {
+	r   Resolver
+	ttl time.Duration
+}
+
+func (f fixedTTL) LookupMX(ctx context.Context, name string) ([]*net.MX, time.Duration, error) {
+	mxs, err := f.r.LookupMX(ctx, name)
+	if err != nil {
+		return mxs, 0, err
+	}
+
+	return mxs, f.ttl, nil
+}
+
+func (f fixedTTL) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	ips, err := f.r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return ips, 0, err
+	}
+
+	return ips, f.ttl, nil
+}
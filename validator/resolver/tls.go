@@ -0,0 +1,87 @@
+package resolver
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// maxIdleTLSConns is the number of connections a TLS transport keeps open for reuse, a handshake per query is costly
+const maxIdleTLSConns = 4
+
+// NewTLSTransport creates a DNS-over-TLS (RFC 7858) transport for server, e.g.: "dns.example.org" or
+// "192.0.2.1:853". The port defaults to 853. A nil config verifies the server's certificate against the system's
+// roots, for the host of server.
+func NewTLSTransport(server string, config *tls.Config) Transport {
+	server = withDefaultPort(server, "853")
+
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(server)
+	}
+
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	return &tlsTransport{
+		server: server,
+		dialer: &tls.Dialer{Config: config},
+		idle:   make(chan net.Conn, maxIdleTLSConns),
+	}
+}
+
+type tlsTransport struct {
+	server string
+	dialer *tls.Dialer
+	idle   chan net.Conn
+}
+
+func (t *tlsTransport) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	// An idle connection might have been closed by the server in the meantime, in which case a new one is used
+	select {
+	case conn := <-t.idle:
+		if response, err := t.exchange(ctx, conn, query); err == nil {
+			return response, nil
+		}
+	default:
+	}
+
+	conn, err := t.dialer.DialContext(ctx, "tcp", t.server)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.exchange(ctx, conn, query)
+}
+
+// exchange sends the query over conn and returns conn to the idle connections when it went well, or closes it
+func (t *tlsTransport) exchange(ctx context.Context, conn net.Conn, query []byte) ([]byte, error) {
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	response, err := exchangeStream(conn, query)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	select {
+	case t.idle <- conn:
+	default:
+		_ = conn.Close()
+	}
+
+	return response, nil
+}
@@ -0,0 +1,145 @@
+package resolver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestCertificate creates a self-signed certificate for 127.0.0.1 and a pool that trusts it
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to create a key: %s", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "resolver stand-in"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create a certificate: %s", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Unable to parse the certificate: %s", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, roots
+}
+
+type dotStandIn struct {
+	listener net.Listener
+	accepted int32
+}
+
+// newDoTStandIn serves the zone of s over TLS, connections are kept open for multiple queries
+func newDoTStandIn(t *testing.T, s *dnsStandIn, cert tls.Certificate) *dotStandIn {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("Unable to listen: %s", err)
+	}
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	d := &dotStandIn{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			atomic.AddInt32(&d.accepted, 1)
+			go func() {
+				defer conn.Close()
+				for {
+					if _, err := serveStream(conn, func(query []byte) ([]byte, error) {
+						return s.respond(query, false)
+					}); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return d
+}
+
+func (d *dotStandIn) Addr() string {
+	return d.listener.Addr().String()
+}
+
+func (d *dotStandIn) Accepted() int {
+	return int(atomic.LoadInt32(&d.accepted))
+}
+
+func TestTLSTransport(t *testing.T) {
+	cert, roots := newTestCertificate(t)
+	d := newDoTStandIn(t, newDNSStandIn(t, testZone()), cert)
+
+	c := NewClient(NewTLSTransport(d.Addr(), &tls.Config{RootCAs: roots}))
+	for i := 0; i < 3; i++ {
+		mxs, _, err := c.LookupMX(context.Background(), "example.org")
+		if err != nil || len(mxs) != 2 {
+			t.Fatalf("Expected 2 MX records, got %v (%v)", mxs, err)
+		}
+	}
+
+	if d.Accepted() != 1 {
+		t.Errorf("Expected the connection to be reused, got %d connections", d.Accepted())
+	}
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		_, _, err := NewClient(NewTLSTransport(d.Addr(), nil)).LookupMX(context.Background(), "example.org")
+		if err == nil {
+			t.Errorf("Expected the certificate to be rejected")
+		}
+	})
+
+	t.Run("closed connections are replaced", func(t *testing.T) {
+		transport := NewTLSTransport(d.Addr(), &tls.Config{RootCAs: roots}).(*tlsTransport)
+		c := NewClient(transport)
+		if _, _, err := c.LookupMX(context.Background(), "example.org"); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		conn := <-transport.idle
+		_ = conn.Close()
+		transport.idle <- conn
+
+		if _, _, err := c.LookupMX(context.Background(), "example.org"); err != nil {
+			t.Errorf("Expected a new connection to be used, got %s", err)
+		}
+	})
+}
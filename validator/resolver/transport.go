@@ -0,0 +1,306 @@
+package resolver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTimeout limits an exchange when the context has no deadline
+const defaultTimeout = 5 * time.Second
+
+// Transport sends a DNS query message to a server and returns its response message
+type Transport interface {
+	Exchange(ctx context.Context, query []byte) ([]byte, error)
+}
+
+// NewTransport creates a transport for the resolver at address, which is either:
+//   - a host with an optional port, for plain DNS, e.g.: "192.0.2.1", "192.0.2.1:5353" or "[2001:db8::1]:5353"
+//   - a DNS-over-TLS URL, the port defaults to 853, e.g.: "tls://dns.example.org" or "tls://192.0.2.1:853"
+//   - a DNS-over-HTTPS URL, e.g.: "https://dns.example.org/dns-query"
+func NewTransport(address string) (Transport, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return nil, errors.New("empty resolver address")
+	}
+
+	if !strings.Contains(address, "://") {
+		if err := validateHostPort(address, "53"); err != nil {
+			return nil, err
+		}
+
+		return NewUDPTransport(address), nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resolver address %q: %w", address, err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "tls":
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+			return nil, fmt.Errorf("invalid resolver address %q, expecting tls://<host>[:port]", address)
+		}
+
+		if err := validateHostPort(u.Host, "853"); err != nil {
+			return nil, err
+		}
+
+		return NewTLSTransport(u.Host, nil), nil
+	case "https":
+		if u.Host == "" || u.User != nil {
+			return nil, fmt.Errorf("invalid resolver address %q, expecting https://<host>[:port]/<path>", address)
+		}
+
+		return NewHTTPSTransport(u.String(), nil), nil
+	}
+
+	return nil, fmt.Errorf("unsupported resolver address %q, expecting a host, tls:// or https://", address)
+}
+
+// validateHostPort verifies that address is a host with an optional port
+func validateHostPort(address, defaultPort string) error {
+	host, port, err := net.SplitHostPort(withDefaultPort(address, defaultPort))
+	if err != nil {
+		return fmt.Errorf("invalid resolver address %q: %w", address, err)
+	}
+
+	if n, err := strconv.ParseUint(port, 10, 16); host == "" || err != nil || n == 0 {
+		return fmt.Errorf("invalid resolver address %q, expecting a host with an optional port", address)
+	}
+
+	return nil
+}
+
+// NewUDPTransport creates a transport for a (recursive) DNS server, e.g.: "192.0.2.1" or "[2001:db8::1]:5353". The
+// port defaults to 53. Truncated responses are retried over TCP.
+func NewUDPTransport(server string) Transport {
+	return &udpTransport{
+		server: withDefaultPort(server, "53"),
+	}
+}
+
+type udpTransport struct {
+	server string
+	dialer net.Dialer
+}
+
+func (t *udpTransport) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	response, err := t.exchange(ctx, "udp", query)
+	if err == nil && isTruncated(response) {
+		return t.exchange(ctx, "tcp", query)
+	}
+
+	return response, err
+}
+
+func (t *udpTransport) exchange(ctx context.Context, network string, query []byte) ([]byte, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	conn, err := t.dialer.DialContext(ctx, network, t.server)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if network == "tcp" {
+		return exchangeStream(conn, query)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	// Responses with another ID are stale or spoofed, those are skipped until the deadline passes
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		if n >= 2 && len(query) >= 2 && buf[0] == query[0] && buf[1] == query[1] {
+			return buf[:n], nil
+		}
+	}
+}
+
+// exchangeStream exchanges a message over a stream, with the two octet length prefix of RFC 1035 §4.2.2
+func exchangeStream(conn io.ReadWriter, query []byte) ([]byte, error) {
+	if len(query) > 65535 {
+		return nil, errors.New("query too large")
+	}
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// withDefaultTimeout applies defaultTimeout when the context has no deadline
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, hasDeadline := ctx.Deadline(); hasDeadline {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, defaultTimeout)
+}
+
+// isTruncated returns true when the TC flag of a DNS message is set
+func isTruncated(msg []byte) bool {
+	return len(msg) >= 3 && msg[2]&0x02 != 0
+}
+
+// withDefaultPort adds port to the address, when it doesn't have one
+func withDefaultPort(address, port string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+
+	return net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(address, "["), "]"), port)
+}
+
+// DialFunc returns a Dial function for net.Resolver, that sends the queries of the (pure Go) resolver over t instead of
+// to the address it picked. Use it with PreferGo, so that host lookups (e.g. when dialing) use the same transport.
+func DialFunc(t Transport) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return &transportConn{ctx: ctx, transport: t}, nil
+	}
+}
+
+// transportConn is a net.Conn that speaks the stream format of RFC 1035 §4.2.2. Each length prefixed query that's
+// written is exchanged over the transport and its response is made available for reading.
+type transportConn struct {
+	ctx       context.Context
+	transport Transport
+
+	lock     sync.Mutex
+	deadline time.Time
+	written  []byte
+	unread   []byte
+}
+
+func (c *transportConn) Write(b []byte) (int, error) {
+	c.lock.Lock()
+	c.written = append(c.written, b...)
+	c.lock.Unlock()
+
+	for {
+		c.lock.Lock()
+		if len(c.written) < 2 || len(c.written) < 2+int(binary.BigEndian.Uint16(c.written)) {
+			c.lock.Unlock()
+			return len(b), nil
+		}
+
+		size := 2 + int(binary.BigEndian.Uint16(c.written))
+		query := append([]byte(nil), c.written[2:size]...)
+		c.written = c.written[size:]
+		deadline := c.deadline
+		c.lock.Unlock()
+
+		ctx := c.ctx
+		if !deadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+
+		response, err := c.transport.Exchange(ctx, query)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(response) > 65535 {
+			return 0, errors.New("response too large")
+		}
+
+		c.lock.Lock()
+		c.unread = binary.BigEndian.AppendUint16(c.unread, uint16(len(response)))
+		c.unread = append(c.unread, response...)
+		c.lock.Unlock()
+	}
+}
+
+func (c *transportConn) Read(b []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.unread) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, c.unread)
+	c.unread = c.unread[n:]
+	return n, nil
+}
+
+func (c *transportConn) SetDeadline(t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.deadline = t
+	return nil
+}
+
+func (c *transportConn) SetReadDeadline(_ time.Time) error {
+	return nil
+}
+
+func (c *transportConn) SetWriteDeadline(t time.Time) error {
+	return c.SetDeadline(t)
+}
+
+func (c *transportConn) Close() error {
+	return nil
+}
+
+func (c *transportConn) LocalAddr() net.Addr {
+	return transportAddr{}
+}
+
+func (c *transportConn) RemoteAddr() net.Addr {
+	return transportAddr{}
+}
+
+type transportAddr struct{}
+
+func (transportAddr) Network() string {
+	return "transport"
+}
+
+func (transportAddr) String() string {
+	return "transport"
+}
@@ -0,0 +1,82 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+		wantErr bool
+	}{
+		{address: "192.0.2.1", want: "192.0.2.1:53"},
+		{address: " 192.0.2.1:5353 ", want: "192.0.2.1:5353"},
+		{address: "[2001:db8::1]:5353", want: "[2001:db8::1]:5353"},
+		{address: "2001:db8::1", want: "[2001:db8::1]:53"},
+		{address: "dns.example.org", want: "dns.example.org:53"},
+		{address: "", wantErr: true},
+		{address: "192.0.2.1:dns", wantErr: true},
+		{address: "192.0.2.1:0", wantErr: true},
+		{address: "192.0.2.1:65536", wantErr: true},
+		{address: ":53", wantErr: true},
+		{address: "tls://dns.example.org", want: "tls dns.example.org:853"},
+		{address: "tls://[2001:db8::1]:8853", want: "tls [2001:db8::1]:8853"},
+		{address: "tls://dns.example.org/dns-query", wantErr: true},
+		{address: "tls://:853", wantErr: true},
+		{address: "https://dns.example.org/dns-query", want: "https https://dns.example.org/dns-query"},
+		{address: "https:///dns-query", wantErr: true},
+		{address: "quic://dns.example.org", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			got, err := NewTransport(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewTransport() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if err == nil && describeTransport(got) != tt.want {
+				t.Errorf("NewTransport() = %q, want %q", describeTransport(got), tt.want)
+			}
+		})
+	}
+}
+
+func describeTransport(t Transport) string {
+	switch t := t.(type) {
+	case *udpTransport:
+		return t.server
+	case *tlsTransport:
+		return "tls " + t.server
+	case *httpsTransport:
+		return "https " + t.url
+	}
+
+	return "unknown"
+}
+
+func TestDialFunc(t *testing.T) {
+	s := newDNSStandIn(t, testZone())
+
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial:     DialFunc(NewUDPTransport(s.Addr())),
+	}
+
+	mxs, err := r.LookupMX(context.Background(), "example.org")
+	if err != nil || len(mxs) != 2 || mxs[0].Host != "mx1.example.org." {
+		t.Errorf("Expected the MX records of the stand-in, got %v (%v)", mxs, err)
+	}
+
+	ips, err := r.LookupIPAddr(context.Background(), "mx1.example.org")
+	if err != nil || len(ips) != 2 {
+		t.Errorf("Expected the addresses of the stand-in, got %v (%v)", ips, err)
+	}
+
+	if _, err := r.LookupMX(context.Background(), "example.com"); err == nil {
+		t.Errorf("Expected unknown names not to be found")
+	}
+}
@@ -0,0 +1,234 @@
+package validator
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// ErrInvalidHELOName is returned for a HELO name that is neither a fully-qualified domain name, nor an address literal
+var ErrInvalidHELOName = errors.New("HELO name must be a fully-qualified domain name or an address literal")
+
+// hostname is replaced in tests
+var hostname = os.Hostname
+
+// ProbeConfig defines how the recipient probe presents itself to mail servers
+type ProbeConfig struct {
+	// HELOName is the name used in the EHLO/HELO greeting, a fully-qualified domain name or an address literal (RFC 5321
+	// section 4.1.4). Most servers expect it to match the PTR record of the probing IP. Defaults to the host name, when
+	// it's fully-qualified, or else to the address literal of the local end of the connection. See ParseHELOName
+	HELOName string
+
+	// MailFrom is the envelope sender used in MAIL FROM. An empty value results in the null sender "<>"
+	MailFrom string
+
+	// StartTLS upgrades the connection when the server advertises STARTTLS
+	StartTLS bool
+
+	// VerifyTLS verifies the certificate of the MX host on STARTTLS. By default TLS is opportunistic (RFC 7435), as with
+	// most mail servers, since a certificate that doesn't match the MX host would otherwise end the probe.
+	VerifyTLS bool
+
+	// TLSConfig is used for STARTTLS. When nil, a config with the MX host as ServerName is used, see VerifyTLS
+	TLSConfig *tls.Config
+}
+
+// ParseHELOName returns name without a trailing dot, when it's usable in the EHLO/HELO greeting. An empty name is
+// returned as-is, see ProbeConfig.HELOName
+func ParseHELOName(name string) (string, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || isFQDN(name) {
+		return name, nil
+	}
+
+	if _, ok := parseAddressLiteral(name); ok {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("%w, got %q", ErrInvalidHELOName, name)
+}
+
+// heloName returns the configured HELO name, or the default for conn
+func (c ProbeConfig) heloName(conn net.Conn) (string, error) {
+	if c.HELOName != "" {
+		return ParseHELOName(c.HELOName)
+	}
+
+	if name, err := hostname(); err == nil && isFQDN(strings.TrimSuffix(name, ".")) {
+		return strings.TrimSuffix(name, "."), nil
+	}
+
+	if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok && addr.IP != nil && !addr.IP.IsUnspecified() {
+		if ip := addr.IP.To4(); ip != nil {
+			return "[" + ip.String() + "]", nil
+		}
+
+		return "[IPv6:" + addr.IP.String() + "]", nil
+	}
+
+	return "", fmt.Errorf("%w, the host name isn't fully-qualified and no local address is known", ErrInvalidHELOName)
+}
+
+// isFQDN returns true for a fully-qualified domain name. IP addresses and names of the local host (e.g.
+// "localhost.localdomain") don't qualify.
+func isFQDN(name string) bool {
+	name = strings.ToLower(name)
+	if !looksLikeValidDomain(name) || net.ParseIP(name) != nil {
+		return false
+	}
+
+	return !strings.HasPrefix(name, "localhost.") && !strings.HasSuffix(name, ".localdomain")
+}
+
+// smtpProbe holds an SMTP conversation up to the point where recipients can be tried
+type smtpProbe struct {
+	client  *smtp.Client
+	replies []SMTPReply
+}
+
+// smtpCommandError ties an error to the SMTP command that caused it
+type smtpCommandError struct {
+	command string
+	err     error
+}
+
+func (e smtpCommandError) Error() string {
+	return e.command + " failed " + e.err.Error()
+}
+
+func (e smtpCommandError) Unwrap() error {
+	return e.err
+}
+
+// newSMTPProbe greets the server on conn, optionally upgrades to TLS and announces the envelope sender. The context
+// deadline, when defined, applies to the entire conversation.
+func newSMTPProbe(ctx context.Context, conn net.Conn, mxHost string, conf ProbeConfig) (*smtpProbe, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	mxHost = strings.TrimSuffix(mxHost, ".")
+	client, err := smtp.NewClient(conn, mxHost)
+	if err != nil {
+		return nil, smtpCommandError{command: "greeting", err: err}
+	}
+
+	probe := &smtpProbe{client: client}
+
+	heloName, err := conf.heloName(conn)
+	if err != nil {
+		_ = client.Close()
+		return nil, smtpCommandError{command: "HELO", err: err}
+	}
+
+	if err = client.Hello(heloName); err != nil {
+		_ = probe.Close()
+		return nil, smtpCommandError{command: "HELO", err: err}
+	}
+
+	if conf.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			tlsConfig := conf.TLSConfig
+			if tlsConfig == nil {
+				//nolint:gosec // Opportunistic TLS, unless VerifyTLS is set
+				tlsConfig = &tls.Config{ServerName: mxHost, MinVersion: tls.VersionTLS12, InsecureSkipVerify: !conf.VerifyTLS}
+			}
+
+			if err = client.StartTLS(tlsConfig); err != nil {
+				// The state of the conversation is unknown after a failed handshake
+				_ = client.Close()
+				return nil, smtpCommandError{command: "STARTTLS", err: err}
+			}
+		}
+	}
+
+	if err = client.Mail(conf.MailFrom); err != nil {
+		_ = probe.Close()
+		return nil, smtpCommandError{command: "MAIL FROM", err: classifySMTPReply(err)}
+	}
+
+	return probe, nil
+}
+
+// Rcpt asks the server if it accepts mail for address. A nil error means the server accepted the recipient (2xx),
+// ErrRCPTRejected is returned on a permanent failure (5xx) and ErrRCPTTemporary on a transient one (4xx).
+func (p *smtpProbe) Rcpt(address string) error {
+	err := p.client.Rcpt(address)
+	if reply, ok := newSMTPReply("RCPT TO:<"+address+">", err); ok {
+		p.replies = append(p.replies, reply)
+	}
+
+	return classifySMTPReply(err)
+}
+
+// Close ends the conversation, without ever sending DATA
+func (p *smtpProbe) Close() error {
+	_ = p.client.Reset()
+	err := p.client.Quit()
+	if err != nil {
+		return p.client.Close()
+	}
+
+	return nil
+}
+
+// classifySMTPReply wraps an SMTP reply error with ErrRCPTTemporary or ErrRCPTRejected, based on the reply code.
+// Other errors (e.g. network errors) are returned as-is.
+func classifySMTPReply(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var tpErr *textproto.Error
+	if !errors.As(err, &tpErr) {
+		return err
+	}
+
+	switch tpErr.Code / 100 {
+	case 4:
+		return smtpReplyError{class: ErrRCPTTemporary, reply: tpErr}
+	case 5:
+		return smtpReplyError{class: ErrRCPTRejected, reply: tpErr}
+	}
+
+	return err
+}
+
+// smtpReplyError is a negative SMTP reply, classified as either ErrRCPTTemporary or ErrRCPTRejected. It matches both
+// the class and the original *textproto.Error
+type smtpReplyError struct {
+	class error
+	reply *textproto.Error
+}
+
+func (e smtpReplyError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.class, e.reply.Code, e.reply.Msg)
+}
+
+func (e smtpReplyError) Is(target error) bool {
+	return target == e.class
+}
+
+func (e smtpReplyError) Unwrap() error {
+	return e.reply
+}
+
+// randomLocalPart returns an unguessable local part, used to find out if a domain accepts any recipient
+func randomLocalPart() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,665 @@
+package validator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dynom/ERI/types"
+	"github.com/Dynom/ERI/validator/validations"
+)
+
+// stubSMTPServer is a minimal in-process SMTP server, it replies to RCPT TO based on the recipients map.
+type stubSMTPServer struct {
+	recipients map[string]string // Reply per recipient address
+	fallback   string            // Reply to unlisted recipients, defaults to "550 5.1.1 No such user"
+	mailFrom   string            // Reply to MAIL FROM, defaults to "250 OK"
+	tlsConfig  *tls.Config       // Advertises STARTTLS when defined
+
+	lock     sync.Mutex
+	helo     []string
+	senders  []string
+	tried    []string
+	usingTLS bool
+}
+
+// localConn is a client connection with the local address of a TCP connection, e.g. for the default HELO name
+type localConn struct {
+	net.Conn
+}
+
+func (localConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 49152}
+}
+
+// start serves a single client connection and returns it, the channel is closed when the conversation ended
+func (s *stubSMTPServer) start(t *testing.T) (net.Conn, <-chan struct{}) {
+	client, server := net.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer server.Close()
+
+		if err := s.serve(server); err != nil {
+			t.Logf("stub SMTP server stopped: %s", err)
+		}
+	}()
+
+	return localConn{Conn: client}, done
+}
+
+// startTCP is like start, but over a loopback connection. Unlike a pipe, it buffers writes, e.g. for a TLS alert that
+// is sent while the server is still writing
+func (s *stubSMTPServer) startTCP(t *testing.T) (net.Conn, <-chan struct{}) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Test setup failed, unable to listen %s", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer l.Close()
+
+		server, err := l.Accept()
+		if err != nil {
+			t.Logf("stub SMTP server stopped: %s", err)
+			return
+		}
+
+		defer server.Close()
+		if err := s.serve(server); err != nil {
+			t.Logf("stub SMTP server stopped: %s", err)
+		}
+	}()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Test setup failed, unable to connect %s", err)
+	}
+
+	return client, done
+}
+
+func (s *stubSMTPServer) serve(conn net.Conn) error {
+	text := textproto.NewConn(conn)
+	if err := text.PrintfLine("220 stub.example.org ESMTP"); err != nil {
+		return err
+	}
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return err
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			s.record(&s.helo, arg)
+			if s.tlsConfig != nil && !s.usingTLS {
+				err = text.PrintfLine("250-stub.example.org\r\n250 STARTTLS")
+			} else {
+				err = text.PrintfLine("250 stub.example.org")
+			}
+
+		case "STARTTLS":
+			if err = text.PrintfLine("220 Ready to start TLS"); err != nil {
+				return err
+			}
+
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err = tlsConn.Handshake(); err != nil {
+				return err
+			}
+
+			s.lock.Lock()
+			s.usingTLS = true
+			s.lock.Unlock()
+
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+
+		case "MAIL":
+			s.record(&s.senders, arg)
+			reply := s.mailFrom
+			if reply == "" {
+				reply = "250 OK"
+			}
+			err = text.PrintfLine("%s", reply)
+
+		case "RCPT":
+			address := strings.TrimSuffix(strings.TrimPrefix(arg, "TO:<"), ">")
+			s.record(&s.tried, address)
+
+			reply, ok := s.recipients[address]
+			if !ok {
+				reply = s.fallback
+			}
+
+			if reply == "" {
+				reply = "550 5.1.1 No such user"
+			}
+			err = text.PrintfLine("%s", reply)
+
+		case "RSET", "NOOP":
+			err = text.PrintfLine("250 OK")
+
+		case "QUIT":
+			return text.PrintfLine("221 Bye")
+
+		default:
+			err = text.PrintfLine("502 Command not implemented")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *stubSMTPServer) record(to *[]string, v string) {
+	s.lock.Lock()
+	*to = append(*to, v)
+	s.lock.Unlock()
+}
+
+// newSelfSignedTLSConfig creates a server config with a freshly generated certificate for host
+func newSelfSignedTLSConfig(t *testing.T, host string) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Test setup failed, unable to generate key %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Test setup failed, unable to create certificate %s", err)
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{der},
+			PrivateKey:  key,
+		}},
+	}
+}
+
+func Test_checkRCPT(t *testing.T) {
+	tests := []struct {
+		name          string
+		server        *stubSMTPServer
+		steps         validations.Steps
+		validations   validations.Validations
+		noConn        bool
+		reconnect     bool
+		wantErr       error
+		wantStep      bool
+		wantValid     bool
+		wantAcceptAll bool
+		wantCodes     []int
+	}{
+		{
+			name:      "recipient accepted",
+			server:    &stubSMTPServer{recipients: map[string]string{"john@example.org": "250 OK"}},
+			wantStep:  true,
+			wantValid: true,
+			wantCodes: []int{250, 550},
+		},
+		{
+			name:          "domain accepts all recipients",
+			server:        &stubSMTPServer{fallback: "250 OK"},
+			wantAcceptAll: true,
+			wantCodes:     []int{250, 250},
+		},
+		{
+			name:          "domain already known to accept all",
+			noConn:        true,
+			validations:   validations.Validations(validations.FAcceptAll),
+			wantAcceptAll: true,
+		},
+		{
+			name:      "domain already known not to accept all",
+			server:    &stubSMTPServer{recipients: map[string]string{"john@example.org": "250 OK"}},
+			steps:     validations.Steps(validations.FAcceptAll),
+			wantStep:  true,
+			wantValid: true,
+			wantCodes: []int{250},
+		},
+		{
+			name:      "recipient rejected",
+			server:    &stubSMTPServer{recipients: map[string]string{"john@example.org": "550 5.1.1 No such user"}},
+			wantErr:   ErrRCPTRejected,
+			wantStep:  true,
+			wantCodes: []int{550},
+		},
+		{
+			name:      "recipient greylisted",
+			server:    &stubSMTPServer{recipients: map[string]string{"john@example.org": "450 4.2.0 Greylisted, try again later"}},
+			wantErr:   ErrRCPTTemporary,
+			wantCodes: []int{450},
+		},
+		{
+			name:      "sender rejected",
+			server:    &stubSMTPServer{mailFrom: "553 5.7.1 Sender rejected"},
+			wantErr:   ErrRCPTTemporary,
+			wantCodes: []int{553},
+		},
+		{
+			name:    "no connection",
+			noConn:  true,
+			wantErr: ErrInvalidHost,
+		},
+		{
+			name:        "connection step passed on an earlier run",
+			server:      &stubSMTPServer{recipients: map[string]string{"john@example.org": "250 OK"}},
+			noConn:      true,
+			reconnect:   true,
+			steps:       validations.Steps(validations.FMXLookup | validations.FMXDomainHasIP | validations.FHostConnect),
+			validations: validations.Validations(validations.FMXLookup | validations.FMXDomainHasIP | validations.FHostConnect),
+			wantStep:    true,
+			wantValid:   true,
+			wantCodes:   []int{250, 550},
+		},
+		{
+			name:     "step already defined, but not valid",
+			noConn:   true,
+			steps:    validations.Steps(validations.FValidRCPT),
+			wantErr:  ErrRCPTRejected,
+			wantStep: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			a := &Artifact{
+				Steps:       tt.steps,
+				Validations: tt.validations,
+				email:       types.NewEmailFromParts("john", "example.org"),
+				ctx:         ctx,
+				connectedMX: "mx.example.org.",
+				resolver:    buildResolver(nil, nil, errors.New("server misbehaving")),
+			}
+
+			var done <-chan struct{}
+			if !tt.noConn {
+				a.conn, done = tt.server.start(t)
+			}
+
+			dialer := &stubSMTPDialer{t: t, server: tt.server}
+			if tt.reconnect {
+				a.resolver = buildResolver([]string{"mx.example.org"}, map[string][]net.IPAddr{"mx.example.org": {{IP: net.ParseIP("192.0.2.1")}}}, nil)
+				a.mxAddress = MXAddressConfig{Allow: IPDocumentation}
+				a.connect = ConnectConfig{Ports: []uint16{25}}
+				a.dialer = dialer
+			}
+
+			err := checkRCPT(a)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("checkRCPT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := a.Steps.HasFlag(validations.FValidRCPT); got != tt.wantStep {
+				t.Errorf("Expected the step to be defined: %t, got: %t", tt.wantStep, got)
+			}
+
+			if got := a.Validations.HasFlag(validations.FValidRCPT); got != tt.wantValid {
+				t.Errorf("Expected the validation to be defined: %t, got: %t", tt.wantValid, got)
+			}
+
+			if got := a.Validations.HasFlag(validations.FAcceptAll); got != tt.wantAcceptAll {
+				t.Errorf("Expected the domain to accept all: %t, got: %t", tt.wantAcceptAll, got)
+			}
+
+			var codes []int
+			for _, reply := range a.smtpReplies {
+				codes = append(codes, reply.Code)
+			}
+
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("Expected the SMTP reply codes %v, got %v", tt.wantCodes, codes)
+			}
+
+			if got := createResult(*a).RecipientStatus(); tt.wantAcceptAll && got != RecipientAcceptAll {
+				t.Errorf("Expected the recipient status to be %q, got: %q", RecipientAcceptAll, got)
+			}
+
+			if done != nil {
+				<-done
+			}
+
+			if dialer.done != nil {
+				<-dialer.done
+			}
+		})
+	}
+}
+
+// stubSMTPDialer connects to the server, a single connection is expected
+type stubSMTPDialer struct {
+	t      *testing.T
+	server *stubSMTPServer
+	done   <-chan struct{}
+}
+
+func (d *stubSMTPDialer) DialContext(context.Context, string, string) (net.Conn, error) {
+	conn, done := d.server.start(d.t)
+	d.done = done
+
+	return conn, nil
+}
+
+func Test_newSMTPProbe(t *testing.T) {
+	t.Run("identity", func(t *testing.T) {
+		server := &stubSMTPServer{fallback: "250 OK"}
+		conn, done := server.start(t)
+
+		probe, err := newSMTPProbe(context.Background(), conn, "mx.example.org.", ProbeConfig{
+			HELOName: "probe.example.com",
+			MailFrom: "bounces@example.com",
+		})
+		if err != nil {
+			t.Fatalf("newSMTPProbe() unexpected error %s", err)
+		}
+
+		if err = probe.Rcpt("john@example.org"); err != nil {
+			t.Errorf("Rcpt() unexpected error %s", err)
+		}
+
+		_ = probe.Close()
+		<-done
+
+		if len(server.helo) != 1 || server.helo[0] != "probe.example.com" {
+			t.Errorf("Expected the configured HELO name to be used, instead I got %+v", server.helo)
+		}
+
+		if len(server.senders) != 1 || !strings.Contains(server.senders[0], "<bounces@example.com>") {
+			t.Errorf("Expected the configured envelope sender to be used, instead I got %+v", server.senders)
+		}
+	})
+
+	t.Run("null sender by default", func(t *testing.T) {
+		stubHostname(t, "probe.example.com")
+
+		server := &stubSMTPServer{}
+		conn, done := server.start(t)
+
+		probe, err := newSMTPProbe(context.Background(), conn, "mx.example.org", ProbeConfig{})
+		if err != nil {
+			t.Fatalf("newSMTPProbe() unexpected error %s", err)
+		}
+
+		_ = probe.Close()
+		<-done
+
+		if server.helo[0] != "probe.example.com" {
+			t.Errorf("Expected the host name, instead I got %+v", server.helo)
+		}
+
+		if !strings.HasPrefix(server.senders[0], "FROM:<>") {
+			t.Errorf("Expected the null sender, instead I got %+v", server.senders)
+		}
+	})
+
+	t.Run("STARTTLS", func(t *testing.T) {
+		server := &stubSMTPServer{fallback: "250 OK", tlsConfig: newSelfSignedTLSConfig(t, "mx.example.org")}
+		conn, done := server.start(t)
+
+		probe, err := newSMTPProbe(context.Background(), conn, "mx.example.org", ProbeConfig{
+			StartTLS: true,
+			//nolint:gosec // Self-signed certificate, test only
+			TLSConfig: &tls.Config{InsecureSkipVerify: true},
+		})
+		if err != nil {
+			t.Fatalf("newSMTPProbe() unexpected error %s", err)
+		}
+
+		if err = probe.Rcpt("john@example.org"); err != nil {
+			t.Errorf("Rcpt() unexpected error %s", err)
+		}
+
+		_ = probe.Close()
+		<-done
+
+		if !server.usingTLS {
+			t.Errorf("Expected the connection to be upgraded to TLS")
+		}
+
+		// After STARTTLS, the client greets again
+		if len(server.helo) != 2 {
+			t.Errorf("Expected two greetings, instead I got %+v", server.helo)
+		}
+	})
+
+	t.Run("STARTTLS is opportunistic", func(t *testing.T) {
+		server := &stubSMTPServer{fallback: "250 OK", tlsConfig: newSelfSignedTLSConfig(t, "mx.example.com")}
+		conn, done := server.start(t)
+
+		probe, err := newSMTPProbe(context.Background(), conn, "mx.example.org", ProbeConfig{StartTLS: true})
+		if err != nil {
+			t.Fatalf("newSMTPProbe() unexpected error %s", err)
+		}
+
+		_ = probe.Close()
+		<-done
+
+		if !server.usingTLS {
+			t.Errorf("Expected the connection to be upgraded to TLS")
+		}
+	})
+
+	t.Run("STARTTLS with verification", func(t *testing.T) {
+		server := &stubSMTPServer{tlsConfig: newSelfSignedTLSConfig(t, "mx.example.com")}
+		conn, done := server.startTCP(t)
+
+		_, err := newSMTPProbe(context.Background(), conn, "mx.example.org", ProbeConfig{StartTLS: true, VerifyTLS: true})
+		<-done
+
+		var cmdErr smtpCommandError
+		if !errors.As(err, &cmdErr) || cmdErr.command != "STARTTLS" {
+			t.Errorf("Expected the unverified certificate to fail STARTTLS, instead I got %v", err)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer server.Close()
+
+		// The server never greets, so the context deadline should end the conversation
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := newSMTPProbe(ctx, client, "mx.example.org", ProbeConfig{})
+		if err == nil {
+			t.Errorf("Expected newSMTPProbe() to fail when the deadline expires")
+		}
+	})
+}
+
+func Test_classifySMTPReply(t *testing.T) {
+	other := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "no error"},
+		{name: "temporary", err: &textproto.Error{Code: 451, Msg: "try later"}, want: ErrRCPTTemporary},
+		{name: "permanent", err: &textproto.Error{Code: 550, Msg: "no such user"}, want: ErrRCPTRejected},
+		{name: "other", err: other, want: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifySMTPReply(tt.err); !errors.Is(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("classifySMTPReply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkAcceptAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		fallback      string
+		wantStep      bool
+		wantAcceptAll bool
+	}{
+		{name: "accepts all", fallback: "250 OK", wantStep: true, wantAcceptAll: true},
+		{name: "rejects unknown recipients", fallback: "550 5.1.1 No such user", wantStep: true},
+		{name: "temporary failure", fallback: "451 4.3.0 Try again later"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &stubSMTPServer{fallback: tt.fallback}
+			conn, done := server.start(t)
+
+			probe, err := newSMTPProbe(context.Background(), conn, "mx.example.org", ProbeConfig{})
+			if err != nil {
+				t.Fatalf("newSMTPProbe() unexpected error %s", err)
+			}
+
+			a := &Artifact{email: types.NewEmailFromParts("john", "example.org")}
+			if got := checkAcceptAll(a, probe); got != tt.wantAcceptAll {
+				t.Errorf("checkAcceptAll() = %t, want %t", got, tt.wantAcceptAll)
+			}
+
+			_ = probe.Close()
+			<-done
+
+			if got := a.Steps.HasFlag(validations.FAcceptAll); got != tt.wantStep {
+				t.Errorf("Expected the step to be defined: %t, got: %t", tt.wantStep, got)
+			}
+
+			if got := a.Validations.HasFlag(validations.FAcceptAll); got != tt.wantAcceptAll {
+				t.Errorf("Expected the validation to be defined: %t, got: %t", tt.wantAcceptAll, got)
+			}
+
+			// The probed recipient should be random and on the same domain
+			if len(server.tried) != 1 || !strings.HasSuffix(server.tried[0], "@example.org") || strings.HasPrefix(server.tried[0], "john@") {
+				t.Errorf("Expected a random recipient on the same domain, instead I got %+v", server.tried)
+			}
+		})
+	}
+}
+
+// stubHostname replaces the host name of this machine for the duration of the test
+func stubHostname(t *testing.T, name string) {
+	original := hostname
+	hostname = func() (string, error) {
+		return name, nil
+	}
+
+	t.Cleanup(func() {
+		hostname = original
+	})
+}
+
+func TestParseHELOName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "", want: ""},
+		{name: "probe.example.com", want: "probe.example.com"},
+		{name: "probe.example.com.", want: "probe.example.com"},
+		{name: "[192.0.2.1]", want: "[192.0.2.1]"},
+		{name: "[IPv6:2001:db8::1]", want: "[IPv6:2001:db8::1]"},
+		{name: "localhost", wantErr: true},
+		{name: "localhost.localdomain", wantErr: true},
+		{name: "probe", wantErr: true},
+		{name: "192.0.2.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHELOName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseHELOName() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidHELOName) {
+				t.Errorf("Expected ErrInvalidHELOName, got %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseHELOName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeConfig_heloName(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		helo     string
+		hostname string
+		conn     net.Conn
+		want     string
+		wantErr  bool
+	}{
+		{name: "configured", helo: "probe.example.com", hostname: "probe.example.org", conn: client, want: "probe.example.com"},
+		{name: "configured, not fully-qualified", helo: "localhost", hostname: "probe.example.org", conn: client, wantErr: true},
+		{name: "host name", hostname: "probe.example.org.", conn: client, want: "probe.example.org"},
+		{name: "IPv4 address literal", hostname: "localhost", conn: localConn{Conn: client}, want: "[192.0.2.1]"},
+		{name: "IPv6 address literal", hostname: "probe", conn: localConn6{Conn: client}, want: "[IPv6:2001:db8::1]"},
+		{name: "no usable name", hostname: "localhost", conn: client, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubHostname(t, tt.hostname)
+
+			got, err := ProbeConfig{HELOName: tt.helo}.heloName(tt.conn)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidHELOName)) {
+				t.Errorf("heloName() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("heloName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type localConn6 struct {
+	net.Conn
+}
+
+func (localConn6) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 49152}
+}
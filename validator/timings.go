@@ -9,6 +9,6 @@ func (t *Timings) Add(l string, d time.Duration) {
 }
 
 type Timing struct {
-	Label    string
-	Duration time.Duration
+	Label    string        `json:"label"`
+	Duration time.Duration `json:"duration"`
 }
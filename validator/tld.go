@@ -0,0 +1,108 @@
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed" // The embedded copies of the lists
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/Dynom/ERI/types"
+)
+
+// ErrEmptyList is returned when loading a list without entries, which is more likely a failed download than a valid list
+var ErrEmptyList = errors.New("list has no entries")
+
+//go:embed data/tlds-alpha-by-domain.txt
+var embeddedTLDs []byte
+
+// NewTLDList creates a list of top-level domains, loaded with the embedded copy of the IANA list. It can be refreshed
+// with Load, using https://data.iana.org/TLD/tlds-alpha-by-domain.txt
+func NewTLDList() *TLDList {
+	l := &TLDList{}
+	if err := l.Load(bytes.NewReader(embeddedTLDs)); err != nil {
+		panic("embedded TLD list is invalid: " + err.Error())
+	}
+
+	return l
+}
+
+// TLDList holds the known top-level domains. It's safe for concurrent use and can be updated at runtime.
+type TLDList struct {
+	lock sync.RWMutex
+	tlds map[string]struct{}
+}
+
+// Replace atomically swaps the content of the list with tlds
+func (l *TLDList) Replace(tlds []string) {
+	set := make(map[string]struct{}, len(tlds))
+	for _, tld := range tlds {
+		if tld = normalizeDomainEntry(tld); tld != "" {
+			set[tld] = struct{}{}
+		}
+	}
+
+	l.lock.Lock()
+	l.tlds = set
+	l.lock.Unlock()
+}
+
+// Load replaces the content of the list with what is read from r, in the format of the IANA list. It expects one
+// top-level domain per line, in either the ASCII (e.g.: "XN--P1AI") or Unicode form. Empty lines and lines starting
+// with a "#" are ignored.
+func (l *TLDList) Load(r io.Reader) error {
+	var tlds []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tlds = append(tlds, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(tlds) == 0 {
+		return ErrEmptyList
+	}
+
+	l.Replace(tlds)
+	return nil
+}
+
+// Len returns the number of top-level domains on the list
+func (l *TLDList) Len() int {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return len(l.tlds)
+}
+
+// HasTLD returns true if the top-level domain of domain (e.g.: "org" for "example.org") is on the list
+func (l *TLDList) HasTLD(domain string) bool {
+	tld := topLevelDomain(normalizeDomainEntry(domain))
+
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	_, ok := l.tlds[tld]
+	return ok
+}
+
+// topLevelDomain returns the last label of domain
+func topLevelDomain(domain string) string {
+	return domain[strings.LastIndexByte(domain, '.')+1:]
+}
+
+// normalizeDomainEntry returns the lower-cased ASCII form of a domain, without the trailing root label
+func normalizeDomainEntry(domain string) string {
+	ascii, _ := types.NormalizeDomain(normalizeListEntry(domain))
+	return ascii
+}
@@ -0,0 +1,69 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTLDList_Load(t *testing.T) {
+	input := `
+# Version 2023020900
+ORG
+  COM
+XN--P1AI
+`
+
+	l := NewTLDList()
+	if err := l.Load(strings.NewReader(input)); err != nil {
+		t.Fatalf("Load() unexpected error %s", err)
+	}
+
+	if l.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", l.Len())
+	}
+
+	if l.HasTLD("example.net") {
+		t.Errorf("Expected the embedded entries to be replaced")
+	}
+
+	if err := l.Load(strings.NewReader("# Nothing but comments\n")); !errors.Is(err, ErrEmptyList) {
+		t.Errorf("Expected an empty list to be refused, got %v", err)
+	}
+
+	if !l.HasTLD("example.org") {
+		t.Errorf("Expected a refused list to keep the previous entries")
+	}
+}
+
+func TestTLDList_HasTLD(t *testing.T) {
+	l := NewTLDList()
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{want: true, domain: "example.org"},
+		{want: true, domain: "Example.ORG"},
+		{want: true, domain: "example.org."},
+		{want: true, domain: "mail.example.co.uk"},
+		{want: true, domain: "org"},
+		{want: true, domain: "example.xn--p1ai"},
+		{want: true, domain: "пример.рф"},
+		{want: true, domain: "example.mail.museum"},
+
+		{domain: "example.cmo"},
+		{domain: "example.mail"},
+		{domain: "example.local"},
+		{domain: "localhost"},
+		{domain: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := l.HasTLD(tt.domain); got != tt.want {
+				t.Errorf("HasTLD() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,8 +17,23 @@ type Artifact struct {
 	mx       []string
 	ctx      context.Context
 	dialer   DialContext
-	resolver LookupMX
+	resolver Resolver
 	conn     net.Conn
+
+	mxFound          []string // The MX hosts as found, unlike mx it's not altered by later steps
+	mxAddresses      map[string][]net.IP
+	connectedMX      string
+	connectedAddress string // The address and port connectedMX was connected on
+	smtpReplies      []SMTPReply
+	probe            ProbeConfig
+	connect          ConnectConfig
+	mxAddress        MXAddressConfig
+	postureConfig    PostureConfig
+	posture          *Posture
+	disposable       *DisposableList
+	tlds             *TLDList
+	roleAccounts     map[string]struct{}
+	syntaxMode       SyntaxMode
 }
 
 type stateFn func(a *Artifact) error
@@ -26,6 +41,12 @@ type stateFn func(a *Artifact) error
 type Result struct {
 	Validations validations.Validations
 	Steps       validations.Steps
+
+	// Posture is the mail-authentication posture of the domain, nil unless StepPosture ran
+	Posture *Posture
+
+	// Diagnostics explains the outcome of a single check, it has no meaning for other checks and isn't meant to be cached
+	Diagnostics Diagnostics
 }
 
 type Details struct {
@@ -42,9 +63,40 @@ func (r Result) HasValidStructure() bool {
 	return r.ValidatorsRan() && r.Validations.HasFlag(validations.FSyntax) && r.Steps.HasFlag(validations.FSyntax)
 }
 
+// IsRoleAccount returns true when the local part is known to belong to a role or system account, such as "info" or
+// "noreply", which are typically shared mailboxes.
+func (r Result) IsRoleAccount() bool {
+	return r.Validations.HasFlag(validations.FRoleAccount)
+}
+
+// RecipientStatus describes what is known about the recipient (local part) of an address
+type RecipientStatus string
+
+const (
+	RecipientUnknown   RecipientStatus = "unknown"
+	RecipientValid     RecipientStatus = "valid"
+	RecipientRejected  RecipientStatus = "rejected"
+	RecipientAcceptAll RecipientStatus = "accept-all" // The domain accepts mail for any recipient
+)
+
+// RecipientStatus returns what the recipient probe found out, if it ran at all
+func (r Result) RecipientStatus() RecipientStatus {
+	switch {
+	case r.Validations.HasFlag(validations.FAcceptAll):
+		return RecipientAcceptAll
+	case r.Steps.HasFlag(validations.FValidRCPT) && r.Validations.HasFlag(validations.FValidRCPT):
+		return RecipientValid
+	case r.Steps.HasFlag(validations.FValidRCPT):
+		return RecipientRejected
+	}
+
+	return RecipientUnknown
+}
+
 func createResult(a Artifact) Result {
 	return Result{
 		Validations: a.Validations,
 		Steps:       a.Steps,
+		Posture:     a.posture,
 	}
 }
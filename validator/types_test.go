@@ -51,3 +51,30 @@ func Test_createResult(t *testing.T) {
 		})
 	}
 }
+
+func TestResult_RecipientStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		validations validations.Flag
+		steps       validations.Flag
+		want        RecipientStatus
+	}{
+		{name: "not probed", validations: validations.FSyntax, steps: validations.FSyntax, want: RecipientUnknown},
+		{name: "valid", validations: validations.FValidRCPT, steps: validations.FValidRCPT, want: RecipientValid},
+		{name: "rejected", validations: 0, steps: validations.FValidRCPT, want: RecipientRejected},
+		{name: "accept-all", validations: validations.FAcceptAll, steps: validations.FAcceptAll, want: RecipientAcceptAll},
+		{name: "accept-all takes precedence", validations: validations.FAcceptAll | validations.FValidRCPT, steps: validations.FAcceptAll | validations.FValidRCPT, want: RecipientAcceptAll},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Result{
+				Validations: validations.Validations(tt.validations),
+				Steps:       validations.Steps(tt.steps),
+			}
+
+			if got := r.RecipientStatus(); got != tt.want {
+				t.Errorf("RecipientStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/Dynom/ERI/types"
@@ -16,13 +17,18 @@ import (
 var (
 	ErrInvalidHost        = errors.New("invalid host")
 	ErrEmailAddressSyntax = errors.New("invalid syntax")
+	ErrNullMX             = errors.New("domain does not accept mail (null MX)")
+	ErrRCPTRejected       = errors.New("recipient rejected")
+	ErrRCPTTemporary      = errors.New("recipient could not be verified, temporary failure")
+	ErrUnknownTLD         = errors.New("unknown top-level domain")
+	ErrUnroutableMX       = errors.New("MX host has no routable address")
 )
 
 func getNewArtifact(ctx context.Context, ep types.EmailParts, options ...ArtifactFn) Artifact {
 	a := Artifact{
 		Validations: 0,
 		Steps:       0,
-		Timings:     make(Timings, 10),
+		Timings:     make(Timings, 0, 10),
 		email:       ep,
 		mx:          nil,
 		ctx:         ctx,
@@ -50,47 +56,20 @@ func WithDialer(dialer *net.Dialer) ArtifactFn {
 	}
 }
 
-// getConnection attempts to connect to a host with one of the common email ports.
-func getConnection(ctx context.Context, dialer DialContext, mxHost string) (net.Conn, error) {
-	var conn net.Conn
-	var err error
-
-	ports := []string{"25", "587", "2525", "465"}
-	for _, port := range ports {
-
-		// @todo Should we check multiple ports, and do this in parallel?
-		// @todo Do we want to force ipv4/6?
-
-		var dialErr error
-		conn, dialErr = dialer.DialContext(ctx, "tcp", mxHost+":"+port)
-
-		if dialErr == nil {
-			break
-		}
-
-		if !strings.Contains(dialErr.Error(), "connection refused") {
-			err = fmt.Errorf("%s "+mxHost+":"+port+" %w", err, dialErr)
-		}
-	}
-
-	// When dialing fails, we could end up with a nil connection, which indicates that all options have been exhausted
-	// and that we've ended up with no reachable hosts
-	if conn == nil {
-		return conn, fmt.Errorf("no connection possible %w", ErrInvalidHost)
+// fetchMXHosts collects up to N MX hosts for a given domain. When the domain has no MX records, the domain itself is
+// used as implicit MX (RFC 5321 §5.1), as long as it has an A or AAAA record. A null MX (RFC 7505) results in ErrNullMX
+func fetchMXHosts(ctx context.Context, resolver Resolver, domain string) ([]string, error) {
+	mxs, err := resolver.LookupMX(ctx, domain)
+	if isNotFound(err) || (err == nil && len(mxs) == 0) {
+		return fetchImplicitMX(ctx, resolver, domain)
 	}
 
-	return conn, err
-}
-
-// fetchMXHosts collects up to N MX hosts for a given domain
-func fetchMXHosts(ctx context.Context, resolver LookupMX, domain string) ([]string, error) {
-	mxs, err := resolver.LookupMX(ctx, domain)
 	if err != nil {
 		return nil, fmt.Errorf("MX lookup failed %w", err)
 	}
 
-	if len(mxs) == 0 {
-		return nil, fmt.Errorf("no MX records found %w", err)
+	if isNullMX(mxs) {
+		return nil, ErrNullMX
 	}
 
 	// Reading an external source, limiting to a liberal amount
@@ -113,6 +92,35 @@ func fetchMXHosts(ctx context.Context, resolver LookupMX, domain string) ([]stri
 	return collected, err
 }
 
+// fetchImplicitMX returns the domain as its own mail host, when it resolves to at least one address
+func fetchImplicitMX(ctx context.Context, resolver Resolver, domain string) ([]string, error) {
+	ips, err := resolver.LookupIPAddr(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("no MX records found and implicit MX lookup failed %w", err)
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no MX records found and no address for implicit MX %w", ErrInvalidHost)
+	}
+
+	return []string{domain}, nil
+}
+
+// isNullMX returns true when the records represent a "null MX", a single record with the root as host (RFC 7505)
+func isNullMX(mxs []*net.MX) bool {
+	if len(mxs) != 1 || mxs[0] == nil {
+		return false
+	}
+
+	return mxs[0].Host == "." || mxs[0].Host == ""
+}
+
+// isNotFound returns true when the error is a DNS error stating that the name (or the requested record) doesn't exist
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
 // MightBeAHostOrIP is a very rudimentary check to see if the argument could be either a host name or IP address
 // It aims at speed and not correctness. It's intended to weed-out bogus responses such as '.'
 //
@@ -206,6 +214,133 @@ func looksLikeValidLocalPart(local string) bool {
 	return true
 }
 
+// looksLikeValidQuotedLocalPart checks the syntax of a quoted local part (RFC 5322 §3.2.4), e.g.: "john doe". Control
+// characters aren't allowed, a backslash escapes the next character and UTF-8 is allowed (RFC 6532).
+func looksLikeValidQuotedLocalPart(local string) bool {
+	// RFC 5321 §4.5.3.1.1 limits the local part to 64 octets, the quotes included
+	l := len(local)
+	if 3 > l || l > 64 || local[0] != '"' || local[l-1] != '"' {
+		return false
+	}
+
+	var escaped bool
+	for _, c := range local[1 : l-1] {
+		switch {
+		case c == utf8.RuneError:
+			return false
+		case c < 32 /* controls */ && c != 9 /* tab */, c == 127 /* DEL */ :
+			return false
+		case escaped:
+			escaped = false
+		case c == 92 /* \ */ :
+			escaped = true
+		case c == 34 /* " */ :
+			return false
+		}
+	}
+
+	return !escaped
+}
+
+// isQuotedLocalPart returns true when the local part is in the quoted form, it doesn't check the syntax
+func isQuotedLocalPart(local string) bool {
+	return strings.HasPrefix(local, `"`)
+}
+
+// parseAddressLiteral returns the IP of an address literal domain (RFC 5321 §4.1.3), e.g.: "[192.0.2.1]" or
+// "[IPv6:2001:db8::1]". General address literals (with other tags) aren't supported.
+func parseAddressLiteral(domain string) (net.IP, bool) {
+	l := len(domain)
+	if 2 >= l || domain[0] != '[' || domain[l-1] != ']' {
+		return nil, false
+	}
+
+	literal := domain[1 : l-1]
+	const tagIPv6 = "ipv6:"
+	if len(literal) > len(tagIPv6) && strings.EqualFold(literal[:len(tagIPv6)], tagIPv6) {
+		ip := net.ParseIP(literal[len(tagIPv6):])
+		if ip == nil || !strings.Contains(literal[len(tagIPv6):], ":") {
+			return nil, false
+		}
+
+		return ip, true
+	}
+
+	ip := net.ParseIP(literal)
+	if ip == nil || ip.To4() == nil || strings.Contains(literal, ":") {
+		return nil, false
+	}
+
+	return ip, true
+}
+
+// isAddressLiteral returns true when the domain is in the address literal form, it doesn't check the syntax
+func isAddressLiteral(domain string) bool {
+	return strings.HasPrefix(domain, "[")
+}
+
+// DefaultRoleAccounts are local parts commonly used for shared mailboxes (role accounts) and automated senders
+var DefaultRoleAccounts = []string{
+	"abuse",
+	"admin",
+	"administrator",
+	"billing",
+	"contact",
+	"help",
+	"hostmaster",
+	"info",
+	"mailer-daemon",
+	"marketing",
+	"noc",
+	"no-reply",
+	"noreply",
+	"office",
+	"postmaster",
+	"root",
+	"sales",
+	"security",
+	"support",
+	"webmaster",
+}
+
+// newRoleAccountSet creates a lookup set for looksLikeRoleAccount
+func newRoleAccountSet(localParts []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(localParts))
+	for _, local := range localParts {
+		if local = normalizeRoleLocalPart(local); local != "" {
+			set[local] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// looksLikeRoleAccount returns true when the local part belongs to a role or system account. The comparison ignores
+// case, the separators ".", "-" and "_" and a sub-address ("+tag"), e.g.: "No.Reply+orders" matches "noreply"
+func looksLikeRoleAccount(set map[string]struct{}, local string) bool {
+	if len(set) == 0 {
+		return false
+	}
+
+	_, ok := set[normalizeRoleLocalPart(local)]
+	return ok
+}
+
+func normalizeRoleLocalPart(local string) string {
+	if i := strings.IndexByte(local, '+'); i > 0 {
+		local = local[:i]
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '-', '_':
+			return -1
+		}
+
+		return unicode.ToLower(r)
+	}, strings.TrimSpace(local))
+}
+
 //nolint:gocyclo
 func looksLikeValidDomain(domain string) bool {
 	lastIndexPos := len(domain) - 1
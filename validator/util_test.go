@@ -29,16 +29,38 @@ func (sd stubDialer) DialContext(ctx context.Context, network, address string) (
 
 type stubResolver struct {
 	mxs []*net.MX
-	err error // A single error for every resolver
+	ips map[string][]net.IPAddr // Addresses per host, hosts without an entry are reported as not found
+	err error                   // A single error for every resolver
 }
 
 func (sr stubResolver) LookupMX(_ context.Context, domain string) ([]*net.MX, error) {
 	return sr.mxs, sr.err
 }
 
-func buildLookupMX(mxHosts []string, err error) LookupMX {
+func (sr stubResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	if sr.err != nil {
+		return nil, sr.err
+	}
+
+	ips, ok := sr.ips[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return ips, nil
+}
+
+func buildLookupMX(mxHosts []string, err error) Resolver {
+	return buildResolver(mxHosts, nil, err)
+}
+
+// withDocumentationMX accepts MX hosts with the addresses reserved for documentation, as used by the stub resolvers
+var withDocumentationMX = WithMXAddressConfig(MXAddressConfig{Allow: IPDocumentation})
+
+func buildResolver(mxHosts []string, ips map[string][]net.IPAddr, err error) Resolver {
 	var r stubResolver
 	r.err = err
+	r.ips = ips
 
 	r.mxs = make([]*net.MX, len(mxHosts))
 	for i, d := range mxHosts {
@@ -53,30 +75,41 @@ func buildLookupMX(mxHosts []string, err error) LookupMX {
 func Test_fetchMXHosts(t *testing.T) {
 	type args struct {
 		hosts []string
+		ips   map[string][]net.IPAddr
 		err   error
 	}
 
+	localIP := []net.IPAddr{{IP: net.IPv4(192, 0, 2, 1)}}
+
 	tests := []struct {
-		name    string
-		args    args
-		want    []string
-		wantErr bool
+		name      string
+		args      args
+		want      []string
+		wantErr   bool
+		wantErrIs error
 	}{
 		// The good
 		{name: "Happy flow", want: []string{"mx1.example.org"}, args: args{hosts: []string{"mx1.example.org"}}},
+		{name: "implicit MX", want: []string{"foobar.local"}, args: args{hosts: []string{}, ips: map[string][]net.IPAddr{"foobar.local": localIP}}},
 
 		// The bad
 		{wantErr: true, name: "no MX records", want: nil, args: args{hosts: []string{}}},
+		{wantErr: true, name: "no MX records, no addresses", want: nil, args: args{hosts: []string{}, ips: map[string][]net.IPAddr{"foobar.local": {}}}},
 		{wantErr: true, name: "lookup error", want: nil, args: args{hosts: []string{"."}, err: errors.New("err")}},
+		{wantErr: true, name: "null MX", want: nil, args: args{hosts: []string{"."}}, wantErrIs: ErrNullMX},
 
 		// We had a result, but all were invalid. The result is an empty slice instead of a nil slice.
-		{wantErr: true, name: "malformed MX records", want: []string{}, args: args{hosts: []string{"."}}},
+		{wantErr: true, name: "malformed MX records", want: []string{}, args: args{hosts: []string{".", ".."}}},
 	}
 
 	ctx := context.Background()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := fetchMXHosts(ctx, buildLookupMX(tt.args.hosts, tt.args.err), "foobar.local")
+			got, err := fetchMXHosts(ctx, buildResolver(tt.args.hosts, tt.args.ips, tt.args.err), "foobar.local")
+
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("fetchMXHosts() error = %v, want %v", err, tt.wantErrIs)
+			}
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("fetchMXHosts() error = %v, wantErr %v", err, tt.wantErr)
@@ -90,46 +123,6 @@ func Test_fetchMXHosts(t *testing.T) {
 	}
 }
 
-func Test_getConnection(t *testing.T) {
-	// @todo validate connection timeout workings
-	// @todo
-
-	type args struct {
-		err  error
-		conn net.Conn
-	}
-
-	defaultConn := &net.IPConn{}
-
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		// The good
-		{name: "happy flow", args: args{err: nil, conn: defaultConn}},
-		{name: "expected error", args: args{err: errors.New("connection refused"), conn: defaultConn}},
-
-		// The bad
-		{wantErr: true, name: "unexpected error", args: args{err: errors.New("b0rk"), conn: defaultConn}},
-		{wantErr: true, name: "no conn", args: args{err: nil, conn: nil}},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			ctx := context.Background()
-			dialer := newStubDialer(tt.args.err)
-			dialer.conn = tt.args.conn
-
-			_, err := getConnection(ctx, dialer, "mx.example.org")
-			if (err != nil) != tt.wantErr {
-				t.Errorf("getConnection() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-		})
-	}
-}
-
 func TestEmailValidator_getNewArtifact(t *testing.T) {
 	t.Run("Dialer is set with default resolver", func(t *testing.T) {
 		ctx := context.Background()
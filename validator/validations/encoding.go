@@ -0,0 +1,66 @@
+package validations
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Encoding versions of Flag, Validations and Steps. The bit positions of existing flags never change, so a value
+// encoded with an older version is also valid in every newer version.
+const (
+	EncodingV1 EncodingVersion = iota + 1 // 8 bits, used by persisted and published values before the flags were widened
+	EncodingV2                            // 64 bits
+
+	EncodingCurrent = EncodingV2
+)
+
+var (
+	ErrUnknownEncoding  = errors.New("unknown encoding version")
+	ErrEncodingOverflow = errors.New("value doesn't fit the encoding version")
+)
+
+// EncodingVersion identifies the width of a stored or transmitted bitset
+type EncodingVersion uint8
+
+// maxValue returns the largest value that fits the encoding version
+func (ev EncodingVersion) maxValue() (uint64, error) {
+	switch ev {
+	case EncodingV1:
+		return math.MaxUint8, nil
+	case EncodingV2:
+		return math.MaxUint64, nil
+	}
+
+	return 0, fmt.Errorf("%w %d", ErrUnknownEncoding, ev)
+}
+
+// Decode converts a raw value, as read from storage, into a Flag. The value is interpreted as an unsigned integer of
+// the width defined by version, allowing the highest bit to round-trip through signed (e.g. SQL bigint) columns.
+func Decode(raw int64, version EncodingVersion) (Flag, error) {
+	max, err := version.maxValue()
+	if err != nil {
+		return 0, err
+	}
+
+	v := uint64(raw)
+	if v > max {
+		return 0, fmt.Errorf("%w, %d exceeds version %d", ErrEncodingOverflow, v, version)
+	}
+
+	return Flag(v), nil
+}
+
+// Encode converts the Flag into the raw value of the encoding version, suitable for storage.
+func (f Flag) Encode(version EncodingVersion) (int64, error) {
+	max, err := version.maxValue()
+	if err != nil {
+		return 0, err
+	}
+
+	if uint64(f) > max {
+		return 0, fmt.Errorf("%w, %d exceeds version %d", ErrEncodingOverflow, f, version)
+	}
+
+	return int64(f), nil
+}
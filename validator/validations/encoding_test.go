@@ -0,0 +1,80 @@
+package validations
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     int64
+		version EncodingVersion
+		want    Flag
+		wantErr error
+	}{
+		// Version 1, the original 8 bit encoding
+		{name: "v1 zero", raw: 0, version: EncodingV1, want: 0},
+		{name: "v1 all original flags", raw: 0b01111111, version: EncodingV1, want: FValid | FSyntax | FMXLookup | FMXDomainHasIP | FHostConnect | FValidRCPT | FDisposable},
+		{name: "v1 max", raw: math.MaxUint8, version: EncodingV1, want: math.MaxUint8},
+		{name: "v1 overflow", raw: math.MaxUint8 + 1, version: EncodingV1, wantErr: ErrEncodingOverflow},
+		{name: "v1 negative", raw: -1, version: EncodingV1, wantErr: ErrEncodingOverflow},
+
+		// Version 2, 64 bits
+		{name: "v2 reads v1 values", raw: int64(FValid | FDisposable), version: EncodingV2, want: FValid | FDisposable},
+		{name: "v2 beyond 8 bits", raw: 1 << 8, version: EncodingV2, want: 1 << 8},
+		{name: "v2 highest bit", raw: math.MinInt64, version: EncodingV2, want: 1 << 63},
+
+		{name: "unknown version", raw: 1, version: 0, wantErr: ErrUnknownEncoding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.raw, tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("Decode() got = %064b, want %064b", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlag_Encode(t *testing.T) {
+	tests := []struct {
+		name    string
+		f       Flag
+		version EncodingVersion
+		wantErr error
+	}{
+		{name: "v1 fits", f: FValid | FDisposable, version: EncodingV1},
+		{name: "v1 too wide", f: 1 << 8, version: EncodingV1, wantErr: ErrEncodingOverflow},
+		{name: "v2 fits", f: 1 << 8, version: EncodingV2},
+		{name: "v2 highest bit", f: 1 << 63, version: EncodingV2},
+		{name: "unknown version", f: FValid, version: 42, wantErr: ErrUnknownEncoding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.f.Encode(tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			// Every encoded value should survive a round-trip with the same version
+			got, err := Decode(raw, tt.version)
+			if err != nil || got != tt.f {
+				t.Errorf("Decode(Encode()) = %064b, %v, want %064b", got, err, tt.f)
+			}
+		})
+	}
+}
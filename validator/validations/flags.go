@@ -1,6 +1,10 @@
 package validations
 
-import "strings"
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+)
 
 // Validation Flags, these flags represent successful validation steps. Depending on how far you want to go, you can
 // classify a validation as valid enough, for your use-case.
@@ -12,15 +16,22 @@ const (
 	FHostConnect   Flag = 1 << iota
 	FValidRCPT     Flag = 1 << iota
 	FDisposable    Flag = 1 << iota // Address / Domain is considered a disposable e-mail trap
+	FNullMX        Flag = 1 << iota // Domain explicitly states it does not accept mail (RFC 7505)
+	FAcceptAll     Flag = 1 << iota // Domain accepts mail for any recipient (catch-all), recipient probes are meaningless
+	FRoleAccount   Flag = 1 << iota // Local part belongs to a role or system account (e.g. "info", "noreply")
+	FPosture       Flag = 1 << iota // Mail-authentication posture of the domain (SPF, DMARC, MTA-STS, DANE) is known
+
+	// FUserDefined is the first flag that is free to use for user-defined validation steps, flags below it are reserved
+	FUserDefined Flag = 1 << 32
 
 	// FDomainHasIP is Deprecated: Unclear naming. Prefer FMXDomainHasIP
 	FDomainHasIP = FMXDomainHasIP // @deprecated
 )
 
-type Flag uint8
+type Flag uint64
 
 func (f Flag) AsStringSlice() []string {
-	flags := []Flag{FValid, FSyntax, FMXLookup, FMXDomainHasIP, FHostConnect, FValidRCPT, FDisposable}
+	flags := []Flag{FValid, FSyntax, FMXLookup, FMXDomainHasIP, FHostConnect, FValidRCPT, FDisposable, FNullMX, FAcceptAll, FRoleAccount, FPosture}
 	r := make([]string, 0, len(flags))
 
 	for _, flag := range flags {
@@ -33,9 +44,10 @@ func (f Flag) AsStringSlice() []string {
 		r = append(r, toString(flag))
 	}
 
-	if f > 0 {
-		// List of flags is possibly outdated.
-		panic("trouble in paradise")
+	// User-defined flags (see FUserDefined) and reserved bits that this version doesn't know about, e.g. from a newer
+	// encoder.
+	for ; f > 0; f &= f - 1 {
+		r = append(r, toString(f&-f))
 	}
 
 	return r
@@ -61,6 +73,22 @@ func toString(f Flag) string {
 		return "validRecipient"
 	case FDisposable:
 		return "disposable"
+	case FNullMX:
+		return "nullMX"
+	case FAcceptAll:
+		return "acceptAll"
+	case FRoleAccount:
+		return "roleAccount"
+	case FPosture:
+		return "posture"
+	}
+
+	if bits.OnesCount64(uint64(f)) == 1 {
+		if f >= FUserDefined {
+			return "user:" + strconv.Itoa(bits.TrailingZeros64(uint64(f))-bits.TrailingZeros64(uint64(FUserDefined)))
+		}
+
+		return "bit:" + strconv.Itoa(bits.TrailingZeros64(uint64(f)))
 	}
 
 	return "nil"
@@ -1,7 +1,6 @@
 package validations
 
 import (
-	"math"
 	"testing"
 )
 
@@ -13,6 +12,9 @@ func TestFlag_String(t *testing.T) {
 	}{
 		{name: "just one", f: FValid, want: "valid"},
 		{name: "some", f: FValid | FSyntax, want: "valid,syntax"},
+		{name: "user-defined", f: FSyntax | FUserDefined | FUserDefined<<2, want: "syntax,user:0,user:2"},
+		{name: "highest user-defined", f: 1 << 63, want: "user:31"},
+		{name: "reserved", f: FSyntax | 1<<12 | 1<<31, want: "syntax,bit:12,bit:31"},
 	}
 
 	for _, tt := range tests {
@@ -26,13 +28,9 @@ func TestFlag_String(t *testing.T) {
 }
 
 func Test_toString(t *testing.T) {
-	var max Flag = 0
-	for i := 0; max < math.MaxUint8; i++ {
-		sub := uint8(math.Pow(2, float64(i)))
-		if got := toString(Flag(sub)); got == "" {
-			t.Errorf("Got empty value from toString(%08b) = this is unexpected", sub)
+	for f := Flag(1); f != 0; f <<= 1 {
+		if got := toString(f); got == "" {
+			t.Errorf("Got empty value from toString(%08b) = this is unexpected", f)
 		}
-
-		max += Flag(sub)
 	}
 }
@@ -3,7 +3,7 @@ package validations
 import "fmt"
 
 // Steps holds the validation steps performed, they do not signify validity
-type Steps uint8
+type Steps uint64
 
 func (s Steps) String() string {
 	return fmt.Sprintf("%08b", s)
@@ -3,7 +3,7 @@ package validations
 import "fmt"
 
 // Validations holds the validation steps performed.
-type Validations uint8
+type Validations uint64
 
 func (v Validations) String() string {
 	return fmt.Sprintf("%08b", v)
@@ -2,7 +2,6 @@ package validations
 
 import (
 	"fmt"
-	"math"
 	"testing"
 )
 
@@ -146,15 +145,6 @@ func TestValidations_MergeWithNext(t *testing.T) {
 	}
 }
 
-func TestSizeExpectation(t *testing.T) {
-	var v Validations
-
-	v = math.MaxUint8
-	if v+1 != 0 {
-		t.Errorf("Expected v to be uint8, which should overflow to 0, \nv     = %+v, \nv + 1 = %+v", v, v+1)
-	}
-}
-
 func TestStartingFromEmptyValidations(t *testing.T) {
 	var v Flag
 
@@ -333,7 +323,7 @@ func BenchmarkTypeMemoryUsageInt64(b *testing.B) {
 	Int64[0] += 1
 }
 
-func ExampleMaskTest() {
+func Example_maskTest() {
 	fmt.Printf("FValid          %08b %d\n", FValid, FValid)
 	fmt.Printf("FSyntax         %08b %d\n", FSyntax, FSyntax)
 	fmt.Printf("FMXLookup       %08b %d\n", FMXLookup, FMXLookup)
@@ -341,6 +331,10 @@ func ExampleMaskTest() {
 	fmt.Printf("FHostConnect    %08b %d\n", FHostConnect, FHostConnect)
 	fmt.Printf("FValidRCPT      %08b %d\n", FValidRCPT, FValidRCPT)
 	fmt.Printf("FDisposable     %08b %d\n", FDisposable, FDisposable)
+	fmt.Printf("FNullMX         %08b %d\n", FNullMX, FNullMX)
+	fmt.Printf("FAcceptAll      %08b %d\n", FAcceptAll, FAcceptAll)
+	fmt.Printf("FRoleAccount    %08b %d\n", FRoleAccount, FRoleAccount)
+	fmt.Printf("FPosture        %08b %d\n", FPosture, FPosture)
 
 	// Output:
 	// FValid          00000001 1
@@ -350,4 +344,8 @@ func ExampleMaskTest() {
 	// FHostConnect    00010000 16
 	// FValidRCPT      00100000 32
 	// FDisposable     01000000 64
+	// FNullMX         10000000 128
+	// FAcceptAll      100000000 256
+	// FRoleAccount    1000000000 512
+	// FPosture        10000000000 1024
 }
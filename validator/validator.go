@@ -11,9 +11,10 @@ import (
 type (
 	CheckFn    func(ctx context.Context, parts types.EmailParts, options ...ArtifactFn) Result
 	ArtifactFn func(artifact *Artifact)
+	Option     func(v *EmailValidator)
 )
 
-func NewEmailAddressValidator(dialer *net.Dialer) EmailValidator {
+func NewEmailAddressValidator(dialer *net.Dialer, options ...Option) EmailValidator {
 	// @todo fix when Go's stdlib offers a nicer API for this
 	if dialer == nil {
 		dialer = &net.Dialer{}
@@ -23,13 +24,126 @@ func NewEmailAddressValidator(dialer *net.Dialer) EmailValidator {
 		dialer.Resolver = net.DefaultResolver
 	}
 
-	return EmailValidator{
-		dialer: dialer,
+	v := EmailValidator{
+		dialer:       dialer,
+		roleAccounts: newRoleAccountSet(DefaultRoleAccounts),
+		posture:      PostureConfig{}.withDefaults(dialer),
+	}
+
+	for _, o := range options {
+		o(&v)
+	}
+
+	return v
+}
+
+// WithDisposableList enables the disposable domain check. The list can be updated while the validator is in use.
+func WithDisposableList(l *DisposableList) Option {
+	return func(v *EmailValidator) {
+		v.disposable = l
+	}
+}
+
+// WithProbeConfig defines how the recipient probe of CheckWithRCPT presents itself to mail servers
+func WithProbeConfig(c ProbeConfig) Option {
+	return func(v *EmailValidator) {
+		v.probe = c
+	}
+}
+
+// WithRoleAccounts replaces DefaultRoleAccounts, the local parts that are marked as role or system account. An empty
+// list disables the classification.
+func WithRoleAccounts(localParts []string) Option {
+	return func(v *EmailValidator) {
+		v.roleAccounts = newRoleAccountSet(localParts)
+	}
+}
+
+// WithConnectConfig defines how StepConnect connects to MX hosts, e.g. which ports are tried
+func WithConnectConfig(c ConnectConfig) Option {
+	return func(v *EmailValidator) {
+		v.connect = c
+	}
+}
+
+// WithMXAddressConfig defines which addresses of MX hosts are accepted, by default only public addresses are. See
+// MXAddressConfig
+func WithMXAddressConfig(c MXAddressConfig) Option {
+	return func(v *EmailValidator) {
+		v.mxAddress = c
+	}
+}
+
+// WithPostureConfig defines how StepPosture looks up the mail-authentication posture of domains, see PostureConfig
+func WithPostureConfig(c PostureConfig) Option {
+	return func(v *EmailValidator) {
+		v.posture = c.withDefaults(v.dialer)
+	}
+}
+
+// WithResolver replaces the resolver of the dialer for the DNS lookups, e.g. with a caching resolver
+func WithResolver(r Resolver) Option {
+	return func(v *EmailValidator) {
+		v.resolver = r
+	}
+}
+
+// WithTLDList rejects domains of which the top-level domain isn't on the list, as part of the syntax check. This saves
+// the DNS round trip for typos such as "example.cmo". See NewTLDList
+func WithTLDList(l *TLDList) Option {
+	return func(v *EmailValidator) {
+		v.tlds = l
+	}
+}
+
+// SyntaxMode defines which forms of addresses the syntax check accepts
+type SyntaxMode uint8
+
+const (
+	// SyntaxStrict accepts dot-atom local parts and host name domains, the forms commonly used by people
+	SyntaxStrict SyntaxMode = iota
+
+	// SyntaxRFC5322 additionally accepts quoted local parts (e.g.: "john doe"@example.org) and address literals (e.g.:
+	// john@[192.0.2.1] or john@[IPv6:2001:db8::1]). Address literals skip the MX lookup and are connected to directly.
+	SyntaxRFC5322
+)
+
+// WithSyntaxMode defines which forms of addresses are accepted, SyntaxStrict is used by default
+func WithSyntaxMode(mode SyntaxMode) Option {
+	return func(v *EmailValidator) {
+		v.syntaxMode = mode
 	}
 }
 
 type EmailValidator struct {
-	dialer *net.Dialer
+	dialer       *net.Dialer
+	resolver     Resolver
+	disposable   *DisposableList
+	tlds         *TLDList
+	probe        ProbeConfig
+	connect      ConnectConfig
+	mxAddress    MXAddressConfig
+	posture      PostureConfig
+	roleAccounts map[string]struct{}
+	syntaxMode   SyntaxMode
+}
+
+// artifactOptions prepends the validator's own configuration to the options of a single check
+func (v *EmailValidator) artifactOptions(options []ArtifactFn) []ArtifactFn {
+	return prependOptions(options, WithDialer(v.dialer), func(artifact *Artifact) {
+		artifact.disposable = v.disposable
+		artifact.tlds = v.tlds
+		artifact.probe = v.probe
+		artifact.connect = v.connect
+		artifact.mxAddress = v.mxAddress
+		artifact.postureConfig = v.posture
+		artifact.roleAccounts = v.roleAccounts
+		artifact.syntaxMode = v.syntaxMode
+
+		if v.resolver != nil {
+			artifact.resolver = v.resolver
+		}
+	})
 }
 
 func prependOptions(options []ArtifactFn, o ...ArtifactFn) []ArtifactFn {
@@ -41,65 +155,23 @@ func prependOptions(options []ArtifactFn, o ...ArtifactFn) []ArtifactFn {
 //
 // Warning: Using this _can_ degrade your IPs reputation, since it's also a process spammers use.
 func (v *EmailValidator) CheckWithRCPT(ctx context.Context, emailParts types.EmailParts, options ...ArtifactFn) Result {
-	artifact, _ := validateSequence(ctx,
-		getNewArtifact(ctx, emailParts, prependOptions(options, WithDialer(v.dialer))...),
-		[]stateFn{
-			getSyntaxCheck(emailParts),
-			checkIfDomainHasMX,
-			checkIfMXHasIP,
-			checkMXAcceptsConnect,
-			checkRCPT,
-		})
-
-	return createResult(artifact)
+	return v.CheckWithPipeline(RCPTPipeline())(ctx, emailParts, options...)
 }
 
 // CheckWithConnect performs a thorough check, which has the low chance of false-positives. It also tests if the MX server
 // accepts connections, but won't try any mail commands.
 func (v *EmailValidator) CheckWithConnect(ctx context.Context, emailParts types.EmailParts, options ...ArtifactFn) Result {
-	artifact, _ := validateSequence(ctx,
-		getNewArtifact(ctx, emailParts, prependOptions(options, WithDialer(v.dialer))...),
-		[]stateFn{
-			getSyntaxCheck(emailParts),
-			checkIfDomainHasMX,
-			checkIfMXHasIP,
-			checkMXAcceptsConnect,
-		})
-
-	return createResult(artifact)
+	return v.CheckWithPipeline(ConnectPipeline())(ctx, emailParts, options...)
 }
 
 // CheckWithLookup performs a sanity check using DNS lookups. It won't connect to the actual hosts.
 func (v *EmailValidator) CheckWithLookup(ctx context.Context, emailParts types.EmailParts, options ...ArtifactFn) Result {
-	artifact, _ := validateSequence(ctx,
-		getNewArtifact(ctx, emailParts, prependOptions(options, WithDialer(v.dialer))...),
-		[]stateFn{
-			getSyntaxCheck(emailParts),
-			checkIfDomainHasMX,
-			checkIfMXHasIP,
-		})
-
-	return createResult(artifact)
+	return v.CheckWithPipeline(LookupPipeline())(ctx, emailParts, options...)
 }
 
 // CheckWithSyntax performs only a syntax check.
 func (v *EmailValidator) CheckWithSyntax(ctx context.Context, emailParts types.EmailParts, options ...ArtifactFn) Result {
-	artifact, _ := validateSequence(ctx,
-		getNewArtifact(ctx, emailParts, prependOptions(options, WithDialer(v.dialer))...),
-		[]stateFn{
-			getSyntaxCheck(emailParts),
-		})
-
-	return createResult(artifact)
-}
-
-// getSyntaxCheck returns a domain only check, when the local part is missing and otherwise uses a full address check
-func getSyntaxCheck(parts types.EmailParts) stateFn {
-	var syntaxCheck stateFn = checkEmailAddressSyntax
-	if parts.Local == "" {
-		syntaxCheck = checkDomainSyntax
-	}
-	return syntaxCheck
+	return v.CheckWithPipeline(SyntaxPipeline())(ctx, emailParts, options...)
 }
 
 func validateSequence(ctx context.Context, artifact Artifact, sequence []stateFn) (Artifact, error) {
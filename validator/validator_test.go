@@ -56,6 +56,36 @@ func TestEmailValidator_CheckWithLookup(t *testing.T) {
 	}
 }
 
+func TestEmailValidator_CheckWithLookup_addressLiteral(t *testing.T) {
+	// Only example.org is looked up, its MX host resolves to a private address
+	r := buildResolver([]string{"mx.example.org"}, map[string][]net.IPAddr{"mx.example.org": {{IP: net.IPv4(10, 0, 0, 25)}}}, nil)
+	v := NewEmailAddressValidator(nil, WithSyntaxMode(SyntaxRFC5322), WithResolver(r))
+
+	tests := []struct {
+		email     string
+		wantValid bool
+	}{
+		{email: "john@[192.0.2.1]", wantValid: true},
+		{email: "john@[10.0.0.25]", wantValid: true},
+		{email: "john@[IPv6:2001:db8::1]", wantValid: true},
+		{email: "john@example.org", wantValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			parts, err := types.NewEmailParts(tt.email)
+			if err != nil {
+				t.Fatalf("Test setup failed, %s", err)
+			}
+
+			got := v.CheckWithLookup(context.Background(), parts)
+			if got.Validations.IsValid() != tt.wantValid || got.Validations.HasFlag(validations.FMXDomainHasIP) != tt.wantValid {
+				t.Errorf("Expected %q to be valid: %t, got %s (%s)", tt.email, tt.wantValid, got.Validations, got.Diagnostics.Reason)
+			}
+		})
+	}
+}
+
 func Test_validateSequence(t *testing.T) {
 	ctxExpiredDeadline, cancel := context.WithTimeout(context.Background(), -1*time.Hour)
 	defer cancel()
@@ -228,6 +258,89 @@ func TestNewEmailAddressValidator(t *testing.T) {
 	}
 }
 
+type recordingDialer struct {
+	addresses []string
+}
+
+func (rd *recordingDialer) DialContext(_ context.Context, _, address string) (net.Conn, error) {
+	rd.addresses = append(rd.addresses, address)
+	return &net.IPConn{}, nil
+}
+
+func TestEmailValidator_CheckWithConnect_addressLiteral(t *testing.T) {
+	tests := []struct {
+		name        string
+		domain      string
+		wantAddress string
+	}{
+		{name: "IPv4", domain: "[192.0.2.1]", wantAddress: "192.0.2.1:25"},
+		{name: "IPv6", domain: "[IPv6:2001:db8::1]", wantAddress: "[2001:db8::1]:25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := &recordingDialer{}
+			v := NewEmailAddressValidator(nil, WithSyntaxMode(SyntaxRFC5322), withDocumentationMX)
+			r := v.CheckWithConnect(context.Background(), types.NewEmailFromParts(`"john doe"`, tt.domain), func(artifact *Artifact) {
+				artifact.resolver = buildLookupMX(nil, errors.New("the resolver should not be used"))
+				artifact.dialer = dialer
+			})
+
+			if !r.Validations.IsValid() {
+				t.Errorf("Expected the result to be valid, got %+v", r.Diagnostics)
+			}
+
+			if !reflect.DeepEqual(dialer.addresses, []string{tt.wantAddress}) {
+				t.Errorf("Expected a single connection to %q, got %v", tt.wantAddress, dialer.addresses)
+			}
+		})
+	}
+}
+
+func TestEmailValidator_WithResolver(t *testing.T) {
+	resolver := buildResolver([]string{"mx.example.org"}, map[string][]net.IPAddr{
+		"mx.example.org": {{IP: net.ParseIP("192.0.2.1")}},
+	}, nil)
+
+	v := NewEmailAddressValidator(nil, WithResolver(resolver), withDocumentationMX)
+	r := v.CheckWithLookup(context.Background(), types.NewEmailFromParts("john", "example.org"))
+	if !r.Validations.IsValid() {
+		t.Errorf("Expected the lookups to use the resolver, got %+v", r.Diagnostics)
+	}
+
+	// Options of a single check take precedence
+	r = v.CheckWithLookup(context.Background(), types.NewEmailFromParts("john", "example.org"), func(artifact *Artifact) {
+		artifact.resolver = buildLookupMX(nil, errors.New("b0rk"))
+	})
+
+	if r.Validations.IsValid() {
+		t.Errorf("Expected the resolver of the check to be used")
+	}
+}
+
+func TestEmailValidator_WithTLDList(t *testing.T) {
+	resolver := &countingResolver{}
+	v := NewEmailAddressValidator(nil, WithResolver(resolver), WithTLDList(NewTLDList()), withDocumentationMX)
+
+	r := v.CheckWithLookup(context.Background(), types.NewEmailFromParts("john", "example.cmo"))
+	if r.Validations.IsValid() || r.Diagnostics.Reason != ReasonUnknownTLD {
+		t.Errorf("Expected the unknown TLD to be the reason, got %q (%s)", r.Diagnostics.Reason, r.Diagnostics.Detail)
+	}
+
+	if r.Validations.HasFlag(validations.FSyntax) {
+		t.Errorf("Expected an unknown TLD to fail the syntax check")
+	}
+
+	if resolver.Lookups() != 0 {
+		t.Errorf("Expected the unknown TLD to be rejected without a lookup")
+	}
+
+	r = v.CheckWithLookup(context.Background(), types.NewEmailFromParts("john", "example.org"))
+	if !r.Validations.IsValid() {
+		t.Errorf("Expected a known TLD to pass, got %+v", r.Diagnostics)
+	}
+}
+
 var looksLikeValidDomainResult bool
 
 func Benchmark_looksLikeValidDomain(b *testing.B) {
@@ -260,3 +373,21 @@ func Benchmark_looksLikeValidDomain(b *testing.B) {
 		_ = !!looksLikeValidDomainResult
 	}
 }
+
+func TestEmailValidator_WithMXAddressConfig(t *testing.T) {
+	resolver := buildResolver([]string{"mx.example.org"}, map[string][]net.IPAddr{
+		"mx.example.org": {{IP: net.ParseIP("127.0.0.1")}},
+	}, nil)
+
+	v := NewEmailAddressValidator(nil, WithResolver(resolver))
+	r := v.CheckWithLookup(context.Background(), types.NewEmailFromParts("john", "example.org"))
+	if r.Validations.IsValid() || r.Diagnostics.Reason != ReasonMXUnroutable {
+		t.Errorf("Expected a loopback MX to be unroutable, got %q (%s)", r.Diagnostics.Reason, r.Diagnostics.Detail)
+	}
+
+	v = NewEmailAddressValidator(nil, WithResolver(resolver), WithMXAddressConfig(MXAddressConfig{Allow: IPLoopback}))
+	r = v.CheckWithLookup(context.Background(), types.NewEmailFromParts("john", "example.org"))
+	if !r.Validations.IsValid() {
+		t.Errorf("Expected a loopback MX to be allowed, got %+v", r.Diagnostics)
+	}
+}